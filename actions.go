@@ -26,18 +26,61 @@ type Actions struct {
 	// in parallel with persisting to disk and performing network sends.
 	Hash []*HashRequest
 
+	// Sign is a set of requests to sign data on behalf of this node.  It is only
+	// populated when the network config requires signatures.  Like Hash, signing
+	// may be done in parallel with persisting to disk and performing network sends.
+	Sign []*SignRequest
+
+	// Verify is a set of signatures which must be verified before the messages
+	// carrying them are processed.  It is only populated when the network config
+	// requires signatures.
+	Verify []*VerifySignature
+
+	// Validate is a set of client requests which this node has received and
+	// digested, but which the application must validate before this node
+	// acknowledges them.  Validation may be done in parallel with hashing.
+	Validate []*ValidateRequest
+
 	// Persisted contains data that should be persisted to persistent storage. It could
 	// be of following types:
 	// QEntry: Multiple QEntries may be persisted for the same SeqNo, but for different
 	//         epochs and all must be retained.
 	// PEntry: Any PEntry already in storage but with an older epoch may be discarded.
+	// Checkpoint: Entries below the lowest retained checkpoint may be discarded.
+	// NetworkConfig: Only the most recent network config must be retained.
+	// ECEntry: Only the EpochChange with the highest epoch must be retained.
+	// NEntry: Only the NEntry with the highest epoch must be retained.
 	Persisted []*pb.Persisted
 
+	// Truncate is set to the new low watermark once the state machine has garbage
+	// collected its persisted state below it.  Once the entries of Persisted have
+	// been written, the entries which the state machine no longer requires, as
+	// described for Persisted, may be discarded from storage.
+	Truncate uint64
+
 	// Commits is a set of batches which have achieved final order and are ready to commit.
 	// They will have previously persisted via QEntries.  When the user processes a commit,
 	// if that commit contains a checkpoint, the user must return a checkpoint result for
 	// this commit.  Checkpoints must be persisted before further commits are reported as applied.
 	Commits []*Commit
+
+	// StateTransfer is set when the network has agreed upon a checkpoint which this
+	// node cannot reach by committing batches.  The user should fetch the application
+	// state for this checkpoint from the other nodes out of band, and once it has been
+	// applied, inform the state machine via Node.StateTransferComplete.
+	StateTransfer *StateTarget
+
+	// Evidence is a set of messages received from other nodes which could only
+	// have been sent by a byzantine node.  The offending messages have been
+	// rejected, but the user may wish to alert an operator, or to remove the
+	// offending node from the network.
+	Evidence []*Evidence
+
+	// Replicas is set when the network is reconfigured, to the replicas of the
+	// new network config.  The broadcasts of these actions, and of all later
+	// ones, are for these replicas, so the consumer should update Node.Replicas
+	// before transmitting them.
+	Replicas []Replica
 }
 
 // Clear nils out all of the fields.
@@ -45,8 +88,15 @@ func (a *Actions) Clear() {
 	a.Broadcast = nil
 	a.Unicast = nil
 	a.Hash = nil
+	a.Sign = nil
+	a.Verify = nil
+	a.Validate = nil
 	a.Persisted = nil
+	a.Truncate = 0
 	a.Commits = nil
+	a.StateTransfer = nil
+	a.Evidence = nil
+	a.Replicas = nil
 }
 
 // IsEmpty returns whether every field is zero in length.
@@ -55,7 +105,14 @@ func (a *Actions) IsEmpty() bool {
 		len(a.Unicast) == 0 &&
 		len(a.Commits) == 0 &&
 		len(a.Hash) == 0 &&
-		len(a.Persisted) == 0
+		len(a.Sign) == 0 &&
+		len(a.Verify) == 0 &&
+		len(a.Validate) == 0 &&
+		len(a.Persisted) == 0 &&
+		a.Truncate == 0 &&
+		a.StateTransfer == nil &&
+		len(a.Evidence) == 0 &&
+		a.Replicas == nil
 }
 
 // Append takes a set of actions and for each field, appends it to
@@ -65,7 +122,20 @@ func (a *Actions) Append(o *Actions) {
 	a.Unicast = append(a.Unicast, o.Unicast...)
 	a.Commits = append(a.Commits, o.Commits...)
 	a.Hash = append(a.Hash, o.Hash...)
+	a.Sign = append(a.Sign, o.Sign...)
+	a.Verify = append(a.Verify, o.Verify...)
+	a.Validate = append(a.Validate, o.Validate...)
 	a.Persisted = append(a.Persisted, o.Persisted...)
+	if o.Truncate > a.Truncate {
+		a.Truncate = o.Truncate
+	}
+	if o.StateTransfer != nil {
+		a.StateTransfer = o.StateTransfer
+	}
+	a.Evidence = append(a.Evidence, o.Evidence...)
+	if o.Replicas != nil {
+		a.Replicas = o.Replicas
+	}
 }
 
 // HashRequest is a request from the state machine to the consumer to hash some data.
@@ -97,6 +167,38 @@ type HashRequest struct {
 	VerifyRequest *VerifyRequest
 }
 
+// SignRequest is a request from the state machine to the consumer to sign some
+// data with this node's identity.  As with hashing, the Data field is generally
+// the only field the consumer should read.
+type SignRequest struct {
+	// Data is a series of byte slices which should be signed.
+	Data [][]byte
+
+	// Msg is the message which will be broadcast, carrying the signature.
+	Msg *pb.Msg
+}
+
+// VerifySignature is a request from the state machine to the consumer to verify
+// that Signature is a valid signature by the node Signer over Data.
+type VerifySignature struct {
+	// Source is the node which sent the message to us.
+	Source uint64
+
+	// Signer is the node which claims to have signed the data.  This differs
+	// from Source when a node forwards a message signed by another, for instance
+	// the epoch change embedded in an epoch change ack.
+	Signer uint64
+
+	// Data is a series of byte slices which were signed.
+	Data [][]byte
+
+	// Signature is the signature to verify.
+	Signature []byte
+
+	// Msg is the message which is processed once its signature is verified.
+	Msg *pb.Msg
+}
+
 type VerifyBatch struct {
 	Source         uint64
 	SeqNo          uint64
@@ -123,6 +225,21 @@ type Request struct {
 	Request *pb.Request
 }
 
+// ValidateRequest is a request from the state machine to the consumer to
+// validate a client request, for instance, that it is well formed and that
+// the client is authorized to submit it.  Until the request is validated,
+// this node will not acknowledge it.
+type ValidateRequest struct {
+	// Source is the node which received the request from the client.
+	Source uint64
+
+	// Request is the client request to validate.
+	Request *pb.Request
+
+	// Digest is the digest of the request, as computed by the consumer.
+	Digest []byte
+}
+
 type EpochChange struct {
 	// Source is who actually sent us the request, whereas Origin is
 	// the purported originator of the message.
@@ -151,11 +268,49 @@ type Commit struct {
 	Checkpoint bool
 }
 
+// Evidence describes a message which violates the protocol in a way which
+// no correct node could.
+type Evidence struct {
+	// Source is the node which sent the message.
+	Source uint64
+
+	// Msg is the offending message.
+	Msg *pb.Msg
+
+	// Reason describes how the message violates the protocol.
+	Reason string
+}
+
 // ActionResults should be populated by the caller as a result of
 // executing the actions, then returned to the state machine.
 type ActionResults struct {
-	Digests     []*HashResult
-	Checkpoints []*CheckpointResult
+	Digests       []*HashResult
+	Signatures    []*SignResult
+	Verifications []*VerifySignatureResult
+	Validations   []*ValidateResult
+	Checkpoints   []*CheckpointResult
+	StateTransfer *StateTransferResult
+}
+
+// SignResult gives the state machine the signature produced for a SignRequest.
+type SignResult struct {
+	Request   *SignRequest
+	Signature []byte
+}
+
+// VerifySignatureResult gives the state machine the outcome of verifying a
+// signature.  Valid should be false if the signature is not a valid signature
+// by the signer, including when the signer is unknown to the consumer.
+type VerifySignatureResult struct {
+	Request *VerifySignature
+	Valid   bool
+}
+
+// ValidateResult gives the state machine the outcome of validating a client
+// request.  Invalid requests are discarded without being acknowledged.
+type ValidateResult struct {
+	Request *ValidateRequest
+	Valid   bool
 }
 
 // CheckpointResult gives the state machine a verifiable checkpoint for the network
@@ -170,4 +325,44 @@ type CheckpointResult struct {
 	// computed from a Merkle tree, hash chain, or other structure exihibiting
 	// the properties of a strong hash function.
 	Value []byte
+
+	// NetworkConfig is set when the entries committed up to this checkpoint
+	// reconfigured the network.  The application decides which requests
+	// reconfigure the network, and must reflect the resulting config in Value.
+	// The new config takes effect once this checkpoint is stable, and applies
+	// to every entry after it.
+	NetworkConfig *pb.NetworkConfig
+}
+
+// StateTarget is a checkpoint which has been agreed upon by the network, and
+// to which this node must transfer its application state.
+type StateTarget struct {
+	// SeqNo is the sequence number of the target checkpoint.
+	SeqNo uint64
+
+	// Value is the checkpoint value agreed upon by the network.
+	Value []byte
+}
+
+// StateTransferResult indicates that the application state now reflects
+// all entries up to and including the target checkpoint.
+type StateTransferResult struct {
+	// Target is the target of the completed state transfer, as supplied
+	// in the StateTransfer action.
+	Target *StateTarget
+
+	// ClientWatermarks are the lowest request numbers which have not committed
+	// at the target checkpoint, for each client known to the application.  The
+	// application typically derives these from the requests it has applied.
+	ClientWatermarks []*ClientWatermark
+
+	// NetworkConfig is the network config in effect at the target checkpoint,
+	// if it differs from the one this node is currently using.
+	NetworkConfig *pb.NetworkConfig
+}
+
+// ClientWatermark is the lowest uncommitted request number for a client.
+type ClientWatermark struct {
+	ClientID     []byte
+	LowWatermark uint64
 }
@@ -8,7 +8,9 @@ package mirbft
 
 import (
 	"bytes"
+
 	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/pkg/errors"
 )
 
 type batchTracker struct {
@@ -140,17 +142,17 @@ func (bt *batchTracker) applyForwardBatchMsg(source NodeID, seqNo uint64, digest
 	}
 }
 
-func (bt *batchTracker) applyVerifyBatchHashResult(digest []byte, verifyBatch *VerifyBatch) {
+func (bt *batchTracker) applyVerifyBatchHashResult(digest []byte, verifyBatch *VerifyBatch) error {
 	if !bytes.Equal(verifyBatch.ExpectedDigest, digest) {
-		panic("byzantine")
-		// XXX this should be a log only, but panic-ing to make dev easier for now
+		// The fetch remains in flight, so a correct node may still satisfy it.
+		return errors.Errorf("forwarded batch for seq_no=%d hashes to %x, not the expected %x", verifyBatch.SeqNo, digest, verifyBatch.ExpectedDigest)
 	}
 
 	inFlight, ok := bt.fetchInFlight[string(digest)]
 	if !ok {
 		// We must have gotten multiple responses, and already
 		// committed one, which is fine.
-		return
+		return nil
 	}
 
 	b, ok := bt.batchesByDigest[string(digest)]
@@ -167,6 +169,8 @@ func (bt *batchTracker) applyVerifyBatchHashResult(digest []byte, verifyBatch *V
 	}
 
 	delete(bt.fetchInFlight, string(digest))
+
+	return nil
 }
 
 func (bt *batchTracker) hasFetchInFlight() bool {
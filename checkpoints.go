@@ -17,18 +17,25 @@ type checkpointTracker struct {
 	highestCheckpoint map[NodeID]*checkpoint
 
 	// checkpoints is a map of bounded size.  The map maintains a checkpoint
-	// so long as it is the highest checkpoint for some node, or, is currently
-	// within the watermarks.
+	// so long as it is currently within the watermarks.
 	checkpoints map[uint64]*checkpoint
 
+	// futureCheckpoints is a map of bounded size.  The map maintains a checkpoint
+	// beyond the watermarks so long as it is the highest checkpoint for some node.
+	futureCheckpoints map[uint64]*checkpoint
+
+	// stateTransfer is the target of the state transfer in progress, if any.
+	stateTransfer *StateTarget
+
 	networkConfig *pb.NetworkConfig
 	myConfig      *Config
 }
 
 func newCheckpointTracker(initialCheckpoints map[uint64]*pb.Checkpoint, networkConfig *pb.NetworkConfig, myConfig *Config) *checkpointTracker {
 	ct := &checkpointTracker{
-		highestCheckpoint: map[NodeID]*checkpoint{}, // TODO, implement
+		highestCheckpoint: map[NodeID]*checkpoint{},
 		checkpoints:       map[uint64]*checkpoint{},
+		futureCheckpoints: map[uint64]*checkpoint{},
 		networkConfig:     networkConfig,
 		myConfig:          myConfig,
 	}
@@ -37,12 +44,15 @@ func newCheckpointTracker(initialCheckpoints map[uint64]*pb.Checkpoint, networkC
 	for seqNo, cp := range initialCheckpoints {
 		pcp := ct.checkpoint(seqNo)
 		pcp.applyCheckpointMsg(NodeID(myConfig.ID), cp.Value)
+		pcp.nextNetworkConfig = cp.NetworkConfig
 		if earliestCheckpoint == nil || earliestCheckpoint.seqNo > seqNo {
 			earliestCheckpoint = pcp
 		}
 	}
 
+	// The network config of the earliest checkpoint has already taken effect
 	earliestCheckpoint.stable = true
+	earliestCheckpoint.nextNetworkConfig = nil
 
 	return ct
 }
@@ -55,6 +65,24 @@ func (ct *checkpointTracker) truncate(lowSeqNo uint64) {
 	}
 }
 
+// lowWatermark returns the sequence number of the highest stable checkpoint.
+func (ct *checkpointTracker) lowWatermark() uint64 {
+	var lowWatermark uint64
+	for seqNo, cp := range ct.checkpoints {
+		if cp.stable && seqNo > lowWatermark {
+			lowWatermark = seqNo
+		}
+	}
+
+	return lowWatermark
+}
+
+// highWatermark returns the highest sequence number which could be committed
+// without first moving the low watermark.
+func (ct *checkpointTracker) highWatermark() uint64 {
+	return ct.lowWatermark() + 3*uint64(ct.networkConfig.CheckpointInterval)
+}
+
 func (ct *checkpointTracker) checkpoint(seqNo uint64) *checkpoint {
 	cp, ok := ct.checkpoints[seqNo]
 	if !ok {
@@ -71,8 +99,168 @@ func (ct *checkpointTracker) applyCheckpointMsg(source NodeID, seqNo uint64, val
 	return cp.applyCheckpointMsg(source, value)
 }
 
-func (ct *checkpointTracker) applyCheckpointResult(seqNo uint64, value []byte) *Actions {
-	return ct.checkpoints[seqNo].applyCheckpointResult(value)
+// applyFutureCheckpointMsg tracks checkpoints which are beyond our watermarks,
+// retaining only the highest checkpoint for each node.  If an intersection quorum
+// agrees on the value of such a checkpoint, then it is returned as a target for
+// state transfer.
+func (ct *checkpointTracker) applyFutureCheckpointMsg(source NodeID, seqNo uint64, value []byte) *StateTarget {
+	if highest, ok := ct.highestCheckpoint[source]; ok && highest.seqNo >= seqNo {
+		return nil
+	}
+
+	cp, ok := ct.futureCheckpoints[seqNo]
+	if !ok {
+		cp = newCheckpoint(seqNo, ct.networkConfig, ct.myConfig)
+		ct.futureCheckpoints[seqNo] = cp
+	}
+	cp.applyCheckpointMsg(source, value)
+	ct.highestCheckpoint[source] = cp
+
+	ct.garbageCollectFutureCheckpoints()
+
+	stableValue := cp.networkStableValue()
+	if stableValue == nil {
+		return nil
+	}
+
+	return &StateTarget{
+		SeqNo: seqNo,
+		Value: stableValue,
+	}
+}
+
+// garbageCollectFutureCheckpoints discards any future checkpoint which has
+// fallen within the watermarks, or which is no longer the highest checkpoint
+// for any node.
+func (ct *checkpointTracker) garbageCollectFutureCheckpoints() {
+	highWatermark := ct.highWatermark()
+	for seqNo := range ct.futureCheckpoints {
+		referenced := false
+		for _, highest := range ct.highestCheckpoint {
+			if highest.seqNo == seqNo {
+				referenced = true
+				break
+			}
+		}
+
+		if !referenced || seqNo <= highWatermark {
+			delete(ct.futureCheckpoints, seqNo)
+		}
+	}
+}
+
+// retain carries over the checkpoints beyond the watermarks, and the state
+// transfer in progress, from the tracker this one replaces on reconfiguration.
+// Only the agreements of nodes which remain in the network are retained.
+func (ct *checkpointTracker) retain(old *checkpointTracker) {
+	ct.stateTransfer = old.stateTransfer
+
+	nodes := map[NodeID]struct{}{}
+	for _, id := range ct.networkConfig.Nodes {
+		nodes[NodeID(id)] = struct{}{}
+	}
+
+	for seqNo, cp := range old.futureCheckpoints {
+		cp.networkConfig = ct.networkConfig
+		for value, valueNodes := range cp.values {
+			var retained []NodeID
+			for _, node := range valueNodes {
+				if _, ok := nodes[node]; ok {
+					retained = append(retained, node)
+				}
+			}
+
+			if len(retained) == 0 {
+				delete(cp.values, value)
+				continue
+			}
+			cp.values[value] = retained
+		}
+		ct.futureCheckpoints[seqNo] = cp
+	}
+
+	for source, highest := range old.highestCheckpoint {
+		if _, ok := nodes[source]; ok {
+			ct.highestCheckpoint[source] = highest
+		}
+	}
+
+	ct.garbageCollectFutureCheckpoints()
+}
+
+// networkStableTarget returns the highest checkpoint at or beyond the given
+// sequence whose value an intersection quorum of the network agrees upon, or
+// nil if there is no such checkpoint within the watermarks.
+func (ct *checkpointTracker) networkStableTarget(seqNo uint64) *StateTarget {
+	var target *StateTarget
+	for cpSeqNo, cp := range ct.checkpoints {
+		if cpSeqNo < seqNo || (target != nil && cpSeqNo < target.SeqNo) {
+			continue
+		}
+
+		value := cp.networkStableValue()
+		if value == nil {
+			continue
+		}
+
+		target = &StateTarget{
+			SeqNo: cpSeqNo,
+			Value: value,
+		}
+	}
+
+	return target
+}
+
+// requestStateTransfer returns a state transfer action for the target, unless
+// a state transfer to this, or a later checkpoint is already in progress.
+func (ct *checkpointTracker) requestStateTransfer(target *StateTarget) *Actions {
+	if ct.stateTransfer != nil && ct.stateTransfer.SeqNo >= target.SeqNo {
+		return &Actions{}
+	}
+
+	ct.stateTransfer = target
+
+	return &Actions{
+		StateTransfer: target,
+	}
+}
+
+// applyStateTransfer discards all checkpoints below the target, and marks the
+// target checkpoint as stable with the transferred value.
+func (ct *checkpointTracker) applyStateTransfer(target *StateTarget) {
+	ct.stateTransfer = nil
+
+	cp, ok := ct.futureCheckpoints[target.SeqNo]
+	if ok {
+		// Retain the votes we collected while this checkpoint was beyond our watermarks
+		ct.checkpoints[target.SeqNo] = cp
+	} else {
+		cp = ct.checkpoint(target.SeqNo)
+	}
+	cp.myValue = target.Value
+	cp.committedValue = target.Value
+	cp.stable = true
+
+	ct.truncate(target.SeqNo)
+	ct.garbageCollectFutureCheckpoints()
+}
+
+func (ct *checkpointTracker) applyCheckpointResult(seqNo uint64, value []byte, networkConfig *pb.NetworkConfig) *Actions {
+	cp := ct.checkpoint(seqNo)
+	cp.nextNetworkConfig = networkConfig
+	return cp.applyCheckpointResult(value)
+}
+
+// applySignature retains our signed checkpoint message, if the checkpoint is
+// still within the watermarks.
+func (ct *checkpointTracker) applySignature(signed *pb.Checkpoint) {
+	cp, ok := ct.checkpoints[signed.SeqNo]
+	if !ok {
+		return
+	}
+
+	cp.mySigned = signed
 }
 
 func (ct *checkpointTracker) status() []*CheckpointStatus {
@@ -100,6 +288,14 @@ type checkpoint struct {
 	myValue        []byte
 	stable         bool
 	obsolete       bool
+
+	// mySigned is our checkpoint message as signed, retained so that it may
+	// be retransmitted when the network requires signatures.
+	mySigned *pb.Checkpoint
+
+	// nextNetworkConfig is the network config which takes effect once this
+	// checkpoint is stable, if the network is reconfigured at this checkpoint.
+	nextNetworkConfig *pb.NetworkConfig
 }
 
 func newCheckpoint(seqNo uint64, config *pb.NetworkConfig, myConfig *Config) *checkpoint {
@@ -112,6 +308,15 @@ func newCheckpoint(seqNo uint64, config *pb.NetworkConfig, myConfig *Config) *ch
 }
 
 func (cw *checkpoint) applyCheckpointMsg(source NodeID, value []byte) bool {
+	for _, nodes := range cw.values {
+		for _, node := range nodes {
+			if node == source {
+				// We have already counted a checkpoint from this node
+				return false
+			}
+		}
+	}
+
 	stateChange := false
 
 	checkpointValueNodes := append(cw.values[string(value)], source)
@@ -128,16 +333,12 @@ func (cw *checkpoint) applyCheckpointMsg(source NodeID, value []byte) bool {
 	}
 
 	// If I have completed this checkpoint, along with a quorum of the network, and I've not already run this path
-	if cw.myValue != nil && cw.committedValue != nil && !cw.stable {
-		if !bytes.Equal(value, cw.committedValue) {
-			// TODO optionally handle this more gracefully, with state transfer (though this
-			// indicates a violation of the byzantine assumptions)
-			panic("my checkpoint disagrees with the committed network view of this checkpoint")
-		}
-
+	// Note, if my value disagrees with the network, the checkpoint never becomes stable, and instead
+	// the state machine transfers state once the network value is stable.
+	if cw.myValue != nil && cw.committedValue != nil && !cw.stable && bytes.Equal(cw.myValue, cw.committedValue) {
 		// This checkpoint has enough agreements, including my own, it may now be garbage collectable
 		// Note, this must be >= (not ==) because my agreement could come after 2f+1 from the network.
-		if agreements >= intersectionQuorum(cw.networkConfig) {
+		if len(cw.values[string(cw.myValue)]) >= intersectionQuorum(cw.networkConfig) {
 			cw.stable = true
 			stateChange = true
 		}
@@ -151,6 +352,24 @@ func (cw *checkpoint) applyCheckpointMsg(source NodeID, value []byte) bool {
 	return stateChange
 }
 
+// diverged returns whether my value for this checkpoint disagrees with the
+// value committed by the network.
+func (cw *checkpoint) diverged() bool {
+	return cw.myValue != nil && cw.committedValue != nil && !bytes.Equal(cw.myValue, cw.committedValue)
+}
+
+// networkStableValue returns the checkpoint value which an intersection quorum
+// of the network agrees upon, or nil if there is no such value.
+func (cw *checkpoint) networkStableValue() []byte {
+	for value, nodes := range cw.values {
+		if len(nodes) >= intersectionQuorum(cw.networkConfig) {
+			return []byte(value)
+		}
+	}
+
+	return nil
+}
+
 func (cw *checkpoint) applyCheckpointResult(value []byte) *Actions {
 	return &Actions{
 		Broadcast: []*pb.Msg{
@@ -0,0 +1,175 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft_test
+
+import (
+	"context"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/IBM/mirbft/mock"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("StateTransfer", func() {
+	var (
+		doneC          chan struct{}
+		node           *mirbft.Node
+		target         *mirbft.StateTarget
+		initialActions mirbft.Actions
+	)
+
+	BeforeEach(func() {
+		doneC = make(chan struct{})
+
+		logger, err := zap.NewDevelopment()
+		Expect(err).NotTo(HaveOccurred())
+
+		storage := &mock.Storage{}
+		storage.LoadReturns(nil, io.EOF)
+
+		node, err = mirbft.StartNewNode(&mirbft.Config{
+			ID:     3,
+			Logger: logger,
+			BatchParameters: mirbft.BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}, doneC, mirbft.StandardInitialNetworkConfig(4), storage)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(node.Ready()).Should(Receive(&initialActions))
+		Expect(initialActions.StateTransfer).To(BeNil())
+
+		target = &mirbft.StateTarget{
+			SeqNo: 100,
+			Value: []byte("cp100"),
+		}
+	})
+
+	AfterEach(func() {
+		close(doneC)
+	})
+
+	stepCheckpoint := func(source uint64) {
+		err := node.Step(context.Background(), source, &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{
+					SeqNo: target.SeqNo,
+					Value: target.Value,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("requests state transfer once a quorum agrees on a checkpoint beyond the watermarks", func() {
+		stepCheckpoint(0)
+		stepCheckpoint(1)
+		var actions mirbft.Actions
+		Eventually(node.Ready()).Should(Receive(&actions))
+		Expect(actions.StateTransfer).To(BeNil())
+
+		stepCheckpoint(2)
+		Eventually(node.Ready()).Should(Receive(&actions))
+		Expect(actions.StateTransfer).To(Equal(target))
+
+		err := node.StateTransferComplete(&mirbft.StateTransferResult{
+			Target: target,
+			ClientWatermarks: []*mirbft.ClientWatermark{
+				{
+					ClientID:     []byte("client"),
+					LowWatermark: 73,
+				},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(node.Ready()).Should(Receive(&actions))
+		Expect(actions.Persisted).To(HaveLen(1))
+		Expect(actions.Persisted[0].Type.(*pb.Persisted_Checkpoint).Checkpoint).To(Equal(&pb.Checkpoint{
+			SeqNo: 100,
+			Value: []byte("cp100"),
+		}))
+
+		status, err := node.Status(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Checkpoints).To(HaveLen(1))
+		Expect(status.Checkpoints[0].SeqNo).To(Equal(uint64(100)))
+		Expect(status.Checkpoints[0].MaxAgreements).To(Equal(3))
+
+		Expect(status.ClientWindows).To(HaveLen(1))
+		Expect(status.ClientWindows[0].LowWatermark).To(Equal(uint64(73)))
+
+		for _, nodeStatus := range status.Nodes {
+			Expect(nodeStatus.LastCheckpoint).To(Equal(uint64(100)))
+		}
+	})
+
+	It("derives the genesis checkpoint value from the initial network config", func() {
+		genesisValue, err := proto.Marshal(mirbft.StandardInitialNetworkConfig(4))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(len(initialActions.Persisted)).To(BeNumerically(">=", 2))
+		Expect(initialActions.Persisted[1].Type.(*pb.Persisted_Checkpoint).Checkpoint).To(Equal(&pb.Checkpoint{
+			SeqNo: 0,
+			Value: genesisValue,
+		}))
+	})
+
+	It("rejects a result whose network config has no client window width", func() {
+		networkConfig := mirbft.StandardInitialNetworkConfig(4)
+		networkConfig.ClientWindowWidth = 0
+
+		err := node.StateTransferComplete(&mirbft.StateTransferResult{
+			Target:        target,
+			NetworkConfig: networkConfig,
+		})
+		Expect(err).To(MatchError("invalid network config for state transfer to seqno=100: client window width must be greater than zero"))
+
+		err = node.AddResults(mirbft.ActionResults{
+			Checkpoints: []*mirbft.CheckpointResult{
+				{
+					SeqNo:         20,
+					Value:         []byte("cp20"),
+					NetworkConfig: networkConfig,
+				},
+			},
+		})
+		Expect(err).To(MatchError("invalid network config for checkpoint at seqno=20: client window width must be greater than zero"))
+	})
+
+	It("rejects a state transfer result with no target", func() {
+		err := node.StateTransferComplete(&mirbft.StateTransferResult{
+			NetworkConfig: mirbft.StandardInitialNetworkConfig(4),
+		})
+		Expect(err).To(MatchError("state transfer result has no target"))
+	})
+
+	It("ignores a result which does not match the requested target", func() {
+		err := node.StateTransferComplete(&mirbft.StateTransferResult{
+			Target: target,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var actions mirbft.Actions
+		Eventually(node.Ready()).Should(Receive(&actions))
+		Expect(actions.IsEmpty()).To(BeTrue())
+
+		status, err := node.Status(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Checkpoints).To(HaveLen(1))
+		Expect(status.Checkpoints[0].SeqNo).To(Equal(uint64(0)))
+	})
+})
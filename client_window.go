@@ -11,11 +11,13 @@ import (
 	"sort"
 
 	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/pkg/errors"
 )
 
 type clientWindows struct {
 	windows       map[string]*clientWindow
 	clients       []string
+	onCommit      map[string]func(*CommitReceipt) // Registered via the OnCommit option, by client
 	networkConfig *pb.NetworkConfig
 	myConfig      *Config
 }
@@ -25,6 +27,12 @@ func (cws *clientWindows) clientWindow(clientID []byte) (*clientWindow, bool) {
 	return cw, ok
 }
 
+// newWindow creates a client window beginning at the given low watermark, whose
+// width is determined by the network config.
+func (cws *clientWindows) newWindow(lowWatermark uint64) *clientWindow {
+	return newClientWindow(lowWatermark, lowWatermark+cws.networkConfig.ClientWindowWidth-1, cws.networkConfig, cws.myConfig)
+}
+
 func (cws *clientWindows) insert(clientID []byte, cw *clientWindow) {
 	cws.windows[string(clientID)] = cw
 	cws.clients = append(cws.clients, string(clientID))
@@ -33,6 +41,78 @@ func (cws *clientWindows) insert(clientID []byte, cw *clientWindow) {
 	})
 }
 
+// reinitialize replaces the window for this client with an empty window
+// beginning at the given low watermark.  This is used after state transfer,
+// as the requests committed by the transferred state are unknown to us.
+func (cws *clientWindows) reinitialize(clientID []byte, lowWatermark uint64) {
+	newWindow := cws.newWindow(lowWatermark)
+
+	oldWindow, ok := cws.clientWindow(clientID)
+	if !ok {
+		cws.insert(clientID, newWindow)
+		return
+	}
+
+	close(oldWindow.clientWaiter.expired)
+	cws.windows[string(clientID)] = newWindow
+}
+
+// commit records the sequence at which the request committed, and reports
+// it to the function registered for the client, if any.  The function only
+// queues the receipt for the client's receiptDispatcher, so it never blocks.
+func (cws *clientWindows) commit(clientID []byte, reqNo, seqNo, epoch uint64) {
+	cw, ok := cws.clientWindow(clientID)
+	if !ok {
+		panic("we never should have committed this without the client available")
+	}
+
+	cw.request(reqNo).committed = &seqNo
+
+	if onCommit, ok := cws.onCommit[string(clientID)]; ok {
+		onCommit(&CommitReceipt{
+			ClientID: clientID,
+			ReqNo:    reqNo,
+			SeqNo:    seqNo,
+			Epoch:    epoch,
+		})
+	}
+}
+
+// replyFetchRequest forwards the request data to the source if we have it,
+// so that it may be validated in place of the data the source is missing.
+func (cws *clientWindows) replyFetchRequest(source NodeID, clientID []byte, reqNo uint64, digest []byte) *Actions {
+	cw, ok := cws.clientWindow(clientID)
+	if !ok || reqNo < cw.lowWatermark || reqNo > cw.highWatermark {
+		return &Actions{}
+	}
+
+	crn := cw.request(reqNo)
+	if crn == nil {
+		return &Actions{}
+	}
+
+	cr, ok := crn.digests[string(digest)]
+	if !ok || cr.data == nil {
+		return &Actions{}
+	}
+
+	return &Actions{
+		Unicast: []Unicast{
+			{
+				Target: uint64(source),
+				Msg: &pb.Msg{
+					Type: &pb.Msg_ForwardRequest{
+						ForwardRequest: &pb.ForwardRequest{
+							Request: cr.data,
+							Digest:  digest,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func (cws *clientWindows) iterator() *clientWindowIterator {
 	return &clientWindowIterator{
 		clientWindows: cws,
@@ -96,30 +176,31 @@ func newClientWindow(lowWatermark, highWatermark uint64, networkConfig *pb.Netwo
 	}
 }
 
+// garbageCollect moves the low watermark past the requests which committed at
+// or before the given sequence, and the high watermark so that the window is
+// as wide as the network config requires.  Should the width have shrunk, the
+// high watermark is never moved backwards, as the client may already have
+// submitted requests up to it, instead the window narrows as it advances.
 func (cw *clientWindow) garbageCollect(maxSeqNo uint64) {
-	newRequests := make([]*clientReqNo, int(cw.highWatermark-cw.lowWatermark)+1)
-	i := 0
 	j := uint64(0)
-	copying := false
 	for _, request := range cw.clientRequests {
 		if request == nil || request.committed == nil || *request.committed > maxSeqNo {
-			copying = true
-		}
-
-		if copying {
-			newRequests[i] = request
-			i++
-		} else {
-			if request.committed == nil {
-				panic("this should be initialized if here")
-			}
-			j++
+			break
 		}
+		j++
+	}
 
+	newLowWatermark := cw.lowWatermark + j
+	newHighWatermark := newLowWatermark + cw.networkConfig.ClientWindowWidth - 1
+	if newHighWatermark < cw.highWatermark {
+		newHighWatermark = cw.highWatermark
 	}
 
-	cw.lowWatermark += j
-	cw.highWatermark += j
+	newRequests := make([]*clientReqNo, int(newHighWatermark-newLowWatermark)+1)
+	copy(newRequests, cw.clientRequests[j:])
+
+	cw.lowWatermark = newLowWatermark
+	cw.highWatermark = newHighWatermark
 	cw.clientRequests = newRequests
 	close(cw.clientWaiter.expired)
 	cw.clientWaiter = &clientWaiter{
@@ -129,13 +210,9 @@ func (cw *clientWindow) garbageCollect(maxSeqNo uint64) {
 	}
 }
 
-func (cw *clientWindow) ack(source NodeID, reqNo uint64, digest []byte) {
-	if reqNo > cw.highWatermark {
-		panic(fmt.Sprintf("unexpected: %d > %d", reqNo, cw.highWatermark))
-	}
-
-	if reqNo < cw.lowWatermark {
-		panic(fmt.Sprintf("unexpected: %d < %d", reqNo, cw.lowWatermark))
+func (cw *clientWindow) ack(source NodeID, reqNo uint64, digest []byte) error {
+	if reqNo > cw.highWatermark || reqNo < cw.lowWatermark {
+		return errors.Errorf("req_no=%d is outside of the client window [%d - %d]", reqNo, cw.lowWatermark, cw.highWatermark)
 	}
 
 	offset := int(reqNo - cw.lowWatermark)
@@ -160,6 +237,7 @@ func (cw *clientWindow) ack(source NodeID, reqNo uint64, digest []byte) {
 		cw.clientRequests[offset].strongRequest = cr
 	}
 
+	return nil
 }
 
 func (cw *clientWindow) allocate(requestData *pb.Request, digest []byte) {
@@ -210,21 +288,23 @@ func (cw *clientWindow) request(reqNo uint64) *clientReqNo {
 
 func (cw *clientWindow) status() *ClientWindowStatus {
 	allocated := make([]uint64, len(cw.clientRequests))
+	committed := make([]uint64, len(cw.clientRequests))
 	for i, request := range cw.clientRequests {
 		if request == nil {
 			continue
 		}
 		if request.committed != nil {
-			allocated[i] = 2 // TODO, actually report the seqno it committed to
+			allocated[i] = 2
+			committed[i] = *request.committed
 		} else {
 			allocated[i] = 1
 		}
-		// allocated[i] = bytesToUint64(request.preprocessResult.Proposal.Data)
 	}
 
 	return &ClientWindowStatus{
 		LowWatermark:  cw.lowWatermark,
 		HighWatermark: cw.highWatermark,
 		Allocated:     allocated,
+		Committed:     committed,
 	}
 }
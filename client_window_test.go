@@ -17,7 +17,69 @@ func Uint64ToPtr(value uint64) *uint64 {
 }
 
 var _ = Describe("clientWindows", func() {
+	var (
+		cws *clientWindows
+	)
+
+	BeforeEach(func() {
+		cws = &clientWindows{
+			windows: map[string]*clientWindow{},
+			networkConfig: &pb.NetworkConfig{
+				Nodes:             []uint64{0},
+				ClientWindowWidth: 500,
+			},
+			myConfig: &Config{},
+		}
+	})
+
+	It("sizes new windows according to the network config", func() {
+		cw := cws.newWindow(1)
+		Expect(cw.lowWatermark).To(Equal(uint64(1)))
+		Expect(cw.highWatermark).To(Equal(uint64(500)))
+		Expect(cw.clientRequests).To(HaveLen(500))
+	})
+
+	It("reinitializes windows, expiring any waiters", func() {
+		cws.insert([]byte("client"), cws.newWindow(1))
+		oldWindow, _ := cws.clientWindow([]byte("client"))
 
+		cws.reinitialize([]byte("client"), 73)
+		Expect(oldWindow.clientWaiter.expired).To(BeClosed())
+
+		cw, ok := cws.clientWindow([]byte("client"))
+		Expect(ok).To(BeTrue())
+		Expect(cw.lowWatermark).To(Equal(uint64(73)))
+		Expect(cw.highWatermark).To(Equal(uint64(572)))
+		Expect(cws.clients).To(HaveLen(1))
+	})
+
+	It("records commits, reporting them to the registered function", func() {
+		cws.insert([]byte("client"), cws.newWindow(1))
+		cws.insert([]byte("other"), cws.newWindow(1))
+		cw, _ := cws.clientWindow([]byte("client"))
+		cw.allocate(&pb.Request{ClientId: []byte("client"), ReqNo: 2}, []byte("digest"))
+		otherCW, _ := cws.clientWindow([]byte("other"))
+		otherCW.allocate(&pb.Request{ClientId: []byte("other"), ReqNo: 1}, []byte("digest"))
+
+		var receipts []*CommitReceipt
+		cws.onCommit = map[string]func(*CommitReceipt){
+			"client": func(receipt *CommitReceipt) {
+				receipts = append(receipts, receipt)
+			},
+		}
+
+		cws.commit([]byte("client"), 2, 7, 3)
+		cws.commit([]byte("other"), 1, 8, 3)
+
+		Expect(receipts).To(Equal([]*CommitReceipt{
+			{ClientID: []byte("client"), ReqNo: 2, SeqNo: 7, Epoch: 3},
+		}))
+
+		status := cw.status()
+		Expect(status.Allocated[1]).To(Equal(uint64(2)))
+		Expect(status.Committed[1]).To(Equal(uint64(7)))
+		Expect(status.Committed[0]).To(BeZero())
+	})
 })
 
 var _ = Describe("clientWindow", func() {
@@ -32,7 +94,8 @@ var _ = Describe("clientWindow", func() {
 		lwm = 10
 		hwm = 20
 		networkConfig = &pb.NetworkConfig{
-			Nodes: []uint64{0},
+			Nodes:             []uint64{0},
+			ClientWindowWidth: 11,
 		}
 		myConfig = &Config{} // TODO, populate
 	})
@@ -94,6 +157,37 @@ var _ = Describe("clientWindow", func() {
 				Expect(cw.highWatermark).To(Equal(hwm + 5))
 			})
 		})
+
+		When("the client window width grows", func() {
+			It("moves the high watermark to the new width", func() {
+				cw.allocate(&pb.Request{ReqNo: 10}, []byte("digest"))
+				cw.clientRequests[0].committed = Uint64ToPtr(10)
+				cw.allocate(&pb.Request{ReqNo: 11}, []byte("digest"))
+
+				networkConfig.ClientWindowWidth = 20
+				cw.garbageCollect(10)
+				Expect(cw.lowWatermark).To(Equal(lwm + 1))
+				Expect(cw.highWatermark).To(Equal(lwm + 20))
+				Expect(cw.clientRequests).To(HaveLen(20))
+				Expect(cw.request(11)).NotTo(BeNil())
+				Expect(cw.request(lwm + 20)).To(BeNil())
+			})
+		})
+
+		When("the client window width shrinks", func() {
+			It("narrows the window without moving the high watermark backwards", func() {
+				cw.allocate(&pb.Request{ReqNo: 10}, []byte("digest"))
+				cw.clientRequests[0].committed = Uint64ToPtr(10)
+				cw.allocate(&pb.Request{ReqNo: 20}, []byte("digest"))
+
+				networkConfig.ClientWindowWidth = 5
+				cw.garbageCollect(10)
+				Expect(cw.lowWatermark).To(Equal(lwm + 1))
+				Expect(cw.highWatermark).To(Equal(hwm))
+				Expect(cw.clientRequests).To(HaveLen(10))
+				Expect(cw.request(20)).NotTo(BeNil())
+			})
+		})
 	})
 
 	Context("allocate", func() {
@@ -17,6 +17,11 @@ type Config struct {
 	// pieces of data should be converted into a batch and consented on
 	BatchParameters BatchParameters
 
+	// ClientWeights optionally assigns weights to clients, keyed by client ID.
+	// When proposing, each client receives a share of the bucket proportional
+	// to its weight.  Clients without a (positive) weight have a weight of 1.
+	ClientWeights map[string]int
+
 	// HeartbeatTicks is the number of ticks before a heartbeat is emitted
 	// by a leader.
 	HeartbeatTicks int
@@ -30,12 +35,51 @@ type Config struct {
 	// than 1, as rebroadcast ticks are computed as half this value.
 	NewEpochTimeoutTicks int
 
-	// BufferSize is the number of messages buffered waiting for this node
-	// to process. If buffer is full, oldest message will be dropped to
-	// make room for new messages
+	// MaxNewEpochTimeoutTicks caps the new epoch timeout, which doubles for
+	// each consecutive epoch which fails to commit, and resets once an epoch
+	// commits.  Zero caps the timeout at 32 times NewEpochTimeoutTicks.
+	MaxNewEpochTimeoutTicks int
+
+	// OutOfOrderDelivery allows the Preprepare, Prepare, Commit, and Checkpoint
+	// messages of other nodes to be applied in any order, so long as they are
+	// within the active epoch and watermarks.  Otherwise, they are buffered until
+	// they may be applied in sequence order, which suits in order transports
+	// like TCP, but causes a lost message to stall its bucket.
+	OutOfOrderDelivery bool
+
+	// BufferSize is the number of messages of each kind buffered from each
+	// node waiting for this node to process.  Preprepares, Prepares, and
+	// Commits, Checkpoints, client request messages, and epoch change messages
+	// are buffered separately.  If a buffer is full, its oldest message will
+	// be dropped to make room for new messages.
 	BufferSize int
+
+	// BufferSizeBytes is the total size of the messages of each kind buffered
+	// from each node, beyond which the oldest message of that kind is dropped,
+	// as with BufferSize.  Zero disables the limit.
+	BufferSizeBytes int
 }
 
+// BatchParameters determines when the requests queued for a bucket which
+// this node leads are cut into a batch.  A batch is cut as soon as any of
+// the enabled conditions is met.
 type BatchParameters struct {
+	// CutSizeBytes is the total size of request data at which the queued
+	// requests are cut into a batch.
 	CutSizeBytes int
+
+	// CutSizeRequests is the number of queued requests at which they are
+	// cut into a batch.  Zero disables cutting on request count.
+	CutSizeRequests int
+
+	// MaxSizeBytes is the maximum total size of request data in a batch.
+	// If adding a request to the queue would exceed it, the queue is first
+	// cut into a batch.  A single request larger than this is still batched,
+	// alone.  Zero disables the limit.
+	MaxSizeBytes int
+
+	// BatchTimeoutTicks is the number of ticks after which a non-empty queue
+	// is cut into a batch, regardless of its size.  Zero disables the timeout,
+	// in which case partial batches are only cut on heartbeats.
+	BatchTimeoutTicks int
 }
@@ -7,10 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package mirbft
 
 import (
+	"bytes"
 	"fmt"
 
 	pb "github.com/IBM/mirbft/mirbftpb"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 // epochConfig is the information required by the various
@@ -54,20 +56,38 @@ func (ec *epochConfig) seqToColumn(seqNo uint64) uint64 {
 	return (seqNo-uint64(ec.initialSequence))/uint64(len(ec.buckets)) + 1
 }
 
+func (ec *epochConfig) colBucketToSeq(column uint64, bucket BucketID) uint64 {
+	return ec.initialSequence + (column-1)*uint64(len(ec.buckets)) + uint64(bucket)
+}
+
 /*
 func (ec *epochConfig) seqToBucketColumn(seqNo uint64) (BucketID, uint64) {
 	return ec.seqToBucket(seqNo), ec.seqToColumn(seqNo)
 }
+*/
 
-func (ec *epochConfig) colBucketToSeq(column uint64, bucket BucketID) uint64 {
-	return ec.initialSequence + (column-1)*uint64(len(ec.buckets)) + uint64(bucket)
+// summary returns the parts of the config from which it may be reconstructed
+// via parseEpochConfig, as reported by epoch changes.
+func (ec *epochConfig) summary() *pb.EpochConfig {
+	return &pb.EpochConfig{
+		Number: ec.number,
+		StartingCheckpoint: &pb.Checkpoint{
+			SeqNo: ec.initialSequence - 1,
+		},
+		Leaders: ec.leaders,
+	}
 }
-*/
 
 func (ec *epochConfig) logWidth() int {
 	return 3 * int(ec.networkConfig.CheckpointInterval)
 }
 
+// fetchReply records a retransmission of sequence state to a node.
+type fetchReply struct {
+	seqNo uint64
+	tick  int
+}
+
 type epoch struct {
 	// config contains the static components of the epoch
 	config   *epochConfig
@@ -85,16 +105,19 @@ type epoch struct {
 
 	lastCommittedAtTick uint64
 	ticksSinceProgress  int
+	ticks               int
+
+	// fetchReplies is the last sequence state we retransmitted to each
+	// node, so that we may throttle how often we do so.
+	fetchReplies map[NodeID]fetchReply
 
 	checkpoints       []*checkpoint
 	checkpointTracker *checkpointTracker
 }
 
-// newEpoch creates a new epoch.  It uses the supplied initial checkpoints until
-// new checkpoint windows are created using the given epochConfig.  The initialCheckpoint
-// windows may be empty, of length 1, or length 2.
-func newEpoch(persisted *persisted, newEpochConfig *pb.EpochConfig, checkpointTracker *checkpointTracker, clientWindows *clientWindows, networkConfig *pb.NetworkConfig, myConfig *Config) *epoch {
-
+// parseEpochConfig computes the bucket assignment for an epoch config agreed
+// upon by the network.
+func parseEpochConfig(networkConfig *pb.NetworkConfig, newEpochConfig *pb.EpochConfig) *epochConfig {
 	config := &epochConfig{
 		number:            newEpochConfig.Number,
 		initialSequence:   newEpochConfig.StartingCheckpoint.SeqNo + 1,
@@ -121,6 +144,15 @@ func newEpoch(persisted *persisted, newEpochConfig *pb.EpochConfig, checkpointTr
 		}
 	}
 
+	return config
+}
+
+// newEpoch creates a new epoch.  It uses the supplied initial checkpoints until
+// new checkpoint windows are created using the given epochConfig.  The initialCheckpoint
+// windows may be empty, of length 1, or length 2.
+func newEpoch(persisted *persisted, newEpochConfig *pb.EpochConfig, checkpointTracker *checkpointTracker, clientWindows *clientWindows, networkConfig *pb.NetworkConfig, myConfig *Config) *epoch {
+	config := parseEpochConfig(networkConfig, newEpochConfig)
+
 	lowestUnallocated := make([]int, len(config.buckets))
 	for i := range lowestUnallocated {
 		lowestUnallocated[i] = i + config.logWidth() // The first seq for the bucket beyond our watermarks
@@ -188,6 +220,7 @@ func newEpoch(persisted *persisted, newEpochConfig *pb.EpochConfig, checkpointTr
 		sequences:         sequences,
 		lowestUnallocated: lowestUnallocated,
 		lowestUncommitted: lowestUncommitted,
+		fetchReplies:      map[NodeID]fetchReply{},
 	}
 }
 
@@ -214,6 +247,10 @@ func (e *epoch) applyPreprepareMsg(source NodeID, seqNo uint64, batch []*pb.Requ
 		return seq.applyPrepareMsg(source, seq.digest)
 	}
 
+	if e.myConfig.OutOfOrderDelivery {
+		return e.applyOutOfOrderPreprepare(seq, bucketID, batch)
+	}
+
 	defer func() {
 		e.lowestUnallocated[int(bucketID)] += len(e.config.buckets)
 	}()
@@ -225,6 +262,24 @@ func (e *epoch) applyPreprepareMsg(source NodeID, seqNo uint64, batch []*pb.Requ
 	return seq.allocate(batch)
 }
 
+// applyOutOfOrderPreprepare allocates a sequence for a preprepare which may
+// have arrived before those preceding it in the bucket.  The lowest unallocated
+// sequence of the bucket advances once any gap before it is filled.
+func (e *epoch) applyOutOfOrderPreprepare(seq *sequence, bucketID BucketID, batch []*pb.RequestAck) *Actions {
+	if seq.state != Uninitialized {
+		// Already allocated, for instance by a previous epoch change
+		return &Actions{}
+	}
+
+	actions := seq.allocate(batch)
+
+	for e.lowestUnallocated[int(bucketID)] < len(e.sequences) && e.sequences[e.lowestUnallocated[int(bucketID)]].state != Uninitialized {
+		e.lowestUnallocated[int(bucketID)] += len(e.config.buckets)
+	}
+
+	return actions
+}
+
 func (e *epoch) applyPrepareMsg(source NodeID, seqNo uint64, digest []byte) *Actions {
 	seq, _, err := e.getSequence(seqNo)
 	if err != nil {
@@ -249,33 +304,101 @@ func (e *epoch) applyCommitMsg(source NodeID, seqNo uint64, digest []byte) *Acti
 	actions := &Actions{}
 
 	for e.lowestUncommitted < len(e.sequences) {
-		if e.sequences[e.lowestUncommitted].state != Committed {
+		lowest := e.sequences[e.lowestUncommitted]
+		if lowest.state != Committed {
+			break
+		}
+
+		if err := e.persisted.setLastCommitted(lowest.seqNo); err != nil {
+			e.myConfig.Logger.Error("could not commit sequence", zap.Error(err))
 			break
 		}
 
 		actions.Commits = append(actions.Commits, &Commit{
-			QEntry:     e.sequences[e.lowestUncommitted].qEntry,
-			Checkpoint: e.sequences[e.lowestUncommitted].seqNo%uint64(e.config.networkConfig.CheckpointInterval) == 0,
+			QEntry:     lowest.qEntry,
+			Checkpoint: lowest.seqNo%uint64(e.config.networkConfig.CheckpointInterval) == 0,
 		})
-		for _, reqForward := range e.sequences[e.lowestUncommitted].qEntry.Requests {
-			cw, ok := e.clientWindows.clientWindow(reqForward.Request.ClientId)
-			if !ok {
-				panic("we never should have committed this without the client available")
-			}
-			cw.request(reqForward.Request.ReqNo).committed = &seqNo
+		for _, reqForward := range lowest.qEntry.Requests {
+			e.clientWindows.commit(reqForward.Request.ClientId, reqForward.Request.ReqNo, lowest.seqNo, e.config.number)
 		}
 
-		e.persisted.setLastCommitted(e.sequences[e.lowestUncommitted].seqNo)
 		e.lowestUncommitted++
 	}
 
 	return actions
 }
 
+// applyFetchSequenceStateMsg retransmits to the source the messages we have
+// sent for the sequence, and our checkpoints, as the source has stalled
+// waiting on this sequence and may have lost some of them.  A correct node
+// only asks once per fetch interval, so we ignore any request which does not
+// advance beyond the last sequence we retransmitted to the source within it.
+func (e *epoch) applyFetchSequenceStateMsg(source NodeID, seqNo uint64) *Actions {
+	if seqNo > e.highWatermark() {
+		// We have nothing beyond the watermarks, so treat every such
+		// request alike for throttling.
+		seqNo = e.highWatermark() + 1
+	}
+
+	if last, ok := e.fetchReplies[source]; ok && seqNo <= last.seqNo && e.ticks < last.tick+e.fetchInterval() {
+		return &Actions{}
+	}
+	e.fetchReplies[source] = fetchReply{
+		seqNo: seqNo,
+		tick:  e.ticks,
+	}
+
+	actions := &Actions{}
+
+	for _, cp := range e.checkpoints {
+		if cp.myValue == nil {
+			continue
+		}
+
+		checkpoint := &pb.Checkpoint{
+			SeqNo: cp.seqNo,
+			Value: cp.myValue,
+		}
+
+		if e.config.networkConfig.RequireSignatures {
+			if cp.mySigned == nil || !bytes.Equal(cp.mySigned.Value, cp.myValue) {
+				// We have not yet signed this checkpoint
+				continue
+			}
+			checkpoint = cp.mySigned
+		}
+
+		actions.Unicast = append(actions.Unicast, Unicast{
+			Target: uint64(source),
+			Msg: &pb.Msg{
+				Type: &pb.Msg_Checkpoint{
+					Checkpoint: checkpoint,
+				},
+			},
+		})
+	}
+
+	if seqNo < e.lowWatermark() || seqNo > e.highWatermark() {
+		// We have nothing for this sequence, though the source
+		// may catch up using our checkpoints
+		return actions
+	}
+
+	seq, _, _ := e.getSequence(seqNo)
+	for _, msg := range seq.sentMsgs() {
+		actions.Unicast = append(actions.Unicast, Unicast{
+			Target: uint64(source),
+			Msg:    msg,
+		})
+	}
+
+	return actions
+}
+
 func (e *epoch) moveWatermarks() *Actions {
 	ci := int(e.config.networkConfig.CheckpointInterval)
 
-	for len(e.checkpoints) >= 4 && e.checkpoints[1].stable {
+	for len(e.checkpoints) >= 4 && e.highestStableCheckpoint() > 0 {
 		e.checkpoints = e.checkpoints[1:]
 		e.sequences = e.sequences[ci:]
 		e.lowestUncommitted -= ci
@@ -290,7 +413,7 @@ func (e *epoch) moveWatermarks() *Actions {
 	// If this epoch is ending, don't allocate new sequences
 	if lastCW.seqNo == e.config.plannedExpiration {
 		e.ending = true
-		return &Actions{}
+		return e.drainProposer()
 	}
 
 	for len(e.checkpoints) < 4 {
@@ -305,9 +428,27 @@ func (e *epoch) moveWatermarks() *Actions {
 		e.sequences = append(e.sequences, newSequence(owner, epoch, seqNo, e.clientWindows, e.persisted, e.config.networkConfig, e.myConfig))
 	}
 
+	if e.highestStableCheckpoint() > 0 {
+		// A later checkpoint became stable first, keep moving
+		return e.moveWatermarks()
+	}
+
 	return e.drainProposer()
 }
 
+// highestStableCheckpoint returns the index of the highest stable checkpoint
+// in the watermarks.  When messages are delivered out of order, a checkpoint
+// may become stable before those preceding it, which implies they are stable
+// too.
+func (e *epoch) highestStableCheckpoint() int {
+	for i := len(e.checkpoints) - 1; i > 0; i-- {
+		if e.checkpoints[i].stable {
+			return i
+		}
+	}
+	return 0
+}
+
 func (e *epoch) drainProposer() *Actions {
 	actions := &Actions{}
 
@@ -359,15 +500,51 @@ func (e *epoch) applyProcessResult(seqNo uint64, digest []byte) *Actions {
 	return seq.applyProcessResult(digest)
 }
 
+// fetchInterval is the number of ticks between requests for the state of a
+// stalled sequence.
+func (e *epoch) fetchInterval() int {
+	interval := e.myConfig.SuspectTicks / 2
+	if interval == 0 {
+		interval = 1
+	}
+	return interval
+}
+
+// stalledSeqNo returns the lowest uncommitted sequence, or if all are committed,
+// the first beyond the watermarks, once the epoch has made no progress for over
+// half the suspect ticks, and again every half suspect ticks thereafter.  Even
+// once we suspect the epoch, the rest of the network may not, so we continue
+// to fetch the messages we might have lost.
+func (e *epoch) stalledSeqNo() (uint64, bool) {
+	interval := e.fetchInterval()
+
+	if e.ticksSinceProgress <= e.myConfig.SuspectTicks/2 || (e.ticksSinceProgress-e.myConfig.SuspectTicks/2-1)%interval != 0 {
+		return 0, false
+	}
+
+	if e.lowestUncommitted < len(e.sequences) {
+		return e.sequences[e.lowestUncommitted].seqNo, true
+	}
+
+	return e.highWatermark() + 1, true
+}
+
 func (e *epoch) tick() *Actions {
+	actions := &Actions{}
+
+	e.ticks++
+
+	if e.proposer.tick() {
+		actions.Append(e.drainProposer())
+	}
+
 	if e.lowestUncommitted < len(e.sequences) && e.sequences[e.lowestUncommitted].seqNo != e.lastCommittedAtTick+1 {
 		e.ticksSinceProgress = 0
 		e.lastCommittedAtTick = e.sequences[e.lowestUncommitted].seqNo - 1
-		return &Actions{}
+		return actions
 	}
 
 	e.ticksSinceProgress++
-	actions := &Actions{}
 
 	if e.ticksSinceProgress > e.myConfig.SuspectTicks {
 		actions.Append(&Actions{
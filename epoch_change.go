@@ -15,6 +15,7 @@ import (
 	"github.com/golang/protobuf/proto"
 
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 type epochTargetState int
@@ -42,23 +43,27 @@ type epochTarget struct {
 	strongChanges map[NodeID]*parsedEpochChange
 	echos         map[*pb.EpochConfig]map[NodeID]struct{}
 	readies       map[*pb.EpochConfig]map[NodeID]struct{}
-	suspicions    map[NodeID]struct{}
 
 	persisted       *persisted
 	myNewEpoch      *pb.NewEpoch // The NewEpoch msg we computed from the epoch changes we know of
 	myEpochChange   *parsedEpochChange
-	myLeaderChoice  []uint64        // Set along with myEpochChange
+	myEchoConfig    *pb.EpochConfig // The config we have persisted and echoed, if any
 	leaderNewEpoch  *pb.NewEpoch    // The NewEpoch msg we received directly from the leader
+	gracefulConfig  *pb.EpochConfig // The config we expect, if the preceding epoch ended gracefully
 	networkNewEpoch *pb.EpochConfig // The NewEpoch msg as received via the bracha broadcast
 	isLeader        bool
 
-	networkConfig *pb.NetworkConfig
-	myConfig      *Config
-	batchTracker  *batchTracker
-	clientWindows *clientWindows
+	fetchingRequests map[string]struct{} // The digests of the requests we have asked the network for
+
+	networkConfig     *pb.NetworkConfig
+	myConfig          *Config
+	oddities          *oddities
+	batchTracker      *batchTracker
+	clientWindows     *clientWindows
+	checkpointTracker *checkpointTracker
 }
 
-func (et *epochTarget) constructNewEpoch(newLeaders []uint64, nc *pb.NetworkConfig) *pb.NewEpoch {
+func (et *epochTarget) constructNewEpoch(nc *pb.NetworkConfig) *pb.NewEpoch {
 	filteredStrongChanges := map[NodeID]*parsedEpochChange{}
 	for nodeID, change := range et.strongChanges {
 		if change.underlying == nil {
@@ -71,13 +76,14 @@ func (et *epochTarget) constructNewEpoch(newLeaders []uint64, nc *pb.NetworkConf
 		return nil
 	}
 
-	config := constructNewEpochConfig(nc, newLeaders, filteredStrongChanges)
+	config := constructNewEpochConfig(nc, filteredStrongChanges)
 	if config == nil {
 		return nil
 	}
+	config.Leaders = chooseLeaders(nc, previousEpochConfig(nc, config.Number, filteredStrongChanges), config)
 
-	remoteChanges := make([]*pb.NewEpoch_RemoteEpochChange, 0, len(et.changes))
-	for nodeID := range et.strongChanges {
+	remoteChanges := make([]*pb.NewEpoch_RemoteEpochChange, 0, len(filteredStrongChanges))
+	for nodeID := range filteredStrongChanges {
 		remoteChanges = append(remoteChanges, &pb.NewEpoch_RemoteEpochChange{
 			NodeId: uint64(nodeID),
 			Digest: et.changes[nodeID].strongCert,
@@ -91,11 +97,14 @@ func (et *epochTarget) constructNewEpoch(newLeaders []uint64, nc *pb.NetworkConf
 }
 
 func (et *epochTarget) verifyNewEpochState() *Actions {
+	if len(et.leaderNewEpoch.EpochChanges) == 0 {
+		return et.verifyGracefulNewEpoch()
+	}
+
 	epochChanges := map[NodeID]*parsedEpochChange{}
 	for _, remoteEpochChange := range et.leaderNewEpoch.EpochChanges {
 		if _, ok := epochChanges[NodeID(remoteEpochChange.NodeId)]; ok {
-			// TODO, references multiple epoch changes from the same node, malformed, log oddity
-			return &Actions{}
+			return et.rejectNewEpoch(errors.Errorf("references multiple epoch changes from node %d", remoteEpochChange.NodeId))
 		}
 
 		change, ok := et.changes[NodeID(remoteEpochChange.NodeId)]
@@ -112,12 +121,23 @@ func (et *epochTarget) verifyNewEpochState() *Actions {
 		epochChanges[NodeID(remoteEpochChange.NodeId)] = parsedChange
 	}
 
-	// TODO, do we need to try to validate the leader set?
+	if len(epochChanges) < intersectionQuorum(et.networkConfig) {
+		return et.rejectNewEpoch(errors.Errorf("references only %d epoch changes", len(epochChanges)))
+	}
 
-	newEpochConfig := constructNewEpochConfig(et.networkConfig, et.leaderNewEpoch.Config.Leaders, epochChanges)
+	newEpochConfig := constructNewEpochConfig(et.networkConfig, epochChanges)
+	if newEpochConfig == nil {
+		return et.rejectNewEpoch(errors.Errorf("referenced epoch changes do not determine a config"))
+	}
+	newEpochConfig.Leaders = chooseLeaders(et.networkConfig, previousEpochConfig(et.networkConfig, newEpochConfig.Number, epochChanges), newEpochConfig)
 
 	if !proto.Equal(newEpochConfig, et.leaderNewEpoch.Config) {
-		// TODO byzantine, log oddity
+		return et.rejectNewEpoch(errors.Errorf("config does not match the one derived from the referenced epoch changes"))
+	}
+
+	if et.myEchoConfig != nil && !proto.Equal(et.myEchoConfig, newEpochConfig) {
+		// We echoed a different config for this epoch before restarting,
+		// echoing this one would be equivocation.
 		return &Actions{}
 	}
 
@@ -126,13 +146,75 @@ func (et *epochTarget) verifyNewEpochState() *Actions {
 	return et.advanceState()
 }
 
+// verifyGracefulNewEpoch checks a NewEpoch which references no epoch changes.
+// Such a NewEpoch is only valid if the preceding epoch ended gracefully for us
+// as well, in which case we may compute the expected config ourselves.
+func (et *epochTarget) verifyGracefulNewEpoch() *Actions {
+	if et.gracefulConfig == nil {
+		// Either the primary is lying, or the preceding epoch has
+		// not yet ended for us.
+		return &Actions{}
+	}
+
+	if !proto.Equal(et.gracefulConfig, et.leaderNewEpoch.Config) {
+		return et.rejectNewEpoch(errors.Errorf("config does not match the one expected after a graceful epoch change"))
+	}
+
+	if et.myEchoConfig != nil && !proto.Equal(et.myEchoConfig, et.gracefulConfig) {
+		return &Actions{}
+	}
+
+	et.state = fetching
+
+	return et.advanceState()
+}
+
+// leader returns the node responsible for sending the NewEpoch for this target.
+func (et *epochTarget) leader() NodeID {
+	return NodeID(et.networkConfig.Nodes[et.number%uint64(len(et.networkConfig.Nodes))])
+}
+
+// rejectNewEpoch records that the NewEpoch from the leader is invalid, and
+// discards it.
+func (et *epochTarget) rejectNewEpoch(err error) *Actions {
+	et.oddities.rejectedMessage(et.leader(), &pb.Msg{
+		Type: &pb.Msg_NewEpoch{
+			NewEpoch: et.leaderNewEpoch,
+		},
+	}, err)
+	et.leaderNewEpoch = nil
+	et.state = pending
+	return &Actions{}
+}
+
+// clientWindowsLag handles a request, in a batch the network agreed upon, which
+// our client windows do not cover.  A correct node accepted the request, so our
+// client windows must lag the network's, and we may only proceed once state
+// transfer has caught us up.  If the network has not yet agreed on a checkpoint
+// to transfer to, we wait, and eventually suspect this epoch.
+func (et *epochTarget) clientWindowsLag(seqNo uint64, requestAck *pb.RequestAck, err error) *Actions {
+	et.oddities.clientWindowsLag(seqNo, requestAck, err)
+
+	target := et.checkpointTracker.networkStableTarget(et.persisted.lastCommitted + 1)
+	if target == nil {
+		return &Actions{}
+	}
+
+	return et.checkpointTracker.requestStateTransfer(target)
+}
+
 func (et *epochTarget) fetchNewEpochState() *Actions {
 	actions := &Actions{}
 
 	newEpochConfig := et.leaderNewEpoch.Config
 
 	if newEpochConfig.StartingCheckpoint.SeqNo > et.persisted.lastCommitted {
-		panic("we need checkpoint state transfer to handle this case")
+		// We must first transfer state up to the starting checkpoint,
+		// once complete, the state machine will resume fetching.
+		return et.checkpointTracker.requestStateTransfer(&StateTarget{
+			SeqNo: newEpochConfig.StartingCheckpoint.SeqNo,
+			Value: newEpochConfig.StartingCheckpoint.Value,
+		})
 	}
 
 	fetchPending := false
@@ -158,7 +240,9 @@ func (et *epochTarget) fetchNewEpochState() *Actions {
 		}
 
 		if len(sources) < someCorrectQuorum(et.networkConfig) {
-			panic(fmt.Sprintf("dev only, should never be true, we only found %d sources for seqno=%d with digest=%x", len(sources), seqNo, digest))
+			// The new epoch was verified against these epoch changes, so this
+			// should be impossible, but we reject it rather than trust it.
+			return et.rejectNewEpoch(errors.Errorf("only %d sources for seq_no=%d with digest=%x", len(sources), seqNo, digest))
 		}
 
 		batch, ok := et.batchTracker.getBatch(digest)
@@ -174,11 +258,23 @@ func (et *epochTarget) fetchNewEpochState() *Actions {
 		for _, requestAck := range batch.requestAcks {
 			cw, ok := et.clientWindows.clientWindow(requestAck.ClientId)
 			if !ok {
-				panic("unknown client, we need state transfer to handle this")
+				actions.Append(et.clientWindowsLag(seqNo, requestAck, errors.Errorf("client is unknown")))
+				fetchPending = true
+				continue
 			}
 
+			acked := true
 			for _, nodeID := range sources {
-				cw.ack(NodeID(nodeID), requestAck.ReqNo, requestAck.Digest)
+				if err := cw.ack(NodeID(nodeID), requestAck.ReqNo, requestAck.Digest); err != nil {
+					actions.Append(et.clientWindowsLag(seqNo, requestAck, err))
+					acked = false
+					break
+				}
+			}
+
+			if !acked {
+				fetchPending = true
+				continue
 			}
 
 			if cw.request(requestAck.ReqNo).digests[string(requestAck.Digest)].data != nil {
@@ -187,6 +283,11 @@ func (et *epochTarget) fetchNewEpochState() *Actions {
 
 			// We are missing this request data and must fetch before proceeding
 			fetchPending = true
+			if _, ok := et.fetchingRequests[string(requestAck.Digest)]; ok {
+				// Already requested, no need to ask again each time a fetch completes
+				continue
+			}
+			et.fetchingRequests[string(requestAck.Digest)] = struct{}{}
 			// TODO, perhaps only ask those who have it?
 			actions.Broadcast = append(actions.Broadcast, &pb.Msg{
 				Type: &pb.Msg_FetchRequest{
@@ -205,10 +306,10 @@ func (et *epochTarget) fetchNewEpochState() *Actions {
 	for i, digest := range newEpochConfig.FinalPreprepares {
 		seqNo := uint64(i) + newEpochConfig.StartingCheckpoint.SeqNo + 1
 		if len(digest) == 0 {
-			et.persisted.add(&pb.Persisted{Type: &pb.Persisted_Qentry{Qentry: &pb.QEntry{
+			actions.Append(et.persisted.add(&pb.Persisted{Type: &pb.Persisted_Qentry{Qentry: &pb.QEntry{
 				SeqNo: seqNo,
 				Epoch: et.leaderNewEpoch.Config.Number,
-			}}})
+			}}}))
 			continue
 		}
 
@@ -231,28 +332,43 @@ func (et *epochTarget) fetchNewEpochState() *Actions {
 			Requests: requests,
 		}
 
-		et.persisted.add(&pb.Persisted{Type: &pb.Persisted_Qentry{Qentry: qEntry}})
+		actions.Append(et.persisted.add(&pb.Persisted{Type: &pb.Persisted_Qentry{Qentry: qEntry}}))
 	}
 
-	return &Actions{
-		Broadcast: []*pb.Msg{
-			{
-				Type: &pb.Msg_NewEpochEcho{
-					NewEpochEcho: &pb.NewEpochEcho{
-						Config: et.leaderNewEpoch.Config,
-					},
-				},
+	if et.myEchoConfig == nil {
+		et.myEchoConfig = newEpochConfig
+		actions.Append(et.persisted.add(&pb.Persisted{Type: &pb.Persisted_Nentry{
+			Nentry: &pb.NEntry{
+				Config: newEpochConfig,
 			},
-		},
+		}}))
 	}
+
+	actions.Broadcast = append(actions.Broadcast, &pb.Msg{
+		Type: &pb.Msg_NewEpochEcho{
+			NewEpochEcho: &pb.NewEpochEcho{
+				Config: newEpochConfig,
+			},
+		},
+	})
+
+	return actions
 }
 
-func (et *epochTarget) tick() *Actions {
+// tick advances the timers of the epoch target, timeoutTicks is the number
+// of ticks to wait for a new epoch before suspecting it.
+func (et *epochTarget) tick(timeoutTicks uint64) *Actions {
+	et.stateTicks++
+
+	if et.state > pending && et.state < ready || et.gracefulConfig != nil && et.state == pending {
+		return et.tickNewEpoch(timeoutTicks)
+	}
+
 	switch et.state {
 	case prepending:
-		return et.tickPrepending()
+		return et.tickPrepending(timeoutTicks)
 	case pending:
-		return et.tickPending()
+		return et.tickPending(timeoutTicks)
 	default: // case done:
 	}
 
@@ -271,9 +387,10 @@ func (et *epochTarget) repeatEpochChangeBroadcast() *Actions {
 	}
 }
 
-func (et *epochTarget) tickPrepending() *Actions {
+func (et *epochTarget) tickPrepending(timeoutTicks uint64) *Actions {
 	if et.myNewEpoch == nil {
-		if et.stateTicks%uint64(et.myConfig.NewEpochTimeoutTicks/2) == 0 {
+		// Broadcast on the first tick, then every half timeout
+		if (et.stateTicks-1)%(timeoutTicks/2) == 0 {
 			return et.repeatEpochChangeBroadcast()
 		}
 
@@ -297,8 +414,8 @@ func (et *epochTarget) tickPrepending() *Actions {
 	return &Actions{}
 }
 
-func (et *epochTarget) tickPending() *Actions {
-	pendingTicks := et.stateTicks % uint64(et.myConfig.NewEpochTimeoutTicks)
+func (et *epochTarget) tickPending(timeoutTicks uint64) *Actions {
+	pendingTicks := et.stateTicks % timeoutTicks
 	if et.isLeader {
 		// resend the new-view if others perhaps missed it
 		if pendingTicks%2 == 0 {
@@ -333,6 +450,44 @@ func (et *epochTarget) tickPending() *Actions {
 	return &Actions{}
 }
 
+// tickNewEpoch resends the NewEpoch if we are the leader, and otherwise
+// suspects the new epoch if it has not begun within the timeout.  Once the
+// new epoch is known, it may still stall, for instance while we verify or
+// fetch the state it references, and we must not wait on it forever.
+func (et *epochTarget) tickNewEpoch(timeoutTicks uint64) *Actions {
+	if et.isLeader {
+		if et.state < echoing && et.stateTicks%2 == 0 {
+			return &Actions{
+				Broadcast: []*pb.Msg{
+					{
+						Type: &pb.Msg_NewEpoch{
+							NewEpoch: et.myNewEpoch,
+						},
+					},
+				},
+			}
+		}
+
+		return &Actions{}
+	}
+
+	if et.stateTicks%timeoutTicks == 0 {
+		return &Actions{
+			Broadcast: []*pb.Msg{
+				{
+					Type: &pb.Msg_Suspect{
+						Suspect: &pb.Suspect{
+							Epoch: et.number,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return &Actions{}
+}
+
 func (et *epochTarget) applyEpochChangeAckMsg(source NodeID, origin NodeID, msg *pb.EpochChange) *Actions {
 	// TODO, make sure nodemsgs prevents us from receiving an epoch change twice
 	hashRequest := &HashRequest{
@@ -381,13 +536,14 @@ func (et *epochTarget) checkEpochQuorum() *Actions {
 		return &Actions{}
 	}
 
-	et.myNewEpoch = et.constructNewEpoch(et.myLeaderChoice, et.networkConfig)
+	et.myNewEpoch = et.constructNewEpoch(et.networkConfig)
 	if et.myNewEpoch == nil {
 
 		return &Actions{}
 	}
 
 	et.state = pending
+	et.stateTicks = 0
 
 	if et.isLeader {
 		return &Actions{
@@ -437,28 +593,16 @@ func (et *epochTarget) checkNewEpochEchoQuorum() *Actions {
 
 		et.state = readying
 
-		for i, digest := range config.FinalPreprepares {
-			seqNo := uint64(i) + config.StartingCheckpoint.SeqNo + 1
-			et.persisted.add(&pb.Persisted{Type: &pb.Persisted_Pentry{
-				Pentry: &pb.PEntry{
-					SeqNo:  seqNo,
-					Epoch:  et.leaderNewEpoch.Config.Number,
-					Digest: digest,
-				},
-			}})
-		}
-
-		return &Actions{
-			Broadcast: []*pb.Msg{
-				{
-					Type: &pb.Msg_NewEpochReady{
-						NewEpochReady: &pb.NewEpochReady{
-							Config: config,
-						},
-					},
+		actions := et.persistReady(config)
+		actions.Broadcast = append(actions.Broadcast, &pb.Msg{
+			Type: &pb.Msg_NewEpochReady{
+				NewEpochReady: &pb.NewEpochReady{
+					Config: config,
 				},
 			},
-		}
+		})
+
+		return actions
 	}
 
 	return &Actions{}
@@ -497,22 +641,47 @@ func (et *epochTarget) applyNewEpochReadyMsg(source NodeID, msg *pb.NewEpochRead
 	if et.state < readying {
 		et.state = readying
 
-		return &Actions{
-			Broadcast: []*pb.Msg{
-				{
-					Type: &pb.Msg_NewEpochReady{
-						NewEpochReady: &pb.NewEpochReady{
-							Config: msg.Config,
-						},
-					},
+		actions := et.persistReady(msg.Config)
+		actions.Broadcast = append(actions.Broadcast, &pb.Msg{
+			Type: &pb.Msg_NewEpochReady{
+				NewEpochReady: &pb.NewEpochReady{
+					Config: msg.Config,
 				},
 			},
-		}
+		})
+
+		return actions
 	}
 
 	return et.advanceState()
 }
 
+// persistReady records the final preprepares of the config as prepared in
+// the new epoch, and that we are about to send a NewEpochReady for the config,
+// so that after a restart we do not rejoin this epoch change.
+func (et *epochTarget) persistReady(config *pb.EpochConfig) *Actions {
+	actions := &Actions{}
+	for i, digest := range config.FinalPreprepares {
+		seqNo := uint64(i) + config.StartingCheckpoint.SeqNo + 1
+		actions.Append(et.persisted.add(&pb.Persisted{Type: &pb.Persisted_Pentry{
+			Pentry: &pb.PEntry{
+				SeqNo:  seqNo,
+				Epoch:  config.Number,
+				Digest: digest,
+			},
+		}}))
+	}
+
+	actions.Append(et.persisted.add(&pb.Persisted{Type: &pb.Persisted_Nentry{
+		Nentry: &pb.NEntry{
+			Config: config,
+			Ready:  true,
+		},
+	}}))
+
+	return actions
+}
+
 func (et *epochTarget) checkNewEpochReadyQuorum() *Actions {
 	for config, msgReadies := range et.readies {
 		if len(msgReadies) < intersectionQuorum(et.networkConfig) {
@@ -526,23 +695,26 @@ func (et *epochTarget) checkNewEpochReadyQuorum() *Actions {
 
 		for i := range config.FinalPreprepares {
 			seqNo := uint64(i) + config.StartingCheckpoint.SeqNo + 1
+			if seqNo <= et.persisted.lastCommitted {
+				continue
+			}
 			qEntry := et.persisted.qSet[seqNo][config.Number]
 			if qEntry == nil {
 				panic("this shouldn't be possible once dev is done, but for now it's a nasty corner case")
 			}
-			if seqNo <= et.persisted.lastCommitted {
-				continue
+			if err := et.persisted.setLastCommitted(seqNo); err != nil {
+				et.myConfig.Logger.Error("could not commit sequence", zap.Error(err))
+				break
 			}
+
 			commits = append(commits, &Commit{
 				Checkpoint: seqNo%uint64(et.networkConfig.CheckpointInterval) == 0,
 				QEntry:     qEntry,
 			})
 
 			for _, reqForward := range qEntry.Requests {
-				cw, _ := et.clientWindows.clientWindow(reqForward.Request.ClientId)
-				cw.request(reqForward.Request.ReqNo).committed = &seqNo
+				et.clientWindows.commit(reqForward.Request.ClientId, reqForward.Request.ReqNo, seqNo, config.Number)
 			}
-			et.persisted.setLastCommitted(seqNo)
 		}
 
 		return &Actions{
@@ -587,54 +759,133 @@ func (et *epochTarget) advanceState() *Actions {
 
 type epochChanger struct {
 	lastActiveEpoch    uint64
+	failedEpochs       uint64       // The number of consecutive epochs which have failed since one last committed
+	lastActiveConfig   *epochConfig // nil if we do not know the config of the last active epoch
 	pendingEpochTarget *epochTarget
-	persisted          *persisted
-	networkConfig      *pb.NetworkConfig
-	myConfig           *Config
-	batchTracker       *batchTracker
-	clientWindows      *clientWindows
-	checkpointTracker  *checkpointTracker
-	targets            map[uint64]*epochTarget
+
+	// suspicions is the highest epoch suspected by each node.
+	suspicions map[NodeID]uint64
+
+	// futureTargets is the one epoch beyond the pending target which each
+	// node may reference.
+	futureTargets map[NodeID]uint64
+
+	persisted         *persisted
+	networkConfig     *pb.NetworkConfig
+	myConfig          *Config
+	oddities          *oddities
+	batchTracker      *batchTracker
+	clientWindows     *clientWindows
+	checkpointTracker *checkpointTracker
+	targets           map[uint64]*epochTarget
 }
 
+// defaultMaxNewEpochTimeoutMultiplier bounds the growth of the new epoch
+// timeout when Config.MaxNewEpochTimeoutTicks is not set.
+const defaultMaxNewEpochTimeoutMultiplier = 32
+
 func (ec *epochChanger) tick() *Actions {
-	return ec.pendingEpochTarget.tick()
+	return ec.pendingEpochTarget.tick(ec.newEpochTimeoutTicks())
 }
 
-func (ec *epochChanger) target(epoch uint64) *epochTarget {
-	// TODO, we need to garbage collect in responst to
-	// spammy suspicions and epoch changes.  Basically
-	// if every suspect/epoch change has a corresponding
-	// higher epoch sibling for that node in a later epoch
-	// then we should clean up.
+// newEpochTimeoutTicks returns the number of ticks to wait for a new epoch
+// before suspecting it.  As in PBFT, the timeout doubles with each consecutive
+// failed epoch, so that the network eventually waits long enough for a correct
+// leader, however slow the network.
+func (ec *epochChanger) newEpochTimeoutTicks() uint64 {
+	timeoutTicks := uint64(ec.myConfig.NewEpochTimeoutTicks)
+
+	maxTimeoutTicks := uint64(ec.myConfig.MaxNewEpochTimeoutTicks)
+	if maxTimeoutTicks == 0 {
+		maxTimeoutTicks = defaultMaxNewEpochTimeoutMultiplier * timeoutTicks
+	}
+
+	for i := uint64(0); i < ec.failedEpochs && timeoutTicks < maxTimeoutTicks; i++ {
+		timeoutTicks *= 2
+	}
+
+	if timeoutTicks > maxTimeoutTicks {
+		timeoutTicks = maxTimeoutTicks
+	}
 
+	return timeoutTicks
+}
+
+// epochCommitted resets the new epoch timeout, as the active epoch has
+// committed a sequence.
+func (ec *epochChanger) epochCommitted() {
+	ec.failedEpochs = 0
+}
+
+func (ec *epochChanger) target(epoch uint64) *epochTarget {
 	target, ok := ec.targets[epoch]
 	if !ok {
 		target = &epochTarget{
-			number:        epoch,
-			suspicions:    map[NodeID]struct{}{},
-			changes:       map[NodeID]*epochChange{},
-			strongChanges: map[NodeID]*parsedEpochChange{},
-			echos:         map[*pb.EpochConfig]map[NodeID]struct{}{},
-			readies:       map[*pb.EpochConfig]map[NodeID]struct{}{},
-			isLeader:      epoch%uint64(len(ec.networkConfig.Nodes)) == ec.myConfig.ID,
-			persisted:     ec.persisted,
-			networkConfig: ec.networkConfig,
-			myConfig:      ec.myConfig,
-			batchTracker:  ec.batchTracker,
-			clientWindows: ec.clientWindows,
+			number:            epoch,
+			changes:           map[NodeID]*epochChange{},
+			strongChanges:     map[NodeID]*parsedEpochChange{},
+			echos:             map[*pb.EpochConfig]map[NodeID]struct{}{},
+			readies:           map[*pb.EpochConfig]map[NodeID]struct{}{},
+			fetchingRequests:  map[string]struct{}{},
+			isLeader:          ec.networkConfig.Nodes[epoch%uint64(len(ec.networkConfig.Nodes))] == ec.myConfig.ID,
+			persisted:         ec.persisted,
+			networkConfig:     ec.networkConfig,
+			myConfig:          ec.myConfig,
+			oddities:          ec.oddities,
+			batchTracker:      ec.batchTracker,
+			clientWindows:     ec.clientWindows,
+			checkpointTracker: ec.checkpointTracker,
 		}
 		ec.targets[epoch] = target
 	}
 	return target
 }
 
-func (et *epochTarget) applySuspectMsg(source NodeID) {
-	et.suspicions[source] = struct{}{}
+// setLastActiveConfig records the config of the epoch which has most recently
+// become active, which determines the leaders of the epoch which follows it.
+func (ec *epochChanger) setLastActiveConfig(config *epochConfig) {
+	ec.lastActiveEpoch = config.number
+	ec.lastActiveConfig = config
+}
+
+// targetFor returns the epoch target for a message from the given node, or nil
+// if the message should be ignored.  Messages for epochs before the pending
+// target are stale.  So that a node cannot create unbounded epoch targets, each
+// node may reference only one epoch beyond the pending target, and once it
+// references a later epoch, the earlier one is superseded.
+func (ec *epochChanger) targetFor(source NodeID, epoch uint64) *epochTarget {
+	switch {
+	case epoch < ec.pendingEpochTarget.number:
+		return nil
+	case epoch == ec.pendingEpochTarget.number:
+		return ec.pendingEpochTarget
+	}
+
+	if futureEpoch, ok := ec.futureTargets[source]; ok {
+		switch {
+		case futureEpoch > epoch:
+			return nil
+		case futureEpoch < epoch:
+			ec.futureTargets[source] = epoch
+			ec.releaseTarget(futureEpoch)
+		}
+	} else {
+		ec.futureTargets[source] = epoch
+	}
 
-	if len(et.suspicions) >= intersectionQuorum(et.networkConfig) {
-		et.state = done
+	return ec.target(epoch)
+}
+
+// releaseTarget discards the target for a future epoch once no node
+// references it.
+func (ec *epochChanger) releaseTarget(epoch uint64) {
+	for _, futureEpoch := range ec.futureTargets {
+		if futureEpoch == epoch {
+			return
+		}
 	}
+
+	delete(ec.targets, epoch)
 }
 
 func (ec *epochChanger) setPendingTarget(target *epochTarget) {
@@ -643,19 +894,99 @@ func (ec *epochChanger) setPendingTarget(target *epochTarget) {
 			delete(ec.targets, number)
 		}
 	}
+	for node, futureEpoch := range ec.futureTargets {
+		if futureEpoch <= target.number {
+			delete(ec.futureTargets, node)
+		}
+	}
 	ec.pendingEpochTarget = target
 }
 
+// suspectedEpoch returns the highest epoch which an intersection quorum of
+// nodes have suspected, if any.  A correct node only moves forward through the
+// epochs, so suspecting an epoch implies suspecting every epoch before it.
+func (ec *epochChanger) suspectedEpoch() (uint64, bool) {
+	quorum := intersectionQuorum(ec.networkConfig)
+	if len(ec.suspicions) < quorum {
+		return 0, false
+	}
+
+	epochs := make([]uint64, 0, len(ec.suspicions))
+	for _, epoch := range ec.suspicions {
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool {
+		return epochs[i] > epochs[j]
+	})
+
+	return epochs[quorum-1], true
+}
+
+// beginGracefulEpochChange begins the epoch following the given one, whose
+// planned expiration checkpoint has become stable.  Because every correct node
+// computes the same config for the new epoch, the NewEpoch need not reference
+// any epoch changes, and the new epoch begins without waiting for a timeout.
+func (ec *epochChanger) beginGracefulEpochChange(config *epochConfig, checkpoint *pb.Checkpoint) *Actions {
+	if ec.pendingEpochTarget.number != config.number {
+		// We have already moved beyond this epoch
+		return &Actions{}
+	}
+
+	target := ec.target(config.number + 1)
+	ec.setPendingTarget(target)
+	target.gracefulConfig = &pb.EpochConfig{
+		Number:             config.number + 1,
+		StartingCheckpoint: checkpoint,
+		Leaders:            chooseGracefulLeaders(ec.networkConfig, config),
+	}
+	target.myNewEpoch = &pb.NewEpoch{
+		Config: target.gracefulConfig,
+	}
+	target.state = pending
+
+	actions := &Actions{}
+	if target.isLeader {
+		actions.Broadcast = append(actions.Broadcast, &pb.Msg{
+			Type: &pb.Msg_NewEpoch{
+				NewEpoch: target.myNewEpoch,
+			},
+		})
+	}
+
+	// We may have already received the NewEpoch and its echos
+	actions.Append(target.advanceState())
+	return actions
+}
+
+// maxSuspectEpochLead is how far beyond the last active epoch a suspicion may
+// be before it is ignored.  A correct node only suspects the epoch it is in,
+// which is ahead of ours by at most the number of epochs which have failed
+// since we were last active.
+const maxSuspectEpochLead = 64
+
 func (ec *epochChanger) applySuspectMsg(source NodeID, epoch uint64) *pb.EpochChange {
-	target := ec.target(epoch)
-	target.applySuspectMsg(source)
-	if target.state < done {
+	if epoch > ec.lastActiveEpoch+maxSuspectEpochLead {
+		// TODO, log oddity
 		return nil
 	}
 
-	epochChange := ec.persisted.constructEpochChange(epoch+1, ec.checkpointTracker)
+	if suspected, ok := ec.suspicions[source]; ok && suspected >= epoch {
+		return nil
+	}
+	ec.suspicions[source] = epoch
+
+	failedEpoch, ok := ec.suspectedEpoch()
+	if !ok || failedEpoch < ec.pendingEpochTarget.number {
+		// We have already moved beyond this epoch, and must not
+		// construct a second epoch change for the next one.
+		return nil
+	}
 
-	newTarget := ec.target(epoch + 1)
+	epochChange := ec.persisted.constructEpochChange(failedEpoch+1, ec.lastActiveConfig, ec.checkpointTracker)
+
+	ec.failedEpochs++
+
+	newTarget := ec.target(failedEpoch + 1)
 	ec.setPendingTarget(newTarget)
 	var err error
 	newTarget.myEpochChange, err = newParsedEpochChange(epochChange)
@@ -663,64 +994,194 @@ func (ec *epochChanger) applySuspectMsg(source NodeID, epoch uint64) *pb.EpochCh
 		panic(errors.WithMessage(err, "could not parse the epoch change I generated"))
 	}
 
-	newTarget.myLeaderChoice = []uint64{ec.myConfig.ID}
-
 	return epochChange
 }
 
-/*
-func (ec *epochChanger) chooseLeaders(epochChange *parsedEpochChange) []uint64 {
-	if ec.lastActiveEpoch == nil {
-		panic("this shouldn't happen")
+// chooseLeaders deterministically selects the leaders for a new epoch, given
+// the config of the preceding epoch (if known) and the new epoch config as
+// constructed from the epoch changes.  If the preceding epoch prepared some but
+// not all of the sequences in the new epoch's window, the leader of the bucket
+// owning the lowest unprepared sequence is evicted.  If it prepared every
+// sequence, the cause of the failure is unclear, so its primary is evicted.
+// So that the leader set grows back over time, whenever the preceding epoch
+// made progress one previously evicted node is re-admitted.
+//
+// When the preceding config is not known (for instance because the preceding
+// epoch never became active), the leaders are every node except the primary
+// of the preceding epoch.
+func chooseLeaders(networkConfig *pb.NetworkConfig, previousConfig *epochConfig, newEpochConfig *pb.EpochConfig) []uint64 {
+	if previousConfig == nil {
+		if newEpochConfig.Number == 0 || len(networkConfig.Nodes) == 1 {
+			return networkConfig.Nodes
+		}
+
+		badNode := networkConfig.Nodes[(newEpochConfig.Number-1)%uint64(len(networkConfig.Nodes))]
+		leaders := make([]uint64, 0, len(networkConfig.Nodes)-1)
+		for _, node := range networkConfig.Nodes {
+			if node != badNode {
+				leaders = append(leaders, node)
+			}
+		}
+		return leaders
 	}
 
-	oldLeaders := ec.lastActiveEpoch.config.leaders
-	if len(oldLeaders) == 1 {
-		return []uint64{ec.myConfig.ID}
+	progressed := false
+	for _, digest := range newEpochConfig.FinalPreprepares {
+		if len(digest) != 0 {
+			progressed = true
+			break
+		}
 	}
 
-	// XXX the below logic is definitely wrong, it doesn't always result in a node
-	// being kicked.
+	badNode := networkConfig.Nodes[previousConfig.number%uint64(len(networkConfig.Nodes))]
+	for i, digest := range newEpochConfig.FinalPreprepares {
+		if len(digest) != 0 {
+			continue
+		}
+
+		seqNo := newEpochConfig.StartingCheckpoint.SeqNo + 1 + uint64(i)
+		if seqNo < previousConfig.initialSequence {
+			continue
+		}
+
+		badNode = uint64(previousConfig.buckets[previousConfig.seqToBucket(seqNo)])
+		break
+	}
+
+	previousLeaders := map[uint64]struct{}{}
+	for _, leader := range previousConfig.leaders {
+		previousLeaders[leader] = struct{}{}
+	}
 
-	var badNode uint64
-	if ec.lastActiveEpoch.config.number+1 == epochChange.underlying.NewEpoch {
-		var lowestEntry uint64
-		for i := epochChange.lowWatermark + 1; i < epochChange.lowWatermark+uint64(ec.networkConfig.CheckpointInterval)*2; i++ {
-			if _, ok := epochChange.pSet[i]; !ok {
-				lowestEntry = i
+	var evicted []uint64
+	for _, node := range networkConfig.Nodes {
+		if _, ok := previousLeaders[node]; !ok && node != badNode {
+			evicted = append(evicted, node)
+		}
+	}
+
+	var readmitted uint64
+	readmit := progressed && len(evicted) > 0
+	if readmit {
+		readmitted = evicted[newEpochConfig.Number%uint64(len(evicted))]
+	}
+
+	leaders := make([]uint64, 0, len(networkConfig.Nodes))
+	for _, node := range networkConfig.Nodes {
+		_, wasLeader := previousLeaders[node]
+		switch {
+		case readmit && node == readmitted:
+		case wasLeader && node != badNode:
+		default:
+			continue
+		}
+		leaders = append(leaders, node)
+	}
+
+	if len(leaders) == 0 {
+		// Never evict the last leader
+		return previousConfig.leaders
+	}
+
+	return leaders
+}
+
+// chooseGracefulLeaders selects the leaders for the epoch following one which
+// ended gracefully.  As the epoch made progress, all of its leaders are retained,
+// and one previously evicted node is re-admitted.  The bucket assignment rotates
+// with the epoch number regardless.
+func chooseGracefulLeaders(networkConfig *pb.NetworkConfig, previousConfig *epochConfig) []uint64 {
+	previousLeaders := map[uint64]struct{}{}
+	for _, leader := range previousConfig.leaders {
+		previousLeaders[leader] = struct{}{}
+	}
+
+	var evicted []uint64
+	for _, node := range networkConfig.Nodes {
+		if _, ok := previousLeaders[node]; !ok {
+			evicted = append(evicted, node)
+		}
+	}
+
+	if len(evicted) == 0 {
+		return previousConfig.leaders
+	}
+
+	readmitted := evicted[(previousConfig.number+1)%uint64(len(evicted))]
+
+	leaders := make([]uint64, 0, len(previousConfig.leaders)+1)
+	for _, node := range networkConfig.Nodes {
+		if _, ok := previousLeaders[node]; ok || node == readmitted {
+			leaders = append(leaders, node)
+		}
+	}
+
+	return leaders
+}
+
+// previousEpochConfig derives the config of the epoch preceding the new epoch
+// from the last active configs reported by the given epoch changes.  A config is
+// only trusted if it is reported by enough epoch changes that at least one of
+// them is correct.  As the config is derived only from the epoch changes, every
+// node which verifies a NewEpoch derives the same one, regardless of what it
+// knows locally.  If no such config is found, nil is returned.
+func previousEpochConfig(networkConfig *pb.NetworkConfig, newEpoch uint64, epochChanges map[NodeID]*parsedEpochChange) *epochConfig {
+	if newEpoch == 0 {
+		return nil
+	}
+
+	var configs []*pb.EpochConfig
+	var counts []int
+	for _, change := range epochChanges {
+		config := change.underlying.LastActiveConfig
+		if config == nil || config.Number+1 != newEpoch {
+			continue
+		}
+
+		found := false
+		for i, reported := range configs {
+			if proto.Equal(reported, config) {
+				counts[i]++
+				found = true
 				break
 			}
 		}
 
-		if lowestEntry == 0 {
-			// All of the sequence numbers within the watermarks prepared, so it's
-			// unclear why the epoch failed, eliminate the previous epoch leader
-			badNode = ec.lastActiveEpoch.config.number % uint64(len(ec.networkConfig.Nodes))
-		} else {
-			bucket := ec.lastActiveEpoch.config.seqToBucket(lowestEntry)
-			badNode = uint64(ec.lastActiveEpoch.config.buckets[bucket])
+		if !found {
+			configs = append(configs, config)
+			counts = append(counts, 1)
 		}
-	} else {
-		// If we never saw the last epoch start, we assume
-		// that replica must be faulty.
-		// Subtraction on epoch number is safe, as for epoch 0, lastActiveEpoch is nil
-		badNode = (epochChange.underlying.NewEpoch - 1) % uint64(len(ec.networkConfig.Nodes))
 	}
 
-	newLeaders := make([]uint64, 0, len(oldLeaders)-1)
-	for _, oldLeader := range oldLeaders {
-		if oldLeader == badNode {
+	nodes := map[uint64]struct{}{}
+	for _, node := range networkConfig.Nodes {
+		nodes[node] = struct{}{}
+	}
+
+outer:
+	for i, config := range configs {
+		if counts[i] < someCorrectQuorum(networkConfig) {
 			continue
 		}
-		newLeaders = append(newLeaders, oldLeader)
-	}
 
-	return newLeaders
+		for _, leader := range config.Leaders {
+			if _, ok := nodes[leader]; !ok {
+				continue outer
+			}
+		}
 
+		return parseEpochConfig(networkConfig, config)
+	}
+
+	return nil
 }
-*/
 
 func (ec *epochChanger) applyEpochChangeMsg(source NodeID, msg *pb.EpochChange) *Actions {
+	target := ec.targetFor(source, msg.NewEpoch)
+	if target == nil {
+		return &Actions{}
+	}
+
 	actions := &Actions{}
 	if source != NodeID(ec.myConfig.ID) {
 		// We don't want to echo our own EpochChange message,
@@ -737,24 +1198,32 @@ func (ec *epochChanger) applyEpochChangeMsg(source NodeID, msg *pb.EpochChange)
 
 	// TODO, we could get away with one type of message, an 'EpochChange'
 	// with an 'Origin', but it's a little less clear reading messages on the wire.
-	target := ec.target(msg.NewEpoch)
 	actions.Append(target.applyEpochChangeAckMsg(source, source, msg))
 	return actions
 }
 
 func (ec *epochChanger) applyEpochChangeDigest(epochChange *EpochChange, digest []byte) *Actions {
 	// TODO, fix all this stuttering and repitition
-	target := ec.target(epochChange.EpochChange.NewEpoch)
+	target := ec.targetFor(NodeID(epochChange.Source), epochChange.EpochChange.NewEpoch)
+	if target == nil {
+		return &Actions{}
+	}
 	return target.applyEpochChangeDigest(epochChange, digest)
 }
 
 func (ec *epochChanger) applyEpochChangeAckMsg(source NodeID, ack *pb.EpochChangeAck) *Actions {
-	target := ec.target(ack.EpochChange.NewEpoch)
+	target := ec.targetFor(source, ack.EpochChange.NewEpoch)
+	if target == nil {
+		return &Actions{}
+	}
 	return target.applyEpochChangeAckMsg(source, NodeID(ack.Originator), ack.EpochChange)
 }
 
-func (ec *epochChanger) applyNewEpochMsg(msg *pb.NewEpoch) *Actions {
-	target := ec.target(msg.Config.Number)
+func (ec *epochChanger) applyNewEpochMsg(source NodeID, msg *pb.NewEpoch) *Actions {
+	target := ec.targetFor(source, msg.Config.Number)
+	if target == nil {
+		return &Actions{}
+	}
 	return target.applyNewEpochMsg(msg)
 }
 
@@ -778,12 +1247,18 @@ func (ec *epochChanger) applyNewEpochMsg(msg *pb.NewEpoch) *Actions {
 // r-deliver(m)
 
 func (ec *epochChanger) applyNewEpochEchoMsg(source NodeID, msg *pb.NewEpochEcho) *Actions {
-	target := ec.target(msg.Config.Number)
+	target := ec.targetFor(source, msg.Config.Number)
+	if target == nil {
+		return &Actions{}
+	}
 	return target.applyNewEpochEchoMsg(source, msg)
 }
 
 func (ec *epochChanger) applyNewEpochReadyMsg(source NodeID, msg *pb.NewEpochReady) *Actions {
-	target := ec.target(msg.Config.Number)
+	target := ec.targetFor(source, msg.Config.Number)
+	if target == nil {
+		return &Actions{}
+	}
 	return target.applyNewEpochReadyMsg(source, msg)
 }
 
@@ -850,6 +1325,12 @@ func newParsedEpochChange(underlying *pb.EpochChange) (*parsedEpochChange, error
 		}
 	}
 
+	if config := underlying.LastActiveConfig; config != nil {
+		if config.StartingCheckpoint == nil || len(config.Leaders) == 0 || len(config.FinalPreprepares) != 0 {
+			return nil, errors.Errorf("epoch change contained a malformed last active config")
+		}
+	}
+
 	// TODO, check pSet and qSet for 'too advanced' views.
 
 	// TODO, check pSet and qSet for entries within log window relative to low watermark
@@ -887,7 +1368,7 @@ func newParsedEpochChange(underlying *pb.EpochChange) (*parsedEpochChange, error
 	}, nil
 }
 
-func constructNewEpochConfig(config *pb.NetworkConfig, newLeaders []uint64, epochChanges map[NodeID]*parsedEpochChange) *pb.EpochConfig {
+func constructNewEpochConfig(config *pb.NetworkConfig, epochChanges map[NodeID]*parsedEpochChange) *pb.EpochConfig {
 	type checkpointKey struct {
 		SeqNo uint64
 		Value string
@@ -950,8 +1431,7 @@ func constructNewEpochConfig(config *pb.NetworkConfig, newLeaders []uint64, epoc
 	}
 
 	newEpochConfig := &pb.EpochConfig{
-		Number:  newEpochNumber,
-		Leaders: newLeaders,
+		Number: newEpochNumber,
 		StartingCheckpoint: &pb.Checkpoint{
 			SeqNo: maxCheckpoint.SeqNo,
 			Value: []byte(maxCheckpoint.Value),
@@ -1066,8 +1546,12 @@ func constructNewEpochConfig(config *pb.NetworkConfig, newLeaders []uint64, epoc
 }
 
 func epochChangeHashData(epochChange *pb.EpochChange) [][]byte {
-	// [new_epoch, checkpoints, pSet, qSet]
-	hashData := make([][]byte, 1+len(epochChange.Checkpoints)*2+len(epochChange.PSet)*3+len(epochChange.QSet)*3)
+	// [new_epoch, checkpoints, pSet, qSet, last_active_config]
+	lastActiveConfigLen := 0
+	if epochChange.LastActiveConfig != nil {
+		lastActiveConfigLen = 2 + len(epochChange.LastActiveConfig.Leaders)
+	}
+	hashData := make([][]byte, 1+len(epochChange.Checkpoints)*2+len(epochChange.PSet)*3+len(epochChange.QSet)*3+lastActiveConfigLen)
 	hashData[0] = uint64ToBytes(epochChange.NewEpoch)
 
 	cpOffset := 1
@@ -1090,7 +1574,16 @@ func epochChangeHashData(epochChange *pb.EpochChange) [][]byte {
 		hashData[qEntryOffset+3*i+2] = qEntry.Digest
 	}
 
-	if qEntryOffset+len(epochChange.QSet)*3 != len(hashData) {
+	configOffset := qEntryOffset + len(epochChange.QSet)*3
+	if config := epochChange.LastActiveConfig; config != nil {
+		hashData[configOffset] = uint64ToBytes(config.Number)
+		hashData[configOffset+1] = uint64ToBytes(config.StartingCheckpoint.SeqNo)
+		for i, leader := range config.Leaders {
+			hashData[configOffset+2+i] = uint64ToBytes(leader)
+		}
+	}
+
+	if configOffset+lastActiveConfigLen != len(hashData) {
 		panic("TODO, remove me, but this is bad")
 	}
 
@@ -1135,7 +1628,6 @@ func (et *epochTarget) status() *EpochTargetStatus {
 		EpochChanges: make([]*EpochChangeStatus, 0, len(et.changes)),
 		Echos:        make([]uint64, 0, len(et.echos)),
 		Readies:      make([]uint64, 0, len(et.readies)),
-		Suspicions:   make([]uint64, 0, len(et.suspicions)),
 	}
 
 	for node, change := range et.changes {
@@ -1164,13 +1656,6 @@ func (et *epochTarget) status() *EpochTargetStatus {
 		return status.Readies[i] < status.Readies[j]
 	})
 
-	for node := range et.suspicions {
-		status.Suspicions = append(status.Suspicions, uint64(node))
-	}
-	sort.Slice(status.Suspicions, func(i, j int) bool {
-		return status.Suspicions[i] < status.Suspicions[j]
-	})
-
 	return status
 }
 
@@ -1182,6 +1667,15 @@ func (ec *epochChanger) status() *EpochChangerStatus {
 	for number, target := range ec.targets {
 		ts := target.status()
 		ts.Number = number
+		ts.Suspicions = []uint64{}
+		for node, epoch := range ec.suspicions {
+			if epoch >= number {
+				ts.Suspicions = append(ts.Suspicions, uint64(node))
+			}
+		}
+		sort.Slice(ts.Suspicions, func(i, j int) bool {
+			return ts.Suspicions[i] < ts.Suspicions[j]
+		})
 		targets = append(targets, ts)
 	}
 	sort.Slice(targets, func(i, j int) bool {
@@ -1189,8 +1683,10 @@ func (ec *epochChanger) status() *EpochChangerStatus {
 	})
 
 	return &EpochChangerStatus{
-		State:           ec.pendingEpochTarget.state,
-		LastActiveEpoch: lastActiveEpoch,
-		EpochTargets:    targets,
+		State:                ec.pendingEpochTarget.state,
+		LastActiveEpoch:      lastActiveEpoch,
+		FailedEpochs:         ec.failedEpochs,
+		NewEpochTimeoutTicks: ec.newEpochTimeoutTicks(),
+		EpochTargets:         targets,
 	}
 }
@@ -0,0 +1,594 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pb "github.com/IBM/mirbft/mirbftpb"
+
+	"go.uber.org/zap"
+)
+
+var _ = Describe("chooseLeaders", func() {
+	var (
+		networkConfig  *pb.NetworkConfig
+		previousConfig *epochConfig
+		newEpochConfig *pb.EpochConfig
+	)
+
+	BeforeEach(func() {
+		networkConfig = StandardInitialNetworkConfig(4)
+
+		previousConfig = parseEpochConfig(networkConfig, &pb.EpochConfig{
+			Number:             3,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 20},
+			Leaders:            []uint64{0, 1, 2, 3},
+		})
+
+		newEpochConfig = &pb.EpochConfig{
+			Number:             4,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 20},
+			FinalPreprepares:   make([][]byte, 2*networkConfig.CheckpointInterval),
+		}
+	})
+
+	It("evicts the leader of the bucket which stalled", func() {
+		// Sequences 21 and 22 prepared, but 23 did not
+		newEpochConfig.FinalPreprepares[0] = []byte("digest21")
+		newEpochConfig.FinalPreprepares[1] = []byte("digest22")
+		newEpochConfig.FinalPreprepares[3] = []byte("digest24")
+
+		stalled := uint64(previousConfig.buckets[previousConfig.seqToBucket(23)])
+		leaders := chooseLeaders(networkConfig, previousConfig, newEpochConfig)
+		Expect(leaders).To(HaveLen(3))
+		Expect(leaders).NotTo(ContainElement(stalled))
+	})
+
+	It("evicts the previous primary if every sequence prepared", func() {
+		for i := range newEpochConfig.FinalPreprepares {
+			newEpochConfig.FinalPreprepares[i] = []byte("digest")
+		}
+
+		Expect(chooseLeaders(networkConfig, previousConfig, newEpochConfig)).To(Equal([]uint64{0, 1, 2}))
+	})
+
+	It("re-admits an evicted node once the network makes progress", func() {
+		previousConfig = parseEpochConfig(networkConfig, &pb.EpochConfig{
+			Number:             3,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 20},
+			Leaders:            []uint64{0, 1},
+		})
+		for i := range newEpochConfig.FinalPreprepares {
+			newEpochConfig.FinalPreprepares[i] = []byte("digest")
+		}
+
+		// Node 3, the previous primary, is evicted, and node 2 re-admitted
+		Expect(chooseLeaders(networkConfig, previousConfig, newEpochConfig)).To(Equal([]uint64{0, 1, 2}))
+	})
+
+	It("never evicts the last leader", func() {
+		previousConfig = parseEpochConfig(networkConfig, &pb.EpochConfig{
+			Number:             3,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 20},
+			Leaders:            []uint64{3},
+		})
+
+		Expect(chooseLeaders(networkConfig, previousConfig, newEpochConfig)).To(Equal([]uint64{3}))
+	})
+
+	It("evicts the previous primary when the previous config is unknown", func() {
+		Expect(chooseLeaders(networkConfig, nil, newEpochConfig)).To(Equal([]uint64{0, 1, 2}))
+
+		newEpochConfig.Number = 0
+		Expect(chooseLeaders(networkConfig, nil, newEpochConfig)).To(Equal([]uint64{0, 1, 2, 3}))
+	})
+
+	Describe("previousEpochConfig", func() {
+		var (
+			epochChanges map[NodeID]*parsedEpochChange
+		)
+
+		BeforeEach(func() {
+			epochChanges = map[NodeID]*parsedEpochChange{}
+			for _, nodeID := range []NodeID{0, 1, 2} {
+				epochChanges[nodeID] = &parsedEpochChange{
+					underlying: &pb.EpochChange{
+						NewEpoch: 4,
+					},
+				}
+			}
+		})
+
+		It("derives the config reported by some correct node", func() {
+			epochChanges[0].underlying.LastActiveConfig = previousConfig.summary()
+			epochChanges[1].underlying.LastActiveConfig = previousConfig.summary()
+
+			Expect(previousEpochConfig(networkConfig, 4, epochChanges)).To(Equal(previousConfig))
+		})
+
+		It("ignores a config reported by too few nodes", func() {
+			epochChanges[0].underlying.LastActiveConfig = previousConfig.summary()
+			epochChanges[1].underlying.LastActiveConfig = previousConfig.summary()
+			epochChanges[1].underlying.LastActiveConfig.Leaders = []uint64{1, 2}
+
+			Expect(previousEpochConfig(networkConfig, 4, epochChanges)).To(BeNil())
+		})
+
+		It("ignores a config which does not precede the new epoch", func() {
+			epochChanges[0].underlying.LastActiveConfig = previousConfig.summary()
+			epochChanges[1].underlying.LastActiveConfig = previousConfig.summary()
+
+			Expect(previousEpochConfig(networkConfig, 5, epochChanges)).To(BeNil())
+		})
+
+		It("ignores a config whose leaders are not in the network", func() {
+			for _, nodeID := range []NodeID{0, 1, 2} {
+				epochChanges[nodeID].underlying.LastActiveConfig = previousConfig.summary()
+				epochChanges[nodeID].underlying.LastActiveConfig.Leaders = []uint64{0, 7}
+			}
+
+			Expect(previousEpochConfig(networkConfig, 4, epochChanges)).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("Verifying a NewEpoch", func() {
+	var (
+		networkConfig  *pb.NetworkConfig
+		sm             *stateMachine
+		et             *epochTarget
+		previousConfig *epochConfig
+		newEpoch       *pb.NewEpoch
+	)
+
+	BeforeEach(func() {
+		networkConfig = StandardInitialNetworkConfig(4)
+		sm = newGenesisStateMachine(networkConfig, &Config{
+			ID:     1,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+
+		previousConfig = parseEpochConfig(networkConfig, &pb.EpochConfig{
+			Number:             3,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 0},
+			Leaders:            []uint64{0, 1, 2, 3},
+		})
+
+		et = sm.epochChanger.target(4)
+		epochChanges := map[NodeID]*parsedEpochChange{}
+		newEpoch = &pb.NewEpoch{}
+		for _, nodeID := range []NodeID{0, 1, 2} {
+			parsedChange, err := newParsedEpochChange(&pb.EpochChange{
+				NewEpoch: 4,
+				Checkpoints: []*pb.Checkpoint{
+					{SeqNo: 0, Value: []byte("cp0")},
+				},
+				LastActiveConfig: previousConfig.summary(),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			parsedChange.acks = map[NodeID]struct{}{0: {}, 1: {}}
+
+			digest := []byte(fmt.Sprintf("change-%d", nodeID))
+			et.changes[nodeID] = &epochChange{
+				parsedByDigest: map[string]*parsedEpochChange{
+					string(digest): parsedChange,
+				},
+			}
+			epochChanges[nodeID] = parsedChange
+			newEpoch.EpochChanges = append(newEpoch.EpochChanges, &pb.NewEpoch_RemoteEpochChange{
+				NodeId: uint64(nodeID),
+				Digest: digest,
+			})
+		}
+
+		newEpoch.Config = constructNewEpochConfig(networkConfig, epochChanges)
+		newEpoch.Config.Leaders = chooseLeaders(networkConfig, previousConfig, newEpoch.Config)
+		et.state = pending
+	})
+
+	It("accepts leaders derived from the referenced epoch changes", func() {
+		// Node 3, the primary of epoch 3, is evicted
+		Expect(newEpoch.Config.Leaders).To(Equal([]uint64{0, 1, 2}))
+
+		et.applyNewEpochMsg(newEpoch)
+		Expect(et.state).To(Equal(epochTargetState(echoing)))
+	})
+
+	It("derives the same leaders regardless of what this node knows", func() {
+		sm.epochChanger.setLastActiveConfig(parseEpochConfig(networkConfig, &pb.EpochConfig{
+			Number:             3,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 0},
+			Leaders:            []uint64{1},
+		}))
+
+		et.applyNewEpochMsg(newEpoch)
+		Expect(et.state).To(Equal(epochTargetState(echoing)))
+	})
+
+	It("rejects leaders which do not match the referenced epoch changes", func() {
+		newEpoch.Config.Leaders = []uint64{0, 1, 2, 3}
+
+		et.applyNewEpochMsg(newEpoch)
+		Expect(et.state).To(Equal(epochTargetState(pending)))
+		Expect(et.leaderNewEpoch).To(BeNil())
+		Expect(sm.oddities.nodes[0].rejected).To(Equal(uint64(1)))
+	})
+})
+
+var _ = Describe("chooseGracefulLeaders", func() {
+	var (
+		networkConfig *pb.NetworkConfig
+	)
+
+	BeforeEach(func() {
+		networkConfig = StandardInitialNetworkConfig(4)
+	})
+
+	It("retains every leader", func() {
+		previousConfig := parseEpochConfig(networkConfig, &pb.EpochConfig{
+			Number:             3,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 20},
+			Leaders:            []uint64{0, 1, 2, 3},
+		})
+
+		Expect(chooseGracefulLeaders(networkConfig, previousConfig)).To(Equal([]uint64{0, 1, 2, 3}))
+	})
+
+	It("re-admits an evicted node", func() {
+		previousConfig := parseEpochConfig(networkConfig, &pb.EpochConfig{
+			Number:             3,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 20},
+			Leaders:            []uint64{1, 3},
+		})
+
+		// Nodes 0 and 2 were evicted, epoch 4 re-admits evicted[4%2]
+		Expect(chooseGracefulLeaders(networkConfig, previousConfig)).To(Equal([]uint64{0, 1, 3}))
+	})
+})
+
+var _ = Describe("Graceful epoch change", func() {
+	var (
+		networkConfig *pb.NetworkConfig
+		sm            *stateMachine
+		activeConfig  *epochConfig
+	)
+
+	BeforeEach(func() {
+		networkConfig = StandardInitialNetworkConfig(4)
+		sm = newGenesisStateMachine(networkConfig, &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+
+		activeConfig = parseEpochConfig(networkConfig, &pb.EpochConfig{
+			Number:             0,
+			StartingCheckpoint: &pb.Checkpoint{SeqNo: 0},
+			Leaders:            networkConfig.Nodes,
+		})
+		sm.epochChanger.pendingEpochTarget.state = idle
+		sm.epochChanger.setLastActiveConfig(activeConfig)
+		sm.persisted.lastCommitted = activeConfig.plannedExpiration
+	})
+
+	gracefulNewEpoch := func() *pb.NewEpoch {
+		return &pb.NewEpoch{
+			Config: &pb.EpochConfig{
+				Number: 1,
+				StartingCheckpoint: &pb.Checkpoint{
+					SeqNo: activeConfig.plannedExpiration,
+					Value: []byte("expiration"),
+				},
+				Leaders: []uint64{0, 1, 2, 3},
+			},
+		}
+	}
+
+	It("echoes the NewEpoch once the epoch has ended gracefully", func() {
+		actions := sm.epochChanger.beginGracefulEpochChange(activeConfig, &pb.Checkpoint{
+			SeqNo: activeConfig.plannedExpiration,
+			Value: []byte("expiration"),
+		})
+		Expect(actions.Broadcast).To(BeEmpty())
+		target := sm.epochChanger.pendingEpochTarget
+		Expect(target.number).To(Equal(uint64(1)))
+		Expect(target.state).To(Equal(epochTargetState(pending)))
+
+		actions = sm.epochChanger.applyNewEpochMsg(1, gracefulNewEpoch())
+		Expect(target.state).To(Equal(epochTargetState(echoing)))
+		Expect(actions.Broadcast).To(ContainElement(&pb.Msg{
+			Type: &pb.Msg_NewEpochEcho{
+				NewEpochEcho: &pb.NewEpochEcho{
+					Config: gracefulNewEpoch().Config,
+				},
+			},
+		}))
+	})
+
+	It("rejects a graceful NewEpoch with an unexpected config", func() {
+		sm.epochChanger.beginGracefulEpochChange(activeConfig, &pb.Checkpoint{
+			SeqNo: activeConfig.plannedExpiration,
+			Value: []byte("expiration"),
+		})
+
+		newEpoch := gracefulNewEpoch()
+		newEpoch.Config.Leaders = []uint64{1, 2, 3}
+		actions := sm.epochChanger.applyNewEpochMsg(1, newEpoch)
+		Expect(actions.Broadcast).To(BeEmpty())
+		Expect(sm.epochChanger.pendingEpochTarget.state).To(Equal(epochTargetState(pending)))
+		Expect(sm.epochChanger.pendingEpochTarget.leaderNewEpoch).To(BeNil())
+		Expect(sm.oddities.nodes[1].rejected).To(Equal(uint64(1)))
+	})
+
+	It("ignores a graceful NewEpoch until the epoch has ended", func() {
+		actions := sm.epochChanger.applyNewEpochMsg(1, gracefulNewEpoch())
+		Expect(actions.Broadcast).To(BeEmpty())
+
+		actions = sm.epochChanger.beginGracefulEpochChange(activeConfig, &pb.Checkpoint{
+			SeqNo: activeConfig.plannedExpiration,
+			Value: []byte("expiration"),
+		})
+		Expect(sm.epochChanger.pendingEpochTarget.state).To(Equal(epochTargetState(echoing)))
+		Expect(actions.Broadcast).To(HaveLen(1))
+	})
+
+	It("suspects the new epoch if it does not begin in time", func() {
+		sm.epochChanger.beginGracefulEpochChange(activeConfig, &pb.Checkpoint{
+			SeqNo: activeConfig.plannedExpiration,
+			Value: []byte("expiration"),
+		})
+
+		for i := 1; i < 8; i++ {
+			Expect(sm.epochChanger.tick().Broadcast).To(BeEmpty())
+		}
+
+		Expect(sm.epochChanger.tick().Broadcast).To(Equal([]*pb.Msg{
+			{
+				Type: &pb.Msg_Suspect{
+					Suspect: &pb.Suspect{
+						Epoch: 1,
+					},
+				},
+			},
+		}))
+	})
+})
+
+var _ = Describe("New epoch timeout", func() {
+	var (
+		ec *epochChanger
+	)
+
+	BeforeEach(func() {
+		ec = &epochChanger{
+			myConfig: &Config{
+				NewEpochTimeoutTicks: 8,
+			},
+			targets: map[uint64]*epochTarget{},
+		}
+	})
+
+	It("doubles for each consecutive failed epoch", func() {
+		Expect(ec.newEpochTimeoutTicks()).To(Equal(uint64(8)))
+
+		ec.failedEpochs = 1
+		Expect(ec.newEpochTimeoutTicks()).To(Equal(uint64(16)))
+
+		ec.failedEpochs = 3
+		Expect(ec.newEpochTimeoutTicks()).To(Equal(uint64(64)))
+	})
+
+	It("is capped", func() {
+		ec.failedEpochs = 1000
+		Expect(ec.newEpochTimeoutTicks()).To(Equal(uint64(8 * defaultMaxNewEpochTimeoutMultiplier)))
+
+		ec.myConfig.MaxNewEpochTimeoutTicks = 20
+		Expect(ec.newEpochTimeoutTicks()).To(Equal(uint64(20)))
+	})
+
+	It("resets once an epoch commits", func() {
+		ec.failedEpochs = 3
+		ec.epochCommitted()
+		Expect(ec.newEpochTimeoutTicks()).To(Equal(uint64(8)))
+	})
+
+	It("grows as successive epochs are suspected", func() {
+		networkConfig := StandardInitialNetworkConfig(4)
+		sm := newGenesisStateMachine(networkConfig, &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+
+		for epoch := uint64(0); epoch < 2; epoch++ {
+			for _, node := range networkConfig.Nodes[:3] {
+				sm.applySuspectMsg(NodeID(node), epoch)
+			}
+		}
+
+		status := sm.epochChanger.status()
+		Expect(status.FailedEpochs).To(Equal(uint64(2)))
+		Expect(status.NewEpochTimeoutTicks).To(Equal(uint64(32)))
+	})
+})
+
+var _ = Describe("Epoch target garbage collection", func() {
+	var (
+		networkConfig *pb.NetworkConfig
+		sm            *stateMachine
+	)
+
+	BeforeEach(func() {
+		networkConfig = StandardInitialNetworkConfig(4)
+		sm = newGenesisStateMachine(networkConfig, &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+	})
+
+	epochChange := func(epoch uint64) *pb.EpochChange {
+		return &pb.EpochChange{
+			NewEpoch: epoch,
+			Checkpoints: []*pb.Checkpoint{
+				{SeqNo: 0, Value: []byte("cp0")},
+			},
+		}
+	}
+
+	It("retains only the latest future epoch referenced by each node", func() {
+		for epoch := uint64(1); epoch <= 100; epoch++ {
+			actions := sm.epochChanger.applyEpochChangeMsg(1, epochChange(epoch))
+			Expect(actions.Hash).To(HaveLen(1))
+		}
+
+		Expect(sm.epochChanger.targets).To(HaveLen(2))
+		Expect(sm.epochChanger.targets).To(HaveKey(uint64(0)))
+		Expect(sm.epochChanger.targets).To(HaveKey(uint64(100)))
+	})
+
+	It("retains a future epoch while any node references it", func() {
+		sm.epochChanger.applyEpochChangeMsg(1, epochChange(3))
+		sm.epochChanger.applyEpochChangeMsg(2, epochChange(3))
+		sm.epochChanger.applyEpochChangeMsg(1, epochChange(4))
+
+		Expect(sm.epochChanger.targets).To(HaveKey(uint64(3)))
+		Expect(sm.epochChanger.targets).To(HaveKey(uint64(4)))
+	})
+
+	It("ignores messages for superseded epochs", func() {
+		sm.epochChanger.applyEpochChangeMsg(1, epochChange(5))
+
+		actions := sm.epochChanger.applyEpochChangeMsg(1, epochChange(3))
+		Expect(actions.IsEmpty()).To(BeTrue())
+		Expect(sm.epochChanger.targets).NotTo(HaveKey(uint64(3)))
+	})
+
+	It("does not create targets for suspicions", func() {
+		for epoch := uint64(0); epoch <= 10; epoch++ {
+			sm.epochChanger.applySuspectMsg(1, epoch)
+		}
+
+		Expect(sm.epochChanger.targets).To(HaveLen(1))
+	})
+
+	It("ignores suspicions far beyond the last active epoch", func() {
+		for _, node := range networkConfig.Nodes[:3] {
+			Expect(sm.epochChanger.applySuspectMsg(NodeID(node), maxSuspectEpochLead+1)).To(BeNil())
+		}
+
+		Expect(sm.epochChanger.pendingEpochTarget.number).To(Equal(uint64(0)))
+	})
+
+	It("moves beyond the highest epoch suspected by a quorum", func() {
+		Expect(sm.epochChanger.applySuspectMsg(1, 7)).To(BeNil())
+		Expect(sm.epochChanger.applySuspectMsg(2, 5)).To(BeNil())
+		Expect(sm.epochChanger.applySuspectMsg(3, 9)).NotTo(BeNil())
+
+		Expect(sm.epochChanger.pendingEpochTarget.number).To(Equal(uint64(6)))
+	})
+})
+
+var _ = Describe("Fetching new epoch state", func() {
+	var (
+		sm *stateMachine
+		et *epochTarget
+	)
+
+	BeforeEach(func() {
+		networkConfig := StandardInitialNetworkConfig(4)
+		sm = newGenesisStateMachine(networkConfig, &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+
+		et = sm.epochChanger.target(1)
+		for _, nodeID := range []NodeID{0, 1} {
+			et.changes[nodeID] = &epochChange{
+				parsedByDigest: map[string]*parsedEpochChange{
+					"change": {
+						qSet: map[uint64]map[uint64][]byte{
+							1: {0: []byte("batch")},
+						},
+					},
+				},
+			}
+		}
+
+		et.leaderNewEpoch = &pb.NewEpoch{
+			Config: &pb.EpochConfig{
+				Number:             1,
+				StartingCheckpoint: &pb.Checkpoint{SeqNo: 0},
+				FinalPreprepares:   [][]byte{[]byte("batch")},
+			},
+			EpochChanges: []*pb.NewEpoch_RemoteEpochChange{
+				{NodeId: 0, Digest: []byte("change")},
+				{NodeId: 1, Digest: []byte("change")},
+			},
+		}
+		et.state = fetching
+
+		sm.batchTracker.addBatch(1, []byte("batch"), []*pb.RequestAck{
+			{
+				ClientId: []byte("unknown-client"),
+				ReqNo:    1,
+				Digest:   []byte("request"),
+			},
+		})
+	})
+
+	It("waits, rather than panics, when the batch references an unknown client", func() {
+		actions := et.fetchNewEpochState()
+		Expect(actions.StateTransfer).To(BeNil())
+		Expect(et.state).To(Equal(epochTargetState(fetching)))
+		Expect(sm.oddities.lagging).To(Equal(uint64(1)))
+	})
+
+	It("transfers state once the network agrees on a later checkpoint", func() {
+		for _, nodeID := range []NodeID{1, 2, 3} {
+			sm.checkpointTracker.applyCheckpointMsg(nodeID, 20, []byte("cp20"))
+		}
+
+		actions := et.fetchNewEpochState()
+		Expect(actions.StateTransfer).To(Equal(&StateTarget{
+			SeqNo: 20,
+			Value: []byte("cp20"),
+		}))
+		Expect(et.state).To(Equal(epochTargetState(fetching)))
+	})
+})
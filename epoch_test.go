@@ -0,0 +1,224 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pb "github.com/IBM/mirbft/mirbftpb"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Watermarks", func() {
+	var (
+		e  *epoch
+		ci uint64
+	)
+
+	BeforeEach(func() {
+		networkConfig := StandardInitialNetworkConfig(4)
+		myConfig := &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}
+
+		sm := newGenesisStateMachine(networkConfig, myConfig)
+		e = newEpoch(sm.persisted, &pb.EpochConfig{
+			Number:             1,
+			Leaders:            networkConfig.Nodes,
+			StartingCheckpoint: &pb.Checkpoint{Value: []byte("cp0")},
+		}, sm.checkpointTracker, sm.clientWindows, networkConfig, myConfig)
+		ci = uint64(e.config.networkConfig.CheckpointInterval)
+	})
+
+	It("moves past a later checkpoint which becomes stable first", func() {
+		Expect(e.checkpoints).To(HaveLen(4))
+		Expect(e.checkpoints[0].seqNo).To(Equal(uint64(0)))
+
+		e.checkpoints[2].stable = true
+		e.moveWatermarks()
+
+		Expect(e.checkpoints).To(HaveLen(4))
+		Expect(e.checkpoints[0].seqNo).To(Equal(2 * ci))
+		Expect(e.lowWatermark()).To(Equal(2*ci + 1))
+		Expect(e.highWatermark()).To(Equal(5 * ci))
+	})
+})
+
+var _ = Describe("Commits", func() {
+	var e *epoch
+
+	BeforeEach(func() {
+		networkConfig := StandardInitialNetworkConfig(4)
+		myConfig := &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}
+
+		sm := newGenesisStateMachine(networkConfig, myConfig)
+		e = newEpoch(sm.persisted, &pb.EpochConfig{
+			Number:             1,
+			Leaders:            networkConfig.Nodes,
+			StartingCheckpoint: &pb.Checkpoint{Value: []byte("cp0")},
+		}, sm.checkpointTracker, sm.clientWindows, networkConfig, myConfig)
+
+		e.clientWindows.insert([]byte("client"), e.clientWindows.newWindow(1))
+		cw, _ := e.clientWindows.clientWindow([]byte("client"))
+
+		for i, seq := range e.sequences[:2] {
+			req := &pb.Request{ClientId: []byte("client"), ReqNo: uint64(i + 1)}
+			cw.allocate(req, []byte("digest"))
+			seq.digest = []byte("batch")
+			seq.qEntry = &pb.QEntry{
+				Epoch:    1,
+				SeqNo:    seq.seqNo,
+				Digest:   seq.digest,
+				Requests: []*pb.ForwardRequest{{Request: req, Digest: []byte("digest")}},
+			}
+			seq.state = Prepared
+		}
+	})
+
+	It("records the sequence each request committed at, when several commit at once", func() {
+		var receipts []*CommitReceipt
+		e.clientWindows.onCommit = map[string]func(*CommitReceipt){
+			"client": func(receipt *CommitReceipt) {
+				receipts = append(receipts, receipt)
+			},
+		}
+
+		for _, source := range []NodeID{0, 1, 2} {
+			Expect(e.applyCommitMsg(source, 2, []byte("batch")).Commits).To(BeEmpty())
+		}
+		Expect(e.sequences[1].state).To(Equal(SequenceState(Committed)))
+
+		for _, source := range []NodeID{0, 1} {
+			Expect(e.applyCommitMsg(source, 1, []byte("batch")).Commits).To(BeEmpty())
+		}
+		actions := e.applyCommitMsg(2, 1, []byte("batch"))
+		Expect(actions.Commits).To(HaveLen(2))
+
+		Expect(receipts).To(Equal([]*CommitReceipt{
+			{ClientID: []byte("client"), ReqNo: 1, SeqNo: 1, Epoch: 1},
+			{ClientID: []byte("client"), ReqNo: 2, SeqNo: 2, Epoch: 1},
+		}))
+
+		cw, _ := e.clientWindows.clientWindow([]byte("client"))
+		Expect(cw.status().Committed[:2]).To(Equal([]uint64{1, 2}))
+	})
+
+	It("commits nothing if the sequence does not follow the last committed sequence", func() {
+		e.persisted.lastCommitted = 5
+
+		for _, source := range []NodeID{0, 1} {
+			Expect(e.applyCommitMsg(source, 1, []byte("batch")).Commits).To(BeEmpty())
+		}
+		Expect(e.applyCommitMsg(2, 1, []byte("batch")).Commits).To(BeEmpty())
+
+		Expect(errors.Cause(e.persisted.err)).To(Equal(ErrNonContiguousCommit))
+		Expect(e.lowestUncommitted).To(Equal(0))
+	})
+})
+
+var _ = Describe("Sequence state repair", func() {
+	var (
+		networkConfig *pb.NetworkConfig
+		sm            *stateMachine
+		e             *epoch
+	)
+
+	BeforeEach(func() {
+		networkConfig = StandardInitialNetworkConfig(4)
+	})
+
+	JustBeforeEach(func() {
+		myConfig := &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}
+
+		sm = newGenesisStateMachine(networkConfig, myConfig)
+		e = newEpoch(sm.persisted, &pb.EpochConfig{
+			Number:             1,
+			Leaders:            networkConfig.Nodes,
+			StartingCheckpoint: &pb.Checkpoint{Value: []byte("cp0")},
+		}, sm.checkpointTracker, sm.clientWindows, networkConfig, myConfig)
+		e.checkpoints[0].myValue = []byte("cp0")
+	})
+
+	It("retransmits to each node at most once per fetch interval", func() {
+		Expect(e.applyFetchSequenceStateMsg(1, 5).Unicast).To(HaveLen(1))
+		Expect(e.applyFetchSequenceStateMsg(1, 5).IsEmpty()).To(BeTrue())
+		Expect(e.applyFetchSequenceStateMsg(1, 4).IsEmpty()).To(BeTrue())
+
+		By("answering other nodes, and later sequences, regardless")
+		Expect(e.applyFetchSequenceStateMsg(2, 5).Unicast).To(HaveLen(1))
+		Expect(e.applyFetchSequenceStateMsg(1, 6).Unicast).To(HaveLen(1))
+
+		By("treating every sequence beyond the watermarks alike")
+		Expect(e.applyFetchSequenceStateMsg(1, 1000).Unicast).To(HaveLen(1))
+		Expect(e.applyFetchSequenceStateMsg(1, 2000).IsEmpty()).To(BeTrue())
+
+		By("answering again once the fetch interval has passed")
+		for i := 0; i < e.fetchInterval(); i++ {
+			e.tick()
+		}
+		Expect(e.applyFetchSequenceStateMsg(1, 6).Unicast).To(HaveLen(1))
+	})
+
+	When("the network requires signatures", func() {
+		BeforeEach(func() {
+			networkConfig.RequireSignatures = true
+		})
+
+		It("retransmits only the checkpoints it has signed, with their signatures", func() {
+			Expect(e.applyFetchSequenceStateMsg(1, 5).IsEmpty()).To(BeTrue())
+
+			sm.checkpointTracker.applySignature(&pb.Checkpoint{
+				SeqNo:     0,
+				Value:     []byte("cp0"),
+				Signature: []byte("signature"),
+			})
+
+			Expect(e.applyFetchSequenceStateMsg(1, 6).Unicast).To(Equal([]Unicast{
+				{
+					Target: 1,
+					Msg: &pb.Msg{
+						Type: &pb.Msg_Checkpoint{
+							Checkpoint: &pb.Checkpoint{
+								SeqNo:     0,
+								Value:     []byte("cp0"),
+								Signature: []byte("signature"),
+							},
+						},
+					},
+				},
+			}))
+		})
+	})
+})
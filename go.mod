@@ -12,5 +12,7 @@ require (
 	go.uber.org/atomic v1.3.2 // indirect
 	go.uber.org/multierr v1.1.0 // indirect
 	go.uber.org/zap v1.9.1
+	golang.org/x/net v0.0.0-20190311183353-d8887717615a
+	google.golang.org/grpc v1.18.0
 	honnef.co/go/tools v0.0.0-20190415040902-b088cadd8b3d
 )
@@ -63,6 +63,7 @@ var _ = XDescribe("Integration", func() {
 				Nodes:              []uint64{0},
 				NumberOfBuckets:    1,
 				MaxEpochLength:     10,
+				ClientWindowWidth:  100,
 			}
 
 			persisted := &persisted{
@@ -79,11 +80,11 @@ var _ = XDescribe("Integration", func() {
 				Value: []byte("TODO, get from state"),
 			}
 
-			stateMachineVal = newStateMachine(networkConfig, consumerConfig, persisted)
+			stateMachineVal, _ = newStateMachine(networkConfig, consumerConfig, persisted)
 			stateMachineVal.activeEpoch = newEpoch(nil, epochConfig, stateMachineVal.checkpointTracker, stateMachineVal.clientWindows, networkConfig, consumerConfig)
 			stateMachineVal.nodeMsgs[0].setActiveEpoch(stateMachineVal.activeEpoch)
 
-			serializer = newSerializer(stateMachineVal, doneC)
+			serializer = newSerializer(stateMachineVal, &Actions{}, doneC)
 		})
 
 		It("works from proposal through commit", func() {
@@ -337,6 +338,7 @@ var _ = XDescribe("Integration", func() {
 				Nodes:              []uint64{0, 1, 2, 3},
 				NumberOfBuckets:    4,
 				MaxEpochLength:     10,
+				ClientWindowWidth:  100,
 			}
 
 			persisted := &persisted{
@@ -352,14 +354,14 @@ var _ = XDescribe("Integration", func() {
 				SeqNo: 0,
 				Value: []byte("TODO, get from state"),
 			}
-			stateMachineVal = newStateMachine(networkConfig, consumerConfig, persisted)
+			stateMachineVal, _ = newStateMachine(networkConfig, consumerConfig, persisted)
 			stateMachineVal.activeEpoch = newEpoch(nil, epochConfig, stateMachineVal.checkpointTracker, stateMachineVal.clientWindows, networkConfig, consumerConfig)
 			stateMachineVal.nodeMsgs[0].setActiveEpoch(stateMachineVal.activeEpoch)
 			stateMachineVal.nodeMsgs[1].setActiveEpoch(stateMachineVal.activeEpoch)
 			stateMachineVal.nodeMsgs[2].setActiveEpoch(stateMachineVal.activeEpoch)
 			stateMachineVal.nodeMsgs[3].setActiveEpoch(stateMachineVal.activeEpoch)
 
-			serializer = newSerializer(stateMachineVal, doneC)
+			serializer = newSerializer(stateMachineVal, &Actions{}, doneC)
 
 		})
 
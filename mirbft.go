@@ -16,8 +16,10 @@ package mirbft
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 )
 
@@ -50,11 +52,28 @@ type Replica struct {
 // The methods exposed on Node are all thread safe, though typically, a single loop handles
 // reading Actions, writing results, and writing ticks, while other go routines Propose and Step.
 type Node struct {
-	Config   *Config
-	s        *serializer
+	Config *Config
+	s      *serializer
+
+	// Replicas are the nodes of the current network config.  The node does not
+	// modify them itself, as they are read by the goroutine processing actions,
+	// instead, when the network is reconfigured, that goroutine should replace
+	// them with Actions.Replicas.
 	Replicas []Replica
 }
 
+// replicas returns the replicas of the nodes in the network config.
+func replicas(networkConfig *pb.NetworkConfig) []Replica {
+	replicas := make([]Replica, len(networkConfig.Nodes))
+	for i, node := range networkConfig.Nodes {
+		replicas[i] = Replica{
+			ID: node,
+		}
+	}
+
+	return replicas
+}
+
 type ClientProposer struct {
 	blocking     bool
 	clientID     []byte
@@ -140,48 +159,112 @@ func StandardInitialNetworkConfig(nodeCount int) *pb.NetworkConfig {
 		NumberOfBuckets:    numberOfBuckets,
 		CheckpointInterval: checkpointInterval,
 		MaxEpochLength:     uint64(maxEpochLength),
+		ClientWindowWidth:  100,
+	}
+}
+
+// validateNetworkConfig checks the parts of a network config which the state
+// machine cannot operate without.  It is applied to every network config the
+// node adopts, whether from the caller, from storage, or from the application
+// via a checkpoint or state transfer.
+func validateNetworkConfig(networkConfig *pb.NetworkConfig) error {
+	if networkConfig.ClientWindowWidth == 0 {
+		return errors.Errorf("client window width must be greater than zero")
 	}
+
+	return nil
 }
 
-// StartNewNode creates a node to join a fresh network.  Eventually, this method will either
-// be deprecated, or augmented with a RestartNode or similar.  For now, this method
-// hard codes many of the parameters, but more will be exposed in the future.
+// StartNewNode creates a node to join a fresh network.  The initial network config
+// and genesis checkpoint are emitted as the first Persisted actions, so that the node
+// may later be restarted from storage via RestartNode.  The value of the genesis
+// checkpoint is the serialized initial network config, so that nodes started with
+// differing configs (such as differing client window widths) never agree on it.
+// For now, this method hard codes many of the parameters, but more will be
+// exposed in the future.
 func StartNewNode(
 	config *Config,
 	doneC <-chan struct{},
 	initialNetworkConfig *pb.NetworkConfig,
 	storage Storage,
 ) (*Node, error) {
-	replicas := make([]Replica, len(initialNetworkConfig.Nodes))
-	for i, node := range initialNetworkConfig.Nodes {
-		replicas[i] = Replica{
-			ID: node,
-		}
+	if err := validateNetworkConfig(initialNetworkConfig); err != nil {
+		return nil, errors.WithMessage(err, "failed to start new node")
+	}
+
+	genesisValue, err := proto.Marshal(initialNetworkConfig)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to start new node: could not marshal initial network config")
 	}
 
-	persisted := &persisted{
-		pSet:          map[uint64]*pb.PEntry{},
-		qSet:          map[uint64]map[uint64]*pb.QEntry{},
-		checkpoints:   map[uint64]*pb.Checkpoint{},
-		networkConfig: initialNetworkConfig,
-		myConfig:      config,
+	persisted := newPersisted(config)
+	if err := persisted.load(storage); err != nil {
+		return nil, errors.WithMessage(err, "failed to start new node")
+	}
+
+	actions := persisted.add(&pb.Persisted{
+		Type: &pb.Persisted_NetworkConfig{
+			NetworkConfig: initialNetworkConfig,
+		},
+	})
+
+	if len(persisted.checkpoints) == 0 {
+		actions.Append(persisted.add(&pb.Persisted{
+			Type: &pb.Persisted_Checkpoint{
+				Checkpoint: &pb.Checkpoint{
+					SeqNo: 0,
+					Value: genesisValue,
+				},
+			},
+		}))
 	}
+
+	return newNode(config, doneC, persisted, actions), nil
+}
+
+// RestartNode creates a node from the entries previously persisted to storage.  The
+// network config, checkpoints, and Q and P entries are all read back through
+// storage, and the state machine rebuilt from them.  The application is assumed to
+// have applied all commits up to and including the highest persisted checkpoint.
+// If the node crashed during an epoch change, it resumes that epoch change, otherwise
+// it rejoins the network by requesting a change to the epoch after the last epoch it
+// was active in.
+// If the persisted entries are malformed or inconsistent with one another, the cause
+// of the returned error is ErrCorruptStorage.
+func RestartNode(
+	config *Config,
+	doneC <-chan struct{},
+	storage Storage,
+) (*Node, error) {
+	persisted := newPersisted(config)
 	if err := persisted.load(storage); err != nil {
-		return nil, errors.Errorf("failed to start new node: %s", err)
+		return nil, errors.WithMessage(err, "failed to restart node")
+	}
+
+	if persisted.networkConfig == nil {
+		return nil, errors.Errorf("failed to restart node: no network config in storage")
 	}
 
+	if err := validateNetworkConfig(persisted.networkConfig); err != nil {
+		return nil, errors.WithMessage(err, "failed to restart node")
+	}
+
+	if len(persisted.checkpoints) == 0 {
+		return nil, errors.Errorf("failed to restart node: no checkpoints in storage")
+	}
+
+	return newNode(config, doneC, persisted, &Actions{}), nil
+}
+
+func newNode(config *Config, doneC <-chan struct{}, persisted *persisted, initialActions *Actions) *Node {
+	stateMachine, actions := newStateMachine(persisted.networkConfig, config, persisted)
+	initialActions.Append(actions)
+
 	return &Node{
 		Config:   config,
-		Replicas: replicas,
-		s: newSerializer(
-			newStateMachine(
-				initialNetworkConfig,
-				config,
-				persisted,
-			),
-			doneC,
-		),
-	}, nil
+		Replicas: replicas(persisted.networkConfig),
+		s:        newSerializer(stateMachine, initialActions, doneC),
+	}
 }
 
 type ClientProposerOption interface{}
@@ -190,6 +273,19 @@ type clientProposerBlocking struct {
 	shouldBlock bool
 }
 
+type clientProposerOnCommit struct {
+	onCommit func(*CommitReceipt)
+}
+
+// CommitReceipt reports the sequence, and the epoch, at which a client
+// request committed.
+type CommitReceipt struct {
+	ClientID []byte
+	ReqNo    uint64
+	SeqNo    uint64
+	Epoch    uint64
+}
+
 // WaitForRoom indicates whether the client proposer should block, waiting for
 // space to become available in the client window.  If set to false, the client
 // will immediately return with an error if the window exhausts.
@@ -199,13 +295,90 @@ func WaitForRoom(shouldBlock bool) ClientProposerOption {
 	}
 }
 
+// OnCommit registers a function which is invoked with a receipt once each
+// request of the client commits, that is, once its order is final, though
+// it may not yet have been applied by the consumer.  The function is invoked
+// from a goroutine dedicated to the client, with the receipts in commit order,
+// so it may block, or call into the node, without stalling the state machine,
+// though the receipts queue until it returns.  Requests which this node learns
+// of only via state transfer are not reported.
+func OnCommit(onCommit func(*CommitReceipt)) ClientProposerOption {
+	return clientProposerOnCommit{
+		onCommit: onCommit,
+	}
+}
+
+// receiptDispatcher queues the receipts which the state machine reports for a
+// client, and invokes the client's OnCommit function with them from its own
+// goroutine, so that the serializer never waits on the consumer.
+type receiptDispatcher struct {
+	onCommit func(*CommitReceipt)
+	readyC   chan struct{}
+
+	mutex    sync.Mutex
+	receipts []*CommitReceipt
+}
+
+// enqueue queues the receipt without blocking.
+func (rd *receiptDispatcher) enqueue(receipt *CommitReceipt) {
+	rd.mutex.Lock()
+	rd.receipts = append(rd.receipts, receipt)
+	rd.mutex.Unlock()
+
+	select {
+	case rd.readyC <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers the queued receipts in order until the node stops.
+func (rd *receiptDispatcher) run(doneC <-chan struct{}, errC <-chan struct{}) {
+	for {
+		select {
+		case <-rd.readyC:
+		case <-doneC:
+			return
+		case <-errC:
+			return
+		}
+
+		rd.mutex.Lock()
+		receipts := rd.receipts
+		rd.receipts = nil
+		rd.mutex.Unlock()
+
+		for _, receipt := range receipts {
+			rd.onCommit(receipt)
+		}
+	}
+}
+
 // ClientProposer returns a new ClientProposer for a given clientID.  It is the caller's
 // responsibility to ensure that this method is never invoked twice with the same clientID.
 func (n *Node) ClientProposer(ctx context.Context, clientID []byte, options ...ClientProposerOption) (*ClientProposer, error) {
+	blocking := true
+	var onCommit func(*CommitReceipt)
+	for _, option := range options {
+		switch o := option.(type) {
+		case clientProposerBlocking:
+			blocking = o.shouldBlock
+		case clientProposerOnCommit:
+			rd := &receiptDispatcher{
+				onCommit: o.onCommit,
+				readyC:   make(chan struct{}, 1),
+			}
+			go rd.run(n.s.doneC, n.s.errC)
+			onCommit = rd.enqueue
+		default:
+			panic("unknown option")
+		}
+	}
+
 	replyC := make(chan *clientWaiter, 1)
 	select {
 	case n.s.clientC <- &clientReq{
 		clientID: clientID,
+		onCommit: onCommit,
 		replyC:   replyC,
 	}:
 	case <-ctx.Done():
@@ -224,16 +397,6 @@ func (n *Node) ClientProposer(ctx context.Context, clientID []byte, options ...C
 		return nil, n.s.getExitErr()
 	}
 
-	blocking := true
-	for _, option := range options {
-		switch o := option.(type) {
-		case clientProposerBlocking:
-			blocking = o.shouldBlock
-		default:
-			panic("unknown option")
-		}
-	}
-
 	return &ClientProposer{
 		blocking:     blocking,
 		clientID:     clientID,
@@ -327,9 +490,31 @@ func (n *Node) Tick() error {
 
 // AddResults is a callback from the consumer to the state machine, informing the
 // state machine that Actions have been carried out, and the result of those
-// Actions is applicable.  In the case that the node is stopped, it returns
-// ErrStopped, otherwise nil is returned.
+// Actions is applicable.  If a checkpoint or state transfer result carries a
+// network config which the state machine cannot adopt, an error is returned and
+// none of the results are applied.  In the case that the node is stopped, it
+// returns ErrStopped, otherwise nil is returned.
 func (n *Node) AddResults(results ActionResults) error {
+	for _, checkpointResult := range results.Checkpoints {
+		if checkpointResult.NetworkConfig == nil {
+			continue
+		}
+
+		if err := validateNetworkConfig(checkpointResult.NetworkConfig); err != nil {
+			return errors.WithMessagef(err, "invalid network config for checkpoint at seqno=%d", checkpointResult.SeqNo)
+		}
+	}
+
+	if results.StateTransfer != nil && results.StateTransfer.Target == nil {
+		return errors.Errorf("state transfer result has no target")
+	}
+
+	if results.StateTransfer != nil && results.StateTransfer.NetworkConfig != nil {
+		if err := validateNetworkConfig(results.StateTransfer.NetworkConfig); err != nil {
+			return errors.WithMessagef(err, "invalid network config for state transfer to seqno=%d", results.StateTransfer.Target.SeqNo)
+		}
+	}
+
 	select {
 	case n.s.resultsC <- results:
 		return nil
@@ -337,3 +522,14 @@ func (n *Node) AddResults(results ActionResults) error {
 		return n.s.getExitErr()
 	}
 }
+
+// StateTransferComplete is a callback from the consumer to the state machine,
+// informing the state machine that the state transfer requested via Actions
+// has completed, and that the application state now reflects the target
+// checkpoint.  In the case that the node is stopped, it returns ErrStopped,
+// otherwise nil is returned.
+func (n *Node) StateTransferComplete(result *StateTransferResult) error {
+	return n.AddResults(ActionResults{
+		StateTransfer: result,
+	})
+}
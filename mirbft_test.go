@@ -10,7 +10,9 @@ import (
 	. "github.com/onsi/gomega"
 
 	_ "github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
 	"github.com/IBM/mirbft/testengine"
+	tpb "github.com/IBM/mirbft/testengine/testenginepb"
 )
 
 var _ = Describe("Mirbft", func() {
@@ -133,6 +135,34 @@ var _ = Describe("Mirbft", func() {
 		})
 	})
 
+	When("two nodes lose the prepares and commits for a sequence sent to a third", func() {
+		var suspicions int
+
+		BeforeEach(func() {
+			suspicions = 0
+			recorder.Manglers = []testengine.Mangler{
+				// Count, but never drop, the suspicions
+				testengine.Drop().When(func() bool { suspicions++; return false }).OfTypes("Suspect").Messages(),
+				// Without the messages from both nodes, the third node cannot
+				// reach a quorum for the sequence, and must fetch them.
+				&seqNoDropMangler{
+					seqNo:   10,
+					sources: []uint64{0, 2},
+					target:  1,
+				},
+			}
+			for _, clientConfig := range recorder.ClientConfigs {
+				clientConfig.Total = 20
+			}
+		})
+
+		It("repairs them without suspecting the epoch", func() {
+			_, err := recording.DrainClients(50000)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(suspicions).To(BeZero())
+		})
+	})
+
 	When("the network messages have up to a 30ms jittery delay", func() {
 		BeforeEach(func() {
 			recorder.Manglers = []testengine.Mangler{
@@ -146,6 +176,55 @@ var _ = Describe("Mirbft", func() {
 		})
 	})
 
+	When("the network reorders messages and nodes accept them out of order", func() {
+		BeforeEach(func() {
+			recorder.Manglers = []testengine.Mangler{
+				testengine.Jitter(300).Messages(),
+			}
+			for _, nodeConfig := range recorder.NodeConfigs {
+				nodeConfig.OutOfOrderDelivery = true
+			}
+		})
+
+		It("still commits every request exactly once, in the same order on every node", func() {
+			_, err := recording.DrainClients(50000)
+			Expect(err).NotTo(HaveOccurred())
+
+			expected := map[string]uint64{}
+			for _, client := range recording.Clients {
+				expected[string(client.Config.ID)] = client.Config.Total
+			}
+
+			var firstOrder []*pb.Request
+			for _, node := range recording.Nodes {
+				var order []*pb.Request
+				committed := map[string]map[uint64]struct{}{}
+				for commitList := node.State.FirstCommit; commitList != nil; commitList = commitList.Next {
+					for _, forward := range commitList.Commit.QEntry.Requests {
+						reqNos, ok := committed[string(forward.Request.ClientId)]
+						if !ok {
+							reqNos = map[uint64]struct{}{}
+							committed[string(forward.Request.ClientId)] = reqNos
+						}
+						Expect(reqNos).NotTo(HaveKey(forward.Request.ReqNo))
+						reqNos[forward.Request.ReqNo] = struct{}{}
+						order = append(order, forward.Request)
+					}
+				}
+
+				Expect(committed).To(HaveLen(len(expected)))
+				for clientID, total := range expected {
+					Expect(committed[clientID]).To(HaveLen(int(total)))
+				}
+
+				if firstOrder == nil {
+					firstOrder = order
+				}
+				Expect(order).To(Equal(firstOrder))
+			}
+		})
+	})
+
 	When("the network duplicates messages 10 percent of the time", func() {
 		BeforeEach(func() {
 			recorder.Manglers = []testengine.Mangler{
@@ -159,3 +238,54 @@ var _ = Describe("Mirbft", func() {
 		})
 	})
 })
+
+// seqNoDropMangler drops the first prepare and commit for a sequence sent from
+// each of the sources to the target.
+type seqNoDropMangler struct {
+	seqNo   uint64
+	sources []uint64
+	target  uint64
+	dropped map[string]struct{}
+}
+
+func (sdm *seqNoDropMangler) BeforeStep(random int, el *testengine.EventLog) {
+	event := el.NextEventLogEntry.Event
+	recv, ok := event.Type.(*tpb.Event_Receive_)
+	if !ok || event.Target != sdm.target {
+		return
+	}
+
+	var key string
+	switch msg := recv.Receive.Msg.Type.(type) {
+	case *pb.Msg_Prepare:
+		if msg.Prepare.SeqNo != sdm.seqNo {
+			return
+		}
+		key = "prepare"
+	case *pb.Msg_Commit:
+		if msg.Commit.SeqNo != sdm.seqNo {
+			return
+		}
+		key = "commit"
+	default:
+		return
+	}
+
+	for _, source := range sdm.sources {
+		if recv.Receive.Source != source {
+			continue
+		}
+
+		key = fmt.Sprintf("%s-%d", key, source)
+		if _, ok := sdm.dropped[key]; ok {
+			return
+		}
+
+		if sdm.dropped == nil {
+			sdm.dropped = map[string]struct{}{}
+		}
+		sdm.dropped[key] = struct{}{}
+		event.Dropped = true
+		return
+	}
+}
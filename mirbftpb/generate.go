@@ -6,4 +6,4 @@ SPDX-License-Identifier: Apache-2.0
 
 package mirbftpb
 
-//go:generate protoc --go_out=. mirbft.proto
+//go:generate protoc --go_out=plugins=grpc:. mirbft.proto
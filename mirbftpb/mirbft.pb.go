@@ -12,9 +12,12 @@ It has these top-level messages:
 	Persisted
 	QEntry
 	PEntry
+	ECEntry
+	NEntry
 	Msg
 	FetchBatch
 	ForwardBatch
+	FetchSequenceState
 	ForwardRequest
 	Request
 	RequestAck
@@ -29,6 +32,7 @@ It has these top-level messages:
 	NewEpoch
 	NewEpochEcho
 	NewEpochReady
+	SubmitResponse
 */
 package mirbftpb
 
@@ -36,6 +40,11 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
 var _ = fmt.Errorf
@@ -100,6 +109,15 @@ type NetworkConfig struct {
 	// 16 | 7 | 7 | 6 | 6 | 5 | 5 |
 	// 17 | 8 | 7 | 7 | 6 | 6 | 5 |
 	F int32 `protobuf:"varint,5,opt,name=f" json:"f,omitempty"`
+	// ClientWindowWidth is the number of request numbers, beginning with the
+	// lowest uncommitted one, which a client may have outstanding at once.
+	// Increasing this value allows clients to pipeline more requests, at the
+	// cost of more memory per client.  It must be greater than zero.
+	ClientWindowWidth uint64 `protobuf:"varint,6,opt,name=client_window_width,json=clientWindowWidth" json:"client_window_width,omitempty"`
+	// RequireSignatures indicates that every Checkpoint, EpochChange, and NewEpoch
+	// message must be signed by its originator.  Signed messages may be forwarded
+	// as transferable proofs, but each requires a signature and verification.
+	RequireSignatures bool `protobuf:"varint,7,opt,name=require_signatures,json=requireSignatures" json:"require_signatures,omitempty"`
 }
 
 func (m *NetworkConfig) Reset()                    { *m = NetworkConfig{} }
@@ -142,12 +160,29 @@ func (m *NetworkConfig) GetF() int32 {
 	return 0
 }
 
+func (m *NetworkConfig) GetClientWindowWidth() uint64 {
+	if m != nil {
+		return m.ClientWindowWidth
+	}
+	return 0
+}
+
+func (m *NetworkConfig) GetRequireSignatures() bool {
+	if m != nil {
+		return m.RequireSignatures
+	}
+	return false
+}
+
 // Persisted contains data that should be persited by lib user
 type Persisted struct {
 	// Types that are valid to be assigned to Type:
 	//	*Persisted_Qentry
 	//	*Persisted_Pentry
 	//	*Persisted_Checkpoint
+	//	*Persisted_NetworkConfig
+	//	*Persisted_Ecentry
+	//	*Persisted_Nentry
 	Type isPersisted_Type `protobuf_oneof:"type"`
 }
 
@@ -169,10 +204,22 @@ type Persisted_Pentry struct {
 type Persisted_Checkpoint struct {
 	Checkpoint *Checkpoint `protobuf:"bytes,3,opt,name=checkpoint,oneof"`
 }
+type Persisted_NetworkConfig struct {
+	NetworkConfig *NetworkConfig `protobuf:"bytes,4,opt,name=network_config,json=networkConfig,oneof"`
+}
+type Persisted_Ecentry struct {
+	Ecentry *ECEntry `protobuf:"bytes,5,opt,name=ecentry,oneof"`
+}
+type Persisted_Nentry struct {
+	Nentry *NEntry `protobuf:"bytes,6,opt,name=nentry,oneof"`
+}
 
-func (*Persisted_Qentry) isPersisted_Type()     {}
-func (*Persisted_Pentry) isPersisted_Type()     {}
-func (*Persisted_Checkpoint) isPersisted_Type() {}
+func (*Persisted_Qentry) isPersisted_Type()        {}
+func (*Persisted_Pentry) isPersisted_Type()        {}
+func (*Persisted_Checkpoint) isPersisted_Type()    {}
+func (*Persisted_NetworkConfig) isPersisted_Type() {}
+func (*Persisted_Ecentry) isPersisted_Type()       {}
+func (*Persisted_Nentry) isPersisted_Type()        {}
 
 func (m *Persisted) GetType() isPersisted_Type {
 	if m != nil {
@@ -202,12 +249,36 @@ func (m *Persisted) GetCheckpoint() *Checkpoint {
 	return nil
 }
 
+func (m *Persisted) GetNetworkConfig() *NetworkConfig {
+	if x, ok := m.GetType().(*Persisted_NetworkConfig); ok {
+		return x.NetworkConfig
+	}
+	return nil
+}
+
+func (m *Persisted) GetEcentry() *ECEntry {
+	if x, ok := m.GetType().(*Persisted_Ecentry); ok {
+		return x.Ecentry
+	}
+	return nil
+}
+
+func (m *Persisted) GetNentry() *NEntry {
+	if x, ok := m.GetType().(*Persisted_Nentry); ok {
+		return x.Nentry
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Persisted) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Persisted_OneofMarshaler, _Persisted_OneofUnmarshaler, _Persisted_OneofSizer, []interface{}{
 		(*Persisted_Qentry)(nil),
 		(*Persisted_Pentry)(nil),
 		(*Persisted_Checkpoint)(nil),
+		(*Persisted_NetworkConfig)(nil),
+		(*Persisted_Ecentry)(nil),
+		(*Persisted_Nentry)(nil),
 	}
 }
 
@@ -230,6 +301,21 @@ func _Persisted_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Checkpoint); err != nil {
 			return err
 		}
+	case *Persisted_NetworkConfig:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.NetworkConfig); err != nil {
+			return err
+		}
+	case *Persisted_Ecentry:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Ecentry); err != nil {
+			return err
+		}
+	case *Persisted_Nentry:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Nentry); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Persisted.Type has unexpected type %T", x)
@@ -264,6 +350,30 @@ func _Persisted_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buff
 		err := b.DecodeMessage(msg)
 		m.Type = &Persisted_Checkpoint{msg}
 		return true, err
+	case 4: // type.network_config
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(NetworkConfig)
+		err := b.DecodeMessage(msg)
+		m.Type = &Persisted_NetworkConfig{msg}
+		return true, err
+	case 5: // type.ecentry
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ECEntry)
+		err := b.DecodeMessage(msg)
+		m.Type = &Persisted_Ecentry{msg}
+		return true, err
+	case 6: // type.nentry
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(NEntry)
+		err := b.DecodeMessage(msg)
+		m.Type = &Persisted_Nentry{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -288,6 +398,21 @@ func _Persisted_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(3<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Persisted_NetworkConfig:
+		s := proto.Size(x.NetworkConfig)
+		n += proto.SizeVarint(4<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Persisted_Ecentry:
+		s := proto.Size(x.Ecentry)
+		n += proto.SizeVarint(5<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Persisted_Nentry:
+		s := proto.Size(x.Nentry)
+		n += proto.SizeVarint(6<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -371,6 +496,52 @@ func (m *PEntry) GetDigest() []byte {
 	return nil
 }
 
+// ECEntry is an entry which must be persisted before an EpochChange message is
+// broadcast.  It ensures that a restarted replica never sends two different
+// EpochChange messages for the same epoch.
+type ECEntry struct {
+	EpochChange *EpochChange `protobuf:"bytes,1,opt,name=epoch_change,json=epochChange" json:"epoch_change,omitempty"`
+}
+
+func (m *ECEntry) Reset()                    { *m = ECEntry{} }
+func (m *ECEntry) String() string            { return proto.CompactTextString(m) }
+func (*ECEntry) ProtoMessage()               {}
+func (*ECEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *ECEntry) GetEpochChange() *EpochChange {
+	if m != nil {
+		return m.EpochChange
+	}
+	return nil
+}
+
+// NEntry is an entry which must be persisted before a NewEpochEcho (when ready
+// is false) or NewEpochReady (when ready is true) message is broadcast for the
+// embedded config.
+type NEntry struct {
+	Config *EpochConfig `protobuf:"bytes,1,opt,name=config" json:"config,omitempty"`
+	Ready  bool         `protobuf:"varint,2,opt,name=ready" json:"ready,omitempty"`
+}
+
+func (m *NEntry) Reset()                    { *m = NEntry{} }
+func (m *NEntry) String() string            { return proto.CompactTextString(m) }
+func (*NEntry) ProtoMessage()               {}
+func (*NEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *NEntry) GetConfig() *EpochConfig {
+	if m != nil {
+		return m.Config
+	}
+	return nil
+}
+
+func (m *NEntry) GetReady() bool {
+	if m != nil {
+		return m.Ready
+	}
+	return false
+}
+
 type Msg struct {
 	// Types that are valid to be assigned to Type:
 	//	*Msg_Preprepare
@@ -388,13 +559,14 @@ type Msg struct {
 	//	*Msg_FetchRequest
 	//	*Msg_ForwardRequest
 	//	*Msg_RequestAck
+	//	*Msg_FetchSequenceState
 	Type isMsg_Type `protobuf_oneof:"type"`
 }
 
 func (m *Msg) Reset()                    { *m = Msg{} }
 func (m *Msg) String() string            { return proto.CompactTextString(m) }
 func (*Msg) ProtoMessage()               {}
-func (*Msg) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*Msg) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 type isMsg_Type interface {
 	isMsg_Type()
@@ -445,22 +617,26 @@ type Msg_ForwardRequest struct {
 type Msg_RequestAck struct {
 	RequestAck *RequestAck `protobuf:"bytes,15,opt,name=request_ack,json=requestAck,oneof"`
 }
-
-func (*Msg_Preprepare) isMsg_Type()     {}
-func (*Msg_Prepare) isMsg_Type()        {}
-func (*Msg_Commit) isMsg_Type()         {}
-func (*Msg_Checkpoint) isMsg_Type()     {}
-func (*Msg_Suspect) isMsg_Type()        {}
-func (*Msg_EpochChange) isMsg_Type()    {}
-func (*Msg_EpochChangeAck) isMsg_Type() {}
-func (*Msg_NewEpoch) isMsg_Type()       {}
-func (*Msg_NewEpochEcho) isMsg_Type()   {}
-func (*Msg_NewEpochReady) isMsg_Type()  {}
-func (*Msg_FetchBatch) isMsg_Type()     {}
-func (*Msg_ForwardBatch) isMsg_Type()   {}
-func (*Msg_FetchRequest) isMsg_Type()   {}
-func (*Msg_ForwardRequest) isMsg_Type() {}
-func (*Msg_RequestAck) isMsg_Type()     {}
+type Msg_FetchSequenceState struct {
+	FetchSequenceState *FetchSequenceState `protobuf:"bytes,16,opt,name=fetch_sequence_state,json=fetchSequenceState,oneof"`
+}
+
+func (*Msg_Preprepare) isMsg_Type()         {}
+func (*Msg_Prepare) isMsg_Type()            {}
+func (*Msg_Commit) isMsg_Type()             {}
+func (*Msg_Checkpoint) isMsg_Type()         {}
+func (*Msg_Suspect) isMsg_Type()            {}
+func (*Msg_EpochChange) isMsg_Type()        {}
+func (*Msg_EpochChangeAck) isMsg_Type()     {}
+func (*Msg_NewEpoch) isMsg_Type()           {}
+func (*Msg_NewEpochEcho) isMsg_Type()       {}
+func (*Msg_NewEpochReady) isMsg_Type()      {}
+func (*Msg_FetchBatch) isMsg_Type()         {}
+func (*Msg_ForwardBatch) isMsg_Type()       {}
+func (*Msg_FetchRequest) isMsg_Type()       {}
+func (*Msg_ForwardRequest) isMsg_Type()     {}
+func (*Msg_RequestAck) isMsg_Type()         {}
+func (*Msg_FetchSequenceState) isMsg_Type() {}
 
 func (m *Msg) GetType() isMsg_Type {
 	if m != nil {
@@ -574,6 +750,13 @@ func (m *Msg) GetRequestAck() *RequestAck {
 	return nil
 }
 
+func (m *Msg) GetFetchSequenceState() *FetchSequenceState {
+	if x, ok := m.GetType().(*Msg_FetchSequenceState); ok {
+		return x.FetchSequenceState
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Msg) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Msg_OneofMarshaler, _Msg_OneofUnmarshaler, _Msg_OneofSizer, []interface{}{
@@ -592,6 +775,7 @@ func (*Msg) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, fu
 		(*Msg_FetchRequest)(nil),
 		(*Msg_ForwardRequest)(nil),
 		(*Msg_RequestAck)(nil),
+		(*Msg_FetchSequenceState)(nil),
 	}
 }
 
@@ -674,6 +858,11 @@ func _Msg_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.RequestAck); err != nil {
 			return err
 		}
+	case *Msg_FetchSequenceState:
+		b.EncodeVarint(16<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.FetchSequenceState); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Msg.Type has unexpected type %T", x)
@@ -804,6 +993,14 @@ func _Msg_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (b
 		err := b.DecodeMessage(msg)
 		m.Type = &Msg_RequestAck{msg}
 		return true, err
+	case 16: // type.fetch_sequence_state
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(FetchSequenceState)
+		err := b.DecodeMessage(msg)
+		m.Type = &Msg_FetchSequenceState{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -888,6 +1085,11 @@ func _Msg_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(15<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Msg_FetchSequenceState:
+		s := proto.Size(x.FetchSequenceState)
+		n += proto.SizeVarint(16<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -903,7 +1105,7 @@ type FetchBatch struct {
 func (m *FetchBatch) Reset()                    { *m = FetchBatch{} }
 func (m *FetchBatch) String() string            { return proto.CompactTextString(m) }
 func (*FetchBatch) ProtoMessage()               {}
-func (*FetchBatch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*FetchBatch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 func (m *FetchBatch) GetSeqNo() uint64 {
 	if m != nil {
@@ -928,7 +1130,7 @@ type ForwardBatch struct {
 func (m *ForwardBatch) Reset()                    { *m = ForwardBatch{} }
 func (m *ForwardBatch) String() string            { return proto.CompactTextString(m) }
 func (*ForwardBatch) ProtoMessage()               {}
-func (*ForwardBatch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*ForwardBatch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
 
 func (m *ForwardBatch) GetSeqNo() uint64 {
 	if m != nil {
@@ -951,6 +1153,34 @@ func (m *ForwardBatch) GetDigest() []byte {
 	return nil
 }
 
+// FetchSequenceState is sent by a replica which has stopped making progress
+// waiting on a sequence, to request that the other replicas retransmit the
+// messages they have sent for it, along with their checkpoints, in case any
+// were lost.
+type FetchSequenceState struct {
+	Epoch uint64 `protobuf:"varint,1,opt,name=epoch" json:"epoch,omitempty"`
+	SeqNo uint64 `protobuf:"varint,2,opt,name=seq_no,json=seqNo" json:"seq_no,omitempty"`
+}
+
+func (m *FetchSequenceState) Reset()                    { *m = FetchSequenceState{} }
+func (m *FetchSequenceState) String() string            { return proto.CompactTextString(m) }
+func (*FetchSequenceState) ProtoMessage()               {}
+func (*FetchSequenceState) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *FetchSequenceState) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *FetchSequenceState) GetSeqNo() uint64 {
+	if m != nil {
+		return m.SeqNo
+	}
+	return 0
+}
+
 type ForwardRequest struct {
 	Request *Request `protobuf:"bytes,1,opt,name=request" json:"request,omitempty"`
 	Digest  []byte   `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
@@ -959,7 +1189,7 @@ type ForwardRequest struct {
 func (m *ForwardRequest) Reset()                    { *m = ForwardRequest{} }
 func (m *ForwardRequest) String() string            { return proto.CompactTextString(m) }
 func (*ForwardRequest) ProtoMessage()               {}
-func (*ForwardRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*ForwardRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
 
 func (m *ForwardRequest) GetRequest() *Request {
 	if m != nil {
@@ -984,7 +1214,7 @@ type Request struct {
 func (m *Request) Reset()                    { *m = Request{} }
 func (m *Request) String() string            { return proto.CompactTextString(m) }
 func (*Request) ProtoMessage()               {}
-func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
 
 func (m *Request) GetClientId() []byte {
 	if m != nil {
@@ -1016,7 +1246,7 @@ type RequestAck struct {
 func (m *RequestAck) Reset()                    { *m = RequestAck{} }
 func (m *RequestAck) String() string            { return proto.CompactTextString(m) }
 func (*RequestAck) ProtoMessage()               {}
-func (*RequestAck) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+func (*RequestAck) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
 
 func (m *RequestAck) GetClientId() []byte {
 	if m != nil {
@@ -1048,7 +1278,7 @@ type Preprepare struct {
 func (m *Preprepare) Reset()                    { *m = Preprepare{} }
 func (m *Preprepare) String() string            { return proto.CompactTextString(m) }
 func (*Preprepare) ProtoMessage()               {}
-func (*Preprepare) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+func (*Preprepare) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
 
 func (m *Preprepare) GetSeqNo() uint64 {
 	if m != nil {
@@ -1080,7 +1310,7 @@ type Prepare struct {
 func (m *Prepare) Reset()                    { *m = Prepare{} }
 func (m *Prepare) String() string            { return proto.CompactTextString(m) }
 func (*Prepare) ProtoMessage()               {}
-func (*Prepare) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (*Prepare) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 func (m *Prepare) GetSeqNo() uint64 {
 	if m != nil {
@@ -1112,7 +1342,7 @@ type Commit struct {
 func (m *Commit) Reset()                    { *m = Commit{} }
 func (m *Commit) String() string            { return proto.CompactTextString(m) }
 func (*Commit) ProtoMessage()               {}
-func (*Commit) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+func (*Commit) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
 
 func (m *Commit) GetSeqNo() uint64 {
 	if m != nil {
@@ -1138,12 +1368,20 @@ func (m *Commit) GetDigest() []byte {
 type Checkpoint struct {
 	SeqNo uint64 `protobuf:"varint,1,opt,name=seq_no,json=seqNo" json:"seq_no,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// signature is set by the originator of a checkpoint message when the
+	// network requires signatures.  It is not covered by the signature itself.
+	Signature []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	// network_config is only set for persisted checkpoints, when the network
+	// is reconfigured at this checkpoint.  It is the config which takes effect
+	// once the checkpoint is stable, so that the config is persisted atomically
+	// with the checkpoint which reflects it.
+	NetworkConfig *NetworkConfig `protobuf:"bytes,4,opt,name=network_config,json=networkConfig" json:"network_config,omitempty"`
 }
 
 func (m *Checkpoint) Reset()                    { *m = Checkpoint{} }
 func (m *Checkpoint) String() string            { return proto.CompactTextString(m) }
 func (*Checkpoint) ProtoMessage()               {}
-func (*Checkpoint) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+func (*Checkpoint) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
 
 func (m *Checkpoint) GetSeqNo() uint64 {
 	if m != nil {
@@ -1159,6 +1397,20 @@ func (m *Checkpoint) GetValue() []byte {
 	return nil
 }
 
+func (m *Checkpoint) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *Checkpoint) GetNetworkConfig() *NetworkConfig {
+	if m != nil {
+		return m.NetworkConfig
+	}
+	return nil
+}
+
 type Suspect struct {
 	Epoch uint64 `protobuf:"varint,1,opt,name=epoch" json:"epoch,omitempty"`
 }
@@ -1166,7 +1418,7 @@ type Suspect struct {
 func (m *Suspect) Reset()                    { *m = Suspect{} }
 func (m *Suspect) String() string            { return proto.CompactTextString(m) }
 func (*Suspect) ProtoMessage()               {}
-func (*Suspect) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+func (*Suspect) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
 
 func (m *Suspect) GetEpoch() uint64 {
 	if m != nil {
@@ -1191,12 +1443,21 @@ type EpochChange struct {
 	// q_set contains the entries for the Q-set as defined by the classical
 	// PBFT view-change protocol.
 	QSet []*EpochChange_SetEntry `protobuf:"bytes,4,rep,name=q_set,json=qSet" json:"q_set,omitempty"`
+	// signature is set by the originator when the network requires signatures.
+	// It is not covered by the signature itself, nor by the digest.
+	Signature []byte `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+	// last_active_config is the config of the most recent epoch which the
+	// originator knows to have become active, without its final_preprepares.
+	// It is unset if the originator does not know of such an epoch under the
+	// current network config.  The leaders of the new epoch are derived from
+	// the last active configs of the epoch changes a NewEpoch references.
+	LastActiveConfig *EpochConfig `protobuf:"bytes,6,opt,name=last_active_config,json=lastActiveConfig" json:"last_active_config,omitempty"`
 }
 
 func (m *EpochChange) Reset()                    { *m = EpochChange{} }
 func (m *EpochChange) String() string            { return proto.CompactTextString(m) }
 func (*EpochChange) ProtoMessage()               {}
-func (*EpochChange) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+func (*EpochChange) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
 
 func (m *EpochChange) GetNewEpoch() uint64 {
 	if m != nil {
@@ -1226,6 +1487,20 @@ func (m *EpochChange) GetQSet() []*EpochChange_SetEntry {
 	return nil
 }
 
+func (m *EpochChange) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *EpochChange) GetLastActiveConfig() *EpochConfig {
+	if m != nil {
+		return m.LastActiveConfig
+	}
+	return nil
+}
+
 type EpochChange_SetEntry struct {
 	Epoch  uint64 `protobuf:"varint,1,opt,name=epoch" json:"epoch,omitempty"`
 	SeqNo  uint64 `protobuf:"varint,2,opt,name=seq_no,json=seqNo" json:"seq_no,omitempty"`
@@ -1235,7 +1510,7 @@ type EpochChange_SetEntry struct {
 func (m *EpochChange_SetEntry) Reset()                    { *m = EpochChange_SetEntry{} }
 func (m *EpochChange_SetEntry) String() string            { return proto.CompactTextString(m) }
 func (*EpochChange_SetEntry) ProtoMessage()               {}
-func (*EpochChange_SetEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15, 0} }
+func (*EpochChange_SetEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18, 0} }
 
 func (m *EpochChange_SetEntry) GetEpoch() uint64 {
 	if m != nil {
@@ -1274,7 +1549,7 @@ type EpochChangeAck struct {
 func (m *EpochChangeAck) Reset()                    { *m = EpochChangeAck{} }
 func (m *EpochChangeAck) String() string            { return proto.CompactTextString(m) }
 func (*EpochChangeAck) ProtoMessage()               {}
-func (*EpochChangeAck) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+func (*EpochChangeAck) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
 
 func (m *EpochChangeAck) GetOriginator() uint64 {
 	if m != nil {
@@ -1305,7 +1580,7 @@ type EpochConfig struct {
 func (m *EpochConfig) Reset()                    { *m = EpochConfig{} }
 func (m *EpochConfig) String() string            { return proto.CompactTextString(m) }
 func (*EpochConfig) ProtoMessage()               {}
-func (*EpochConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+func (*EpochConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
 
 func (m *EpochConfig) GetNumber() uint64 {
 	if m != nil {
@@ -1347,12 +1622,15 @@ type NewEpoch struct {
 	// replicas in the network.  If two EpochChanges references originated from the same
 	// replica, then the NewEpoch message is invalid.
 	EpochChanges []*NewEpoch_RemoteEpochChange `protobuf:"bytes,2,rep,name=epoch_changes,json=epochChanges" json:"epoch_changes,omitempty"`
+	// signature is set by the leader when the network requires signatures.
+	// It is not covered by the signature itself.
+	Signature []byte `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
 }
 
 func (m *NewEpoch) Reset()                    { *m = NewEpoch{} }
 func (m *NewEpoch) String() string            { return proto.CompactTextString(m) }
 func (*NewEpoch) ProtoMessage()               {}
-func (*NewEpoch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+func (*NewEpoch) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
 
 func (m *NewEpoch) GetConfig() *EpochConfig {
 	if m != nil {
@@ -1368,6 +1646,13 @@ func (m *NewEpoch) GetEpochChanges() []*NewEpoch_RemoteEpochChange {
 	return nil
 }
 
+func (m *NewEpoch) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
 type NewEpoch_RemoteEpochChange struct {
 	NodeId uint64 `protobuf:"varint,1,opt,name=node_id,json=nodeId" json:"node_id,omitempty"`
 	Digest []byte `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
@@ -1376,7 +1661,7 @@ type NewEpoch_RemoteEpochChange struct {
 func (m *NewEpoch_RemoteEpochChange) Reset()                    { *m = NewEpoch_RemoteEpochChange{} }
 func (m *NewEpoch_RemoteEpochChange) String() string            { return proto.CompactTextString(m) }
 func (*NewEpoch_RemoteEpochChange) ProtoMessage()               {}
-func (*NewEpoch_RemoteEpochChange) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18, 0} }
+func (*NewEpoch_RemoteEpochChange) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21, 0} }
 
 func (m *NewEpoch_RemoteEpochChange) GetNodeId() uint64 {
 	if m != nil {
@@ -1404,7 +1689,7 @@ type NewEpochEcho struct {
 func (m *NewEpochEcho) Reset()                    { *m = NewEpochEcho{} }
 func (m *NewEpochEcho) String() string            { return proto.CompactTextString(m) }
 func (*NewEpochEcho) ProtoMessage()               {}
-func (*NewEpochEcho) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+func (*NewEpochEcho) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
 
 func (m *NewEpochEcho) GetConfig() *EpochConfig {
 	if m != nil {
@@ -1421,7 +1706,7 @@ type NewEpochReady struct {
 func (m *NewEpochReady) Reset()                    { *m = NewEpochReady{} }
 func (m *NewEpochReady) String() string            { return proto.CompactTextString(m) }
 func (*NewEpochReady) ProtoMessage()               {}
-func (*NewEpochReady) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+func (*NewEpochReady) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
 
 func (m *NewEpochReady) GetConfig() *EpochConfig {
 	if m != nil {
@@ -1430,14 +1715,28 @@ func (m *NewEpochReady) GetConfig() *EpochConfig {
 	return nil
 }
 
+// SubmitResponse is returned once a submitted request has been accepted into
+// the client window of the receiving node.  It does not indicate that the
+// request has been committed.
+type SubmitResponse struct {
+}
+
+func (m *SubmitResponse) Reset()                    { *m = SubmitResponse{} }
+func (m *SubmitResponse) String() string            { return proto.CompactTextString(m) }
+func (*SubmitResponse) ProtoMessage()               {}
+func (*SubmitResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
 func init() {
 	proto.RegisterType((*NetworkConfig)(nil), "mirbftpb.NetworkConfig")
 	proto.RegisterType((*Persisted)(nil), "mirbftpb.Persisted")
 	proto.RegisterType((*QEntry)(nil), "mirbftpb.QEntry")
 	proto.RegisterType((*PEntry)(nil), "mirbftpb.PEntry")
+	proto.RegisterType((*ECEntry)(nil), "mirbftpb.ECEntry")
+	proto.RegisterType((*NEntry)(nil), "mirbftpb.NEntry")
 	proto.RegisterType((*Msg)(nil), "mirbftpb.Msg")
 	proto.RegisterType((*FetchBatch)(nil), "mirbftpb.FetchBatch")
 	proto.RegisterType((*ForwardBatch)(nil), "mirbftpb.ForwardBatch")
+	proto.RegisterType((*FetchSequenceState)(nil), "mirbftpb.FetchSequenceState")
 	proto.RegisterType((*ForwardRequest)(nil), "mirbftpb.ForwardRequest")
 	proto.RegisterType((*Request)(nil), "mirbftpb.Request")
 	proto.RegisterType((*RequestAck)(nil), "mirbftpb.RequestAck")
@@ -1454,79 +1753,242 @@ func init() {
 	proto.RegisterType((*NewEpoch_RemoteEpochChange)(nil), "mirbftpb.NewEpoch.RemoteEpochChange")
 	proto.RegisterType((*NewEpochEcho)(nil), "mirbftpb.NewEpochEcho")
 	proto.RegisterType((*NewEpochReady)(nil), "mirbftpb.NewEpochReady")
+	proto.RegisterType((*SubmitResponse)(nil), "mirbftpb.SubmitResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Consensus service
+
+type ConsensusClient interface {
+	// Step carries the messages sent from one replica to another.  Each replica
+	// opens a stream to every other replica over which it sends its messages,
+	// the stream in the reverse direction is unused.
+	Step(ctx context.Context, opts ...grpc.CallOption) (Consensus_StepClient, error)
+	// Submit proposes a client request to the receiving replica.
+	Submit(ctx context.Context, in *Request, opts ...grpc.CallOption) (*SubmitResponse, error)
+}
+
+type consensusClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewConsensusClient(cc *grpc.ClientConn) ConsensusClient {
+	return &consensusClient{cc}
+}
+
+func (c *consensusClient) Step(ctx context.Context, opts ...grpc.CallOption) (Consensus_StepClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Consensus_serviceDesc.Streams[0], c.cc, "/mirbftpb.Consensus/Step", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &consensusStepClient{stream}
+	return x, nil
+}
+
+type Consensus_StepClient interface {
+	Send(*Msg) error
+	Recv() (*Msg, error)
+	grpc.ClientStream
+}
+
+type consensusStepClient struct {
+	grpc.ClientStream
+}
+
+func (x *consensusStepClient) Send(m *Msg) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *consensusStepClient) Recv() (*Msg, error) {
+	m := new(Msg)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *consensusClient) Submit(ctx context.Context, in *Request, opts ...grpc.CallOption) (*SubmitResponse, error) {
+	out := new(SubmitResponse)
+	err := grpc.Invoke(ctx, "/mirbftpb.Consensus/Submit", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Consensus service
+
+type ConsensusServer interface {
+	// Step carries the messages sent from one replica to another.  Each replica
+	// opens a stream to every other replica over which it sends its messages,
+	// the stream in the reverse direction is unused.
+	Step(Consensus_StepServer) error
+	// Submit proposes a client request to the receiving replica.
+	Submit(context.Context, *Request) (*SubmitResponse, error)
+}
+
+func RegisterConsensusServer(s *grpc.Server, srv ConsensusServer) {
+	s.RegisterService(&_Consensus_serviceDesc, srv)
+}
+
+func _Consensus_Step_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ConsensusServer).Step(&consensusStepServer{stream})
+}
+
+type Consensus_StepServer interface {
+	Send(*Msg) error
+	Recv() (*Msg, error)
+	grpc.ServerStream
+}
+
+type consensusStepServer struct {
+	grpc.ServerStream
+}
+
+func (x *consensusStepServer) Send(m *Msg) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *consensusStepServer) Recv() (*Msg, error) {
+	m := new(Msg)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Consensus_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsensusServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mirbftpb.Consensus/Submit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsensusServer).Submit(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Consensus_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "mirbftpb.Consensus",
+	HandlerType: (*ConsensusServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Submit",
+			Handler:    _Consensus_Submit_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Step",
+			Handler:       _Consensus_Step_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "mirbft.proto",
 }
 
 func init() { proto.RegisterFile("mirbft.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1097 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xcd, 0x6e, 0xdb, 0x46,
-	0x10, 0x16, 0x65, 0x8a, 0x92, 0x47, 0x94, 0x6c, 0x6f, 0x12, 0x87, 0x48, 0x81, 0xd4, 0x20, 0x7a,
-	0x10, 0x1c, 0xc4, 0x45, 0x9d, 0x22, 0x69, 0x1b, 0x24, 0x40, 0xec, 0x38, 0x90, 0x81, 0xda, 0x71,
-	0xd6, 0x28, 0xd0, 0x1b, 0x41, 0x91, 0x43, 0x89, 0xb5, 0x44, 0x4a, 0xe4, 0xda, 0x8e, 0xcf, 0x7d,
-	0x8e, 0x9e, 0xfa, 0x08, 0xbd, 0xf5, 0x11, 0xfa, 0x38, 0x7d, 0x82, 0x60, 0x7f, 0x28, 0xae, 0xfe,
-	0x8c, 0x24, 0x30, 0xa0, 0x83, 0x66, 0xe6, 0xfb, 0x76, 0xe7, 0x67, 0x77, 0x66, 0x09, 0xf6, 0x28,
-	0xce, 0x7a, 0x11, 0xdb, 0x1b, 0x67, 0x29, 0x4b, 0x49, 0x43, 0x4a, 0xe3, 0x9e, 0xfb, 0xaf, 0x01,
-	0xad, 0x53, 0x64, 0xd7, 0x69, 0x76, 0x71, 0x98, 0x26, 0x51, 0xdc, 0x27, 0xf7, 0xa1, 0x96, 0xa4,
-	0x21, 0xe6, 0x8e, 0xb1, 0xb3, 0xd6, 0x31, 0xa9, 0x14, 0xc8, 0xf7, 0x70, 0x2f, 0x18, 0x60, 0x70,
-	0x31, 0x4e, 0xe3, 0x84, 0x79, 0x71, 0xc2, 0x30, 0xbb, 0xf2, 0x87, 0x4e, 0x75, 0xc7, 0xe8, 0xd4,
-	0x28, 0x29, 0x4d, 0xc7, 0xca, 0x42, 0x3a, 0xb0, 0x39, 0xf2, 0x3f, 0x7a, 0x38, 0x4e, 0x83, 0x81,
-	0x37, 0xc4, 0xa4, 0xcf, 0x06, 0xce, 0xda, 0x8e, 0xd1, 0x31, 0x69, 0x7b, 0xe4, 0x7f, 0x3c, 0xe2,
-	0xea, 0x5f, 0x85, 0x96, 0xec, 0xc2, 0x56, 0x72, 0x39, 0xea, 0x61, 0xe6, 0xa5, 0x91, 0xd7, 0xbb,
-	0x0c, 0x2e, 0x90, 0xe5, 0x8e, 0x29, 0x16, 0xde, 0x90, 0x86, 0xf7, 0xd1, 0x81, 0x54, 0x13, 0x1b,
-	0x8c, 0xc8, 0xa9, 0x09, 0x9b, 0x11, 0xb9, 0x7f, 0x1b, 0xb0, 0x7e, 0x86, 0x59, 0x1e, 0xe7, 0x0c,
-	0x43, 0xb2, 0x0b, 0xd6, 0x04, 0x13, 0x96, 0xdd, 0x38, 0xc6, 0x8e, 0xd1, 0x69, 0xee, 0x6f, 0xee,
-	0x15, 0x51, 0xee, 0x7d, 0x38, 0xe2, 0xfa, 0x6e, 0x85, 0x2a, 0x04, 0xc7, 0x8e, 0x25, 0xb6, 0x3a,
-	0x8f, 0x3d, 0x9b, 0x62, 0x25, 0x82, 0x3c, 0x07, 0x28, 0xe3, 0x13, 0x31, 0x34, 0xf7, 0xef, 0x97,
-	0xf8, 0xc3, 0xa9, 0xad, 0x5b, 0xa1, 0x1a, 0xf2, 0xc0, 0x02, 0x93, 0xdd, 0x8c, 0xd1, 0xfd, 0xd3,
-	0x00, 0x4b, 0x3a, 0xc0, 0x73, 0x2b, 0x12, 0x22, 0x3c, 0x34, 0xa9, 0x14, 0xc8, 0x03, 0xb0, 0x72,
-	0x9c, 0x78, 0x49, 0x2a, 0x9c, 0x31, 0x69, 0x2d, 0xc7, 0xc9, 0x69, 0x4a, 0xb6, 0xc1, 0x0a, 0xe3,
-	0x3e, 0xe6, 0x72, 0x4f, 0x9b, 0x2a, 0x89, 0xfc, 0x08, 0x8d, 0x0c, 0x27, 0x97, 0x98, 0x8b, 0x34,
-	0xad, 0x75, 0x9a, 0xfb, 0x4e, 0xe9, 0xcd, 0xbb, 0x34, 0xbb, 0xf6, 0xb3, 0x90, 0x4a, 0x00, 0x9d,
-	0x22, 0xdd, 0x13, 0xb0, 0xce, 0xee, 0xce, 0x09, 0xf7, 0x7f, 0x0b, 0xd6, 0x4e, 0xf2, 0x3e, 0x4f,
-	0xce, 0x38, 0x43, 0xfe, 0xf3, 0x33, 0x54, 0x89, 0xd7, 0x92, 0x73, 0x36, 0xb5, 0xf1, 0xe4, 0x94,
-	0x48, 0xf2, 0x14, 0xea, 0x05, 0x49, 0x56, 0x60, 0x6b, 0x96, 0x24, 0x19, 0x05, 0x86, 0xd7, 0x2b,
-	0x48, 0x47, 0xa3, 0xb8, 0xc8, 0xbf, 0x56, 0xaf, 0x43, 0xa1, 0xe7, 0xf5, 0x92, 0x88, 0xb9, 0x7a,
-	0x99, 0x9f, 0x5b, 0x2f, 0xee, 0x52, 0x7e, 0x99, 0x8f, 0x31, 0x60, 0xe2, 0x84, 0xcd, 0xb8, 0x74,
-	0x2e, 0x0d, 0xdc, 0x25, 0x85, 0x21, 0xbf, 0x80, 0x2d, 0x0f, 0x77, 0x30, 0xf0, 0x93, 0x3e, 0x3a,
-	0x96, 0xe0, 0x3c, 0x28, 0x39, 0xe2, 0x8c, 0x1f, 0x0a, 0x63, 0xb7, 0x42, 0x9b, 0x58, 0x8a, 0xe4,
-	0x2d, 0x6c, 0xea, 0x5c, 0xcf, 0x0f, 0x2e, 0x9c, 0xba, 0xe0, 0x3b, 0x4b, 0xf9, 0x6f, 0x82, 0x8b,
-	0x6e, 0x85, 0xb6, 0x71, 0x46, 0x43, 0x7e, 0x80, 0xf5, 0x04, 0xaf, 0xe5, 0x15, 0x73, 0x1a, 0x82,
-	0x4e, 0x4a, 0xfa, 0x29, 0x5e, 0x8b, 0x15, 0xba, 0x15, 0xda, 0x48, 0xd4, 0x7f, 0xf2, 0x1a, 0xda,
-	0x53, 0x8a, 0x87, 0xc1, 0x20, 0x75, 0xd6, 0x05, 0x6f, 0x7b, 0x91, 0x77, 0x14, 0x0c, 0xd2, 0x6e,
-	0x85, 0xda, 0x89, 0x26, 0x93, 0x37, 0xb0, 0x51, 0xf2, 0x33, 0xf4, 0xc3, 0x1b, 0x07, 0xc4, 0x02,
-	0x0f, 0x17, 0x17, 0xa0, 0xdc, 0xdc, 0xad, 0xd0, 0x56, 0xa2, 0x2b, 0xc8, 0x0b, 0x68, 0x46, 0xc8,
-	0x82, 0x81, 0xd7, 0xf3, 0x59, 0x30, 0x70, 0x9a, 0xf3, 0xf5, 0x79, 0xc7, 0x8d, 0x07, 0xdc, 0xc6,
-	0xeb, 0x13, 0x4d, 0x25, 0xf2, 0x0a, 0x5a, 0x91, 0x3c, 0xdd, 0x8a, 0x6a, 0xcf, 0xbb, 0xae, 0x0e,
-	0x7f, 0x41, 0xb6, 0x23, 0x4d, 0x26, 0x2f, 0xa1, 0x25, 0xf7, 0x55, 0x57, 0xc2, 0x69, 0xcd, 0xef,
-	0xac, 0x2e, 0x8d, 0x4c, 0xb6, 0x2d, 0xc0, 0x4a, 0x45, 0x0e, 0x61, 0xa3, 0xd8, 0xbb, 0xa0, 0xb7,
-	0xe7, 0xeb, 0x35, 0x7b, 0xf5, 0x78, 0xbd, 0xa2, 0x19, 0x0d, 0x8f, 0x5c, 0x91, 0x45, 0xc1, 0x37,
-	0x6e, 0xdd, 0x1f, 0xb2, 0xa9, 0x34, 0xed, 0x24, 0x2f, 0x01, 0xca, 0xec, 0x68, 0x37, 0xd6, 0x58,
-	0x7e, 0x63, 0xab, 0x33, 0x37, 0xf6, 0x0a, 0x6c, 0x3d, 0x3f, 0xab, 0xe8, 0x2f, 0xc0, 0xd6, 0x9c,
-	0xcc, 0x9d, 0xaa, 0xe8, 0x30, 0x4b, 0xbd, 0xa4, 0xcd, 0xd2, 0xc7, 0x7c, 0x65, 0xa7, 0xf8, 0x0d,
-	0xda, 0xb3, 0x99, 0x21, 0x4f, 0xa0, 0x5e, 0x24, 0xd1, 0x98, 0xbf, 0x68, 0x45, 0xe3, 0x2a, 0x10,
-	0x2b, 0xc3, 0xf9, 0x00, 0xf5, 0x62, 0xbd, 0x6f, 0x60, 0x3d, 0x18, 0xc6, 0xc8, 0xe7, 0x52, 0x28,
-	0x56, 0xb4, 0x69, 0x43, 0x2a, 0x8e, 0x43, 0x1e, 0x66, 0x36, 0xd3, 0xd7, 0x32, 0x11, 0x26, 0x01,
-	0x33, 0xf4, 0x99, 0xaf, 0x7c, 0x15, 0xff, 0xdd, 0xdf, 0x01, 0xca, 0xe0, 0xbe, 0x6a, 0xd5, 0x55,
-	0x39, 0x40, 0x80, 0xb2, 0x13, 0xae, 0xca, 0xfc, 0xb4, 0x2f, 0x57, 0xf5, 0xbe, 0xbc, 0x0b, 0x35,
-	0x79, 0xda, 0xd7, 0x6e, 0x29, 0x84, 0x84, 0xb8, 0xa7, 0x50, 0x3f, 0xfb, 0x9a, 0x3d, 0x56, 0xb9,
-	0x7d, 0x02, 0x96, 0xec, 0xae, 0x77, 0xb3, 0xdc, 0xcf, 0x00, 0x65, 0xf3, 0xbd, 0x65, 0xc9, 0x2b,
-	0x7f, 0x78, 0x89, 0xaa, 0xdc, 0x52, 0x70, 0xbf, 0x85, 0xba, 0x6a, 0xc1, 0xcb, 0xc7, 0x97, 0xfb,
-	0x57, 0x15, 0x9a, 0x5a, 0xc3, 0xe4, 0xd5, 0x2b, 0x7b, 0xa3, 0x44, 0x96, 0x5d, 0xf0, 0x39, 0x34,
-	0xcb, 0xbe, 0xbf, 0xe4, 0x88, 0x97, 0x5e, 0x52, 0x1d, 0x48, 0x9e, 0x41, 0x6d, 0xec, 0xe5, 0xc8,
-	0x54, 0x2d, 0x1e, 0x2f, 0xed, 0xd5, 0x7b, 0xe7, 0xc8, 0xc4, 0xa0, 0xa5, 0xe6, 0xf8, 0x1c, 0x19,
-	0x27, 0x4d, 0x04, 0xc9, 0xfc, 0x3c, 0xd2, 0xe4, 0x1c, 0xd9, 0xa3, 0xf7, 0xd0, 0x28, 0x34, 0x77,
-	0x33, 0xaf, 0xff, 0x80, 0xf6, 0xec, 0x3c, 0x21, 0x8f, 0x01, 0xd2, 0x2c, 0xee, 0xc7, 0x89, 0xcf,
-	0xd2, 0x4c, 0xad, 0xad, 0x69, 0xc8, 0x4f, 0x73, 0xf3, 0xad, 0x7a, 0xcb, 0x7c, 0x9b, 0x99, 0x6e,
-	0xee, 0x3f, 0x46, 0x51, 0x0b, 0xf9, 0xa2, 0xdc, 0x06, 0x4b, 0xbe, 0xe3, 0xd4, 0x2e, 0x4a, 0x22,
-	0x47, 0x70, 0x2f, 0x67, 0x7e, 0xc6, 0xe2, 0xa4, 0xef, 0x69, 0x13, 0xbb, 0xba, 0x7a, 0x62, 0x53,
-	0x52, 0x10, 0xb4, 0x83, 0xe4, 0x40, 0x7d, 0x88, 0x7e, 0x88, 0x59, 0x2e, 0xea, 0x62, 0xd2, 0x42,
-	0x24, 0x4f, 0x60, 0x2b, 0x8a, 0x13, 0x7f, 0xe8, 0x95, 0x0f, 0x0f, 0xf9, 0x64, 0xb2, 0xe9, 0xa6,
-	0x30, 0x94, 0x97, 0x32, 0x77, 0xff, 0x33, 0xa0, 0x51, 0x8c, 0x2e, 0xf2, 0x94, 0xbf, 0x37, 0xb8,
-	0xf3, 0xaa, 0x43, 0x2d, 0x84, 0x2d, 0x8c, 0x54, 0x81, 0xc8, 0x31, 0xb4, 0xf4, 0x5c, 0x15, 0x47,
-	0xea, 0xbb, 0xc5, 0xa1, 0xb8, 0x47, 0x71, 0x94, 0x32, 0xd4, 0x73, 0x67, 0x6b, 0xb9, 0xcb, 0x1f,
-	0xbd, 0x85, 0xad, 0x05, 0x08, 0x79, 0x08, 0x75, 0xfe, 0x0c, 0x2f, 0x3a, 0x11, 0x4f, 0x61, 0x1a,
-	0xe2, 0x71, 0xb8, 0xb2, 0x3b, 0xbe, 0x02, 0x5b, 0x9f, 0xe3, 0x5f, 0x18, 0x8f, 0xfb, 0x9a, 0x7f,
-	0x14, 0xe8, 0x43, 0xfb, 0xcb, 0xf8, 0x3d, 0x4b, 0x7c, 0x66, 0x3c, 0xfb, 0x14, 0x00, 0x00, 0xff,
-	0xff, 0x8d, 0xfe, 0xd0, 0x6f, 0x76, 0x0c, 0x00, 0x00,
+	// 1359 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xdb, 0x6e, 0xdb, 0x46,
+	0x10, 0x15, 0x75, 0xa1, 0xe4, 0x11, 0x25, 0xcb, 0x9b, 0x1b, 0xe1, 0x06, 0xa9, 0x21, 0xf4, 0x41,
+	0x48, 0x60, 0xb7, 0x75, 0xda, 0xa4, 0x68, 0x90, 0xa0, 0x8e, 0xe2, 0x40, 0x06, 0x6a, 0x47, 0x59,
+	0xa1, 0x48, 0xdf, 0x08, 0x8a, 0x5c, 0x4a, 0xac, 0x25, 0x52, 0xe6, 0xae, 0xac, 0xe4, 0xad, 0x40,
+	0x3f, 0xa2, 0xe8, 0x77, 0xf4, 0x07, 0xfa, 0xd0, 0x8f, 0xe9, 0x67, 0x14, 0x7b, 0xe1, 0x55, 0x96,
+	0x11, 0x07, 0x01, 0xf4, 0xa0, 0x9d, 0x39, 0x67, 0x2f, 0x67, 0x66, 0x67, 0x87, 0x60, 0xcc, 0xfd,
+	0x68, 0xec, 0xb1, 0x83, 0x45, 0x14, 0xb2, 0x10, 0x35, 0xe4, 0x68, 0x31, 0xee, 0xfe, 0x55, 0x86,
+	0xd6, 0x19, 0x61, 0xab, 0x30, 0x3a, 0xef, 0x87, 0x81, 0xe7, 0x4f, 0xd0, 0x6d, 0xa8, 0x05, 0xa1,
+	0x4b, 0xa8, 0xa9, 0xed, 0x55, 0x7a, 0x55, 0x2c, 0x07, 0xe8, 0x6b, 0xb8, 0xe5, 0x4c, 0x89, 0x73,
+	0xbe, 0x08, 0xfd, 0x80, 0x59, 0x7e, 0xc0, 0x48, 0x74, 0x69, 0xcf, 0xcc, 0xf2, 0x9e, 0xd6, 0xab,
+	0x61, 0x94, 0xba, 0x4e, 0x94, 0x07, 0xf5, 0xa0, 0x33, 0xb7, 0xdf, 0x5b, 0x64, 0x11, 0x3a, 0x53,
+	0x6b, 0x46, 0x82, 0x09, 0x9b, 0x9a, 0x95, 0x3d, 0xad, 0x57, 0xc5, 0xed, 0xb9, 0xfd, 0xfe, 0x98,
+	0x9b, 0x7f, 0x16, 0x56, 0xf4, 0x10, 0x76, 0x82, 0xe5, 0x7c, 0x4c, 0x22, 0x2b, 0xf4, 0xac, 0xf1,
+	0xd2, 0x39, 0x27, 0x8c, 0x9a, 0x55, 0x31, 0xf1, 0xb6, 0x74, 0xbc, 0xf1, 0x5e, 0x4a, 0x33, 0x32,
+	0x40, 0xf3, 0xcc, 0x9a, 0xf0, 0x69, 0x1e, 0x3a, 0x80, 0x5b, 0xce, 0xcc, 0x27, 0x01, 0xb3, 0x56,
+	0x7e, 0xe0, 0x86, 0x2b, 0x6b, 0xe5, 0xbb, 0x6c, 0x6a, 0xea, 0x62, 0x99, 0x1d, 0xe9, 0x7a, 0x27,
+	0x3c, 0xef, 0xb8, 0x03, 0xed, 0x03, 0x8a, 0xc8, 0xc5, 0xd2, 0x8f, 0x88, 0x45, 0xfd, 0x49, 0x60,
+	0xb3, 0x65, 0x44, 0xa8, 0x59, 0xdf, 0xd3, 0x7a, 0x0d, 0xbc, 0xa3, 0x3c, 0xa3, 0xc4, 0xd1, 0xfd,
+	0xa7, 0x0c, 0x5b, 0x43, 0x12, 0x51, 0x9f, 0x32, 0xe2, 0xa2, 0x87, 0xa0, 0x5f, 0x90, 0x80, 0x45,
+	0x1f, 0x4c, 0x6d, 0x4f, 0xeb, 0x35, 0x0f, 0x3b, 0x07, 0xb1, 0x88, 0x07, 0x6f, 0x8f, 0xb9, 0x7d,
+	0x50, 0xc2, 0x0a, 0xc1, 0xb1, 0x0b, 0x89, 0x2d, 0x17, 0xb1, 0xc3, 0x04, 0x2b, 0x11, 0xe8, 0x09,
+	0x40, 0x2a, 0x9f, 0x90, 0xa8, 0x79, 0x78, 0x3b, 0xc5, 0xf7, 0x13, 0xdf, 0xa0, 0x84, 0x33, 0x48,
+	0xf4, 0x13, 0xb4, 0x03, 0x19, 0x38, 0xcb, 0x11, 0x91, 0x13, 0x9a, 0x35, 0x0f, 0xef, 0xa5, 0xdc,
+	0x5c, 0x60, 0x07, 0x25, 0xdc, 0x0a, 0x72, 0x91, 0xde, 0x87, 0x3a, 0x71, 0xe4, 0x36, 0x6b, 0x82,
+	0xba, 0x93, 0x52, 0x8f, 0xfb, 0xf1, 0x3e, 0x63, 0x0c, 0x3f, 0x54, 0x20, 0xd1, 0x7a, 0xf1, 0x50,
+	0x67, 0xc9, 0xa1, 0x24, 0xe2, 0xa5, 0x0e, 0x55, 0xf6, 0x61, 0x41, 0xba, 0x7f, 0x68, 0xa0, 0x4b,
+	0x75, 0x78, 0x5e, 0x89, 0x64, 0x10, 0xf2, 0x55, 0xb1, 0x1c, 0xa0, 0x3b, 0xa0, 0x53, 0x72, 0x61,
+	0x05, 0xa1, 0x50, 0xaa, 0x8a, 0x6b, 0x94, 0x5c, 0x9c, 0x85, 0xe8, 0x2e, 0xe8, 0xae, 0x3f, 0x21,
+	0x54, 0x0a, 0x62, 0x60, 0x35, 0x42, 0xdf, 0x41, 0x83, 0xc7, 0x89, 0x50, 0x91, 0x22, 0x95, 0x5e,
+	0xf3, 0xd0, 0x4c, 0x77, 0xf1, 0x3a, 0x8c, 0x56, 0x76, 0xe4, 0x62, 0x09, 0xc0, 0x09, 0xb2, 0x7b,
+	0x0a, 0xfa, 0xf0, 0xf3, 0x6d, 0xa2, 0xdb, 0x87, 0xba, 0x92, 0x07, 0xfd, 0x00, 0x86, 0xcc, 0x70,
+	0x67, 0x6a, 0x07, 0x13, 0xa2, 0x52, 0xe3, 0x4e, 0x46, 0x47, 0xee, 0xed, 0x0b, 0x27, 0x6e, 0x92,
+	0x74, 0xc0, 0xf7, 0x24, 0x55, 0x43, 0xfb, 0xa0, 0xab, 0x00, 0x6e, 0x60, 0x0b, 0x27, 0x56, 0x20,
+	0x7e, 0x84, 0x88, 0xd8, 0xae, 0x4c, 0xad, 0x06, 0x96, 0x83, 0xee, 0xbf, 0x75, 0xa8, 0x9c, 0xd2,
+	0x09, 0xcf, 0xa6, 0x45, 0x44, 0xf8, 0xcf, 0x8e, 0xe2, 0xed, 0x64, 0xb2, 0x69, 0x98, 0xf8, 0x78,
+	0x36, 0xa5, 0x48, 0x9e, 0x0b, 0x31, 0xa9, 0x5c, 0xcc, 0x85, 0x61, 0xc2, 0x88, 0x31, 0x3c, 0x17,
+	0x9c, 0x70, 0x3e, 0xf7, 0xe3, 0x84, 0xcd, 0xe4, 0x42, 0x5f, 0xd8, 0x79, 0x2e, 0x48, 0x44, 0x21,
+	0xc1, 0xab, 0x1f, 0x9d, 0xe0, 0xfb, 0x50, 0xa7, 0x4b, 0xba, 0x20, 0x0e, 0x5b, 0x4f, 0xcf, 0x91,
+	0x74, 0xf0, 0x2d, 0x29, 0x0c, 0xfa, 0xb1, 0x10, 0x0a, 0xfd, 0x9a, 0x50, 0x0c, 0x4a, 0xb9, 0x60,
+	0xa0, 0x57, 0xd0, 0xc9, 0x72, 0x2d, 0xdb, 0x39, 0x17, 0x65, 0x21, 0x97, 0x5e, 0x19, 0xfe, 0x91,
+	0x73, 0x3e, 0x28, 0xe1, 0x36, 0xc9, 0x59, 0xd0, 0xb7, 0xb0, 0x15, 0x90, 0x95, 0x2c, 0x79, 0x66,
+	0x43, 0xd0, 0x51, 0xf6, 0x32, 0xae, 0xc4, 0x0c, 0x83, 0x12, 0x6e, 0x04, 0xea, 0x3f, 0x7a, 0x01,
+	0xed, 0x84, 0x62, 0x11, 0x67, 0x1a, 0x9a, 0x5b, 0x82, 0x77, 0x77, 0x9d, 0x77, 0xec, 0x4c, 0xc3,
+	0x41, 0x09, 0x1b, 0x41, 0x66, 0x8c, 0x8e, 0x60, 0x3b, 0xe5, 0xcb, 0xb4, 0x80, 0xf5, 0x2a, 0x20,
+	0x09, 0x98, 0xbb, 0x65, 0x15, 0xc8, 0x18, 0xd0, 0x53, 0x68, 0x7a, 0x84, 0x39, 0x53, 0x6b, 0x6c,
+	0x33, 0x67, 0x6a, 0x36, 0x8b, 0xf1, 0x79, 0xcd, 0x9d, 0x2f, 0xb9, 0x8f, 0xc7, 0xc7, 0x4b, 0x46,
+	0xe8, 0x39, 0xb4, 0x3c, 0x79, 0xe3, 0x14, 0xd5, 0x28, 0x6e, 0x5d, 0x5d, 0xc8, 0x98, 0x6c, 0x78,
+	0x99, 0x31, 0x7a, 0x06, 0x2d, 0xb9, 0xae, 0xba, 0xa6, 0x66, 0xab, 0xb8, 0xb2, 0xba, 0xc8, 0x52,
+	0x6c, 0x43, 0x80, 0x95, 0x09, 0xf5, 0x61, 0x3b, 0x5e, 0x3b, 0xa6, 0xb7, 0x8b, 0xf1, 0xca, 0x97,
+	0x03, 0x1e, 0x2f, 0x2f, 0x67, 0xe1, 0x27, 0x57, 0x64, 0x11, 0xf0, 0xed, 0x6b, 0xd7, 0x87, 0x28,
+	0x19, 0xa1, 0x21, 0xdc, 0x96, 0x5b, 0xa7, 0xdc, 0x16, 0x38, 0xc4, 0xa2, 0xcc, 0x66, 0xc4, 0xec,
+	0x88, 0x19, 0xee, 0x17, 0xb4, 0x1b, 0x29, 0xd0, 0x88, 0x63, 0x06, 0x25, 0x8c, 0xbc, 0x35, 0x6b,
+	0x52, 0x2f, 0x9f, 0x01, 0xa4, 0x7a, 0x67, 0xea, 0x92, 0x76, 0x75, 0x5d, 0x2a, 0xe7, 0xea, 0xd2,
+	0x25, 0x18, 0x59, 0xc5, 0x37, 0xd1, 0x9f, 0x82, 0x91, 0x39, 0x36, 0x35, 0xcb, 0x7b, 0x95, 0x4d,
+	0xe7, 0xc6, 0xcd, 0xf4, 0xd4, 0x74, 0x63, 0x3d, 0x3c, 0x02, 0xb4, 0x7e, 0xd0, 0x1b, 0x95, 0xda,
+	0xee, 0x2f, 0xd0, 0xce, 0x87, 0x0b, 0x3d, 0x82, 0x7a, 0x1c, 0x59, 0xad, 0x78, 0xfb, 0x15, 0x06,
+	0xc7, 0x88, 0x8d, 0x8a, 0xbc, 0x85, 0x7a, 0x3c, 0xdf, 0x17, 0xb0, 0xa5, 0x7a, 0x05, 0xdf, 0x15,
+	0x33, 0x1a, 0xb8, 0x21, 0x0d, 0x27, 0x2e, 0xdf, 0x55, 0x94, 0xdb, 0x55, 0x24, 0x94, 0x42, 0x50,
+	0x75, 0x6d, 0x66, 0xab, 0xe3, 0x8a, 0xff, 0xdd, 0x5f, 0x01, 0x52, 0x7d, 0x3e, 0x69, 0xd6, 0x4d,
+	0x32, 0x12, 0x80, 0xb4, 0x3c, 0x6f, 0x0a, 0x5e, 0xa2, 0x6a, 0x39, 0xab, 0xea, 0x43, 0xa8, 0xc9,
+	0x2b, 0x58, 0xb9, 0x26, 0x96, 0x12, 0xd2, 0x3d, 0x83, 0xfa, 0xf0, 0x53, 0xd6, 0xd8, 0xb4, 0xed,
+	0x53, 0xd0, 0x65, 0xc9, 0xff, 0x3c, 0xd3, 0xfd, 0xa9, 0x01, 0xa4, 0x4f, 0xc2, 0x35, 0x73, 0x5e,
+	0xda, 0xb3, 0x25, 0x51, 0xf1, 0x96, 0x03, 0x74, 0x1f, 0xb6, 0x92, 0xbe, 0x4e, 0x4d, 0x9b, 0x1a,
+	0x64, 0xad, 0xbd, 0x41, 0xc3, 0x54, 0x68, 0x97, 0xba, 0x5f, 0x42, 0x5d, 0x3d, 0x3b, 0x57, 0xe7,
+	0x76, 0xf7, 0xf7, 0x0a, 0x34, 0x33, 0x8f, 0x04, 0x4f, 0x8e, 0xf4, 0x3d, 0x90, 0xc8, 0xb4, 0xf2,
+	0x3f, 0x81, 0x66, 0xfa, 0xd6, 0x5d, 0x71, 0x09, 0x53, 0x0d, 0x70, 0x16, 0x88, 0x1e, 0x43, 0x6d,
+	0x61, 0x51, 0xc2, 0x54, 0xa8, 0x1f, 0x5c, 0xf9, 0x3e, 0x1d, 0x8c, 0x08, 0x13, 0xcd, 0x05, 0xae,
+	0x2e, 0x46, 0x84, 0x71, 0xd2, 0x85, 0x20, 0x55, 0x3f, 0x8e, 0x74, 0xc1, 0x49, 0x39, 0x35, 0x6b,
+	0x45, 0x35, 0xfb, 0x80, 0x66, 0xb6, 0x28, 0x21, 0xcc, 0xbf, 0x24, 0xb1, 0xa2, 0xfa, 0x75, 0x1d,
+	0x4c, 0x87, 0x13, 0x8e, 0x04, 0x5e, 0x5a, 0x76, 0xdf, 0x40, 0x23, 0x5e, 0xf4, 0xf3, 0xb4, 0x66,
+	0xbf, 0x41, 0x3b, 0xff, 0x4c, 0xa3, 0x07, 0x00, 0x61, 0xe4, 0x4f, 0xfc, 0xc0, 0x66, 0x61, 0xa4,
+	0xe6, 0xce, 0x58, 0xd6, 0x3a, 0xb8, 0xf2, 0x47, 0x77, 0x70, 0x7f, 0x6b, 0x71, 0xb8, 0x65, 0x63,
+	0x76, 0x17, 0x74, 0xf9, 0xb9, 0xa2, 0x56, 0x51, 0x23, 0x74, 0x0c, 0xb7, 0x28, 0xb3, 0x23, 0xe6,
+	0x07, 0x13, 0x2b, 0xd3, 0x08, 0x95, 0x37, 0x37, 0x42, 0x18, 0xc5, 0x84, 0xd4, 0x86, 0x4c, 0xa8,
+	0xcf, 0x88, 0xed, 0x92, 0x88, 0x8a, 0xd0, 0x57, 0x71, 0x3c, 0x44, 0x8f, 0x60, 0xc7, 0xf3, 0x03,
+	0x7b, 0x66, 0xa5, 0xfd, 0x9c, 0xec, 0x8e, 0x0d, 0xdc, 0x11, 0x8e, 0xb4, 0xac, 0xd0, 0xee, 0x7f,
+	0x1a, 0x34, 0xe2, 0x8e, 0xe0, 0xa6, 0xad, 0xe7, 0x09, 0xb4, 0xb2, 0x5a, 0xc5, 0x59, 0xfb, 0xd5,
+	0x7a, 0xaf, 0x71, 0x80, 0xc9, 0x3c, 0x64, 0x24, 0xab, 0x9d, 0x91, 0xd1, 0x8e, 0x5e, 0x7f, 0x55,
+	0x77, 0x5f, 0xc1, 0xce, 0xda, 0x04, 0xe8, 0x1e, 0xd4, 0xf9, 0xb7, 0x68, 0x5c, 0x69, 0xb9, 0xc0,
+	0xa1, 0x4b, 0x4e, 0xdc, 0x8d, 0xd5, 0xff, 0x39, 0x18, 0xd9, 0xe6, 0xe9, 0x86, 0xa7, 0xed, 0xbe,
+	0x80, 0x56, 0x4c, 0x97, 0x9d, 0xd2, 0x0d, 0xf9, 0x1d, 0x68, 0x8f, 0x96, 0xe3, 0xb9, 0xcf, 0x30,
+	0xa1, 0x8b, 0x30, 0xa0, 0xe4, 0x70, 0x06, 0x5b, 0x7d, 0xfe, 0x27, 0xa0, 0x4b, 0x8a, 0x7a, 0x50,
+	0x1d, 0x31, 0xb2, 0x40, 0xad, 0x74, 0x96, 0x53, 0x3a, 0xd9, 0xcd, 0x0f, 0x7b, 0xda, 0x37, 0x1a,
+	0xfa, 0x1e, 0x74, 0x39, 0x11, 0x5a, 0x7f, 0x03, 0x77, 0xcd, 0x6c, 0x53, 0x9c, 0x5d, 0x6d, 0xac,
+	0x8b, 0x6f, 0xfd, 0xc7, 0xff, 0x0f, 0x00, 0x6e, 0x1a, 0x1c, 0xfb, 0xfb, 0x0f, 0x00, 0x00,
 }
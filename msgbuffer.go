@@ -0,0 +1,191 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft
+
+import (
+	"container/list"
+
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+// msgBufferKind partitions the messages buffered for a node, so that a flood
+// of one kind of message, such as Preprepares from the future, cannot evict
+// the messages of another, such as a NewEpoch.
+type msgBufferKind int
+
+const (
+	epochBufferKind       msgBufferKind = iota // Preprepare, Prepare, Commit, and FetchSequenceState
+	checkpointBufferKind                       // Checkpoint
+	clientBufferKind                           // RequestAck, FetchRequest, ForwardRequest, FetchBatch, and ForwardBatch
+	epochChangeBufferKind                      // Suspect, EpochChange, EpochChangeAck, NewEpoch, NewEpochEcho, and NewEpochReady
+	numMsgBufferKinds
+)
+
+var msgBufferKindNames = [numMsgBufferKinds]string{
+	epochBufferKind:       "epoch",
+	checkpointBufferKind:  "checkpoint",
+	clientBufferKind:      "client",
+	epochChangeBufferKind: "epoch_change",
+}
+
+func (k msgBufferKind) String() string {
+	return msgBufferKindNames[k]
+}
+
+func msgBufferKindOf(msg *pb.Msg) msgBufferKind {
+	switch msg.Type.(type) {
+	case *pb.Msg_Checkpoint:
+		return checkpointBufferKind
+	case *pb.Msg_RequestAck, *pb.Msg_FetchRequest, *pb.Msg_ForwardRequest, *pb.Msg_FetchBatch, *pb.Msg_ForwardBatch:
+		return clientBufferKind
+	case *pb.Msg_Suspect, *pb.Msg_EpochChange, *pb.Msg_EpochChangeAck, *pb.Msg_NewEpoch, *pb.Msg_NewEpochEcho, *pb.Msg_NewEpochReady:
+		return epochChangeBufferKind
+	default:
+		return epochBufferKind
+	}
+}
+
+type bufferedMsg struct {
+	arrival uint64
+	size    int
+	msg     *pb.Msg
+}
+
+type msgPartition struct {
+	msgs         *list.List
+	size         int
+	dropped      uint64
+	droppedBytes uint64
+}
+
+// msgBuffer holds the messages from a node which may not yet be applied.  Each
+// kind of message is limited to Config.BufferSize messages, and if set, to
+// Config.BufferSizeBytes bytes, beyond which the oldest message of that kind is
+// dropped.  Regardless of kind, messages are iterated in the order they arrived.
+type msgBuffer struct {
+	id         NodeID
+	myConfig   *Config
+	partitions [numMsgBufferKinds]*msgPartition
+	arrivals   uint64
+}
+
+func newMsgBuffer(id NodeID, myConfig *Config) *msgBuffer {
+	mb := &msgBuffer{
+		id:       id,
+		myConfig: myConfig,
+	}
+
+	for i := range mb.partitions {
+		mb.partitions[i] = &msgPartition{
+			msgs: list.New(),
+		}
+	}
+
+	return mb
+}
+
+func (mb *msgBuffer) push(msg *pb.Msg) {
+	kind := msgBufferKindOf(msg)
+	partition := mb.partitions[kind]
+
+	bm := &bufferedMsg{
+		arrival: mb.arrivals,
+		size:    proto.Size(msg),
+		msg:     msg,
+	}
+	mb.arrivals++
+
+	partition.msgs.PushBack(bm)
+	partition.size += bm.size
+
+	for partition.msgs.Len() > mb.myConfig.BufferSize || (mb.myConfig.BufferSizeBytes > 0 && partition.size > mb.myConfig.BufferSizeBytes) {
+		oldest := partition.msgs.Remove(partition.msgs.Front()).(*bufferedMsg)
+		partition.size -= oldest.size
+		partition.dropped++
+		partition.droppedBytes += uint64(oldest.size)
+		mb.myConfig.Logger.Warn("dropping buffered message, buffer is full", append(logBasics(mb.id, oldest.msg), zap.Stringer("BufferKind", kind))...)
+	}
+}
+
+// Len returns the number of messages buffered, of every kind.
+func (mb *msgBuffer) Len() int {
+	total := 0
+	for _, partition := range mb.partitions {
+		total += partition.msgs.Len()
+	}
+	return total
+}
+
+func (mb *msgBuffer) iterator() *msgBufferIterator {
+	mbi := &msgBufferIterator{
+		msgBuffer: mb,
+	}
+
+	for i, partition := range mb.partitions {
+		mbi.heads[i] = partition.msgs.Front()
+	}
+
+	return mbi
+}
+
+func (mb *msgBuffer) status() []NodeBufferStatus {
+	statuses := make([]NodeBufferStatus, len(mb.partitions))
+	for i, partition := range mb.partitions {
+		statuses[i] = NodeBufferStatus{
+			Kind:         msgBufferKind(i).String(),
+			Msgs:         partition.msgs.Len(),
+			Bytes:        partition.size,
+			Dropped:      partition.dropped,
+			DroppedBytes: partition.droppedBytes,
+		}
+	}
+
+	return statuses
+}
+
+// msgBufferIterator walks the partitions of a msgBuffer together, returning
+// the buffered messages in the order they arrived.
+type msgBufferIterator struct {
+	msgBuffer *msgBuffer
+	heads     [numMsgBufferKinds]*list.Element
+	lastKind  msgBufferKind
+	last      *list.Element
+}
+
+func (mbi *msgBufferIterator) next() *pb.Msg {
+	var earliest *list.Element
+	var earliestKind msgBufferKind
+	for i, head := range mbi.heads {
+		if head == nil {
+			continue
+		}
+
+		if earliest == nil || head.Value.(*bufferedMsg).arrival < earliest.Value.(*bufferedMsg).arrival {
+			earliest = head
+			earliestKind = msgBufferKind(i)
+		}
+	}
+
+	mbi.last = earliest
+	mbi.lastKind = earliestKind
+	if earliest == nil {
+		return nil
+	}
+
+	mbi.heads[earliestKind] = earliest.Next()
+	return earliest.Value.(*bufferedMsg).msg
+}
+
+// remove removes the message most recently returned by next from the buffer.
+func (mbi *msgBufferIterator) remove() {
+	partition := mbi.msgBuffer.partitions[mbi.lastKind]
+	bm := partition.msgs.Remove(mbi.last).(*bufferedMsg)
+	partition.size -= bm.size
+	mbi.last = nil
+}
@@ -57,6 +57,10 @@ func preProcess(outerMsg *pb.Msg) error {
 		if innerMsg.ForwardBatch == nil {
 			return errors.Errorf("message of type ForwardBatch, but forward_batch field is nil")
 		}
+	case *pb.Msg_FetchSequenceState:
+		if innerMsg.FetchSequenceState == nil {
+			return errors.Errorf("message of type FetchSequenceState, but fetch_sequence_state field is nil")
+		}
 	case *pb.Msg_EpochChange:
 		if innerMsg.EpochChange == nil {
 			return errors.Errorf("message of type EpochChange, but epoch_change field is nil")
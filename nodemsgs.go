@@ -7,8 +7,6 @@ SPDX-License-Identifier: Apache-2.0
 package mirbft
 
 import (
-	"container/list"
-
 	pb "github.com/IBM/mirbft/mirbftpb"
 )
 
@@ -72,7 +70,7 @@ const (
 type nodeMsgs struct {
 	id             NodeID
 	oddities       *oddities
-	buffer         *list.List
+	buffer         *msgBuffer
 	epochMsgs      *epochMsgs
 	myConfig       *Config
 	networkConfig  *pb.NetworkConfig
@@ -89,25 +87,37 @@ type epochMsgs struct {
 	// next maintains the info about the next expected messages for
 	// a particular bucket.
 	next map[BucketID]*nextMsg
+
+	// received maintains the kinds of messages received for each sequence,
+	// when messages may be delivered out of order.
+	received map[uint64]receivedMsgs
 }
 
+// receivedMsgs is a bit set of the kinds of messages received from a node
+// for a sequence.
+type receivedMsgs uint8
+
+const (
+	receivedPrepare receivedMsgs = 1 << iota // Note Prepare is Preprepare if the node is the leader
+	receivedCommit
+	receivedCheckpoint
+)
+
 type nextMsg struct {
 	leader  bool
 	prepare uint64 // Note Prepare is Preprepare if Leader is true
 	commit  uint64
 }
 
-func newNodeMsgs(nodeID NodeID, networkConfig *pb.NetworkConfig, myConfig *Config, clientWindows *clientWindows, oddities *oddities) *nodeMsgs {
+func newNodeMsgs(nodeID NodeID, lowWatermark uint64, networkConfig *pb.NetworkConfig, myConfig *Config, clientWindows *clientWindows, oddities *oddities) *nodeMsgs {
 
 	return &nodeMsgs{
 		id:       nodeID,
 		oddities: oddities,
 
-		// nextCheckpoint: epochConfig.lowWatermark + epochConfig.checkpointInterval,
-		// XXX we should initialize this properly, sort of like the above
-		nextCheckpoint: uint64(networkConfig.CheckpointInterval),
+		nextCheckpoint: lowWatermark + uint64(networkConfig.CheckpointInterval),
 		clientWindows:  clientWindows,
-		buffer:         list.New(),
+		buffer:         newMsgBuffer(nodeID, myConfig),
 		myConfig:       myConfig,
 		networkConfig:  networkConfig,
 	}
@@ -121,15 +131,20 @@ func (n *nodeMsgs) setActiveEpoch(epoch *epoch) {
 	n.epochMsgs = newEpochMsgs(n.id, n.clientWindows, epoch, n.myConfig)
 }
 
+// moveLowWatermark skips this node's checkpoint messages up to and including
+// the given sequence number, which has been reached via state transfer.
+func (n *nodeMsgs) moveLowWatermark(seqNo uint64) {
+	if n.nextCheckpoint > seqNo {
+		return
+	}
+	n.nextCheckpoint = seqNo + uint64(n.networkConfig.CheckpointInterval)
+}
+
 // ingest the message for management by the nodeMsgs.  This message
 // may immediately become available to read from next(), or it may be enqueued
 // for future consumption
 func (n *nodeMsgs) ingest(outerMsg *pb.Msg) {
-	n.buffer.PushBack(outerMsg)
-	if n.buffer.Len() > n.myConfig.BufferSize {
-		e := n.buffer.Front()
-		n.buffer.Remove(e)
-	}
+	n.buffer.push(outerMsg)
 }
 
 func (n *nodeMsgs) process(outerMsg *pb.Msg) applyable {
@@ -141,6 +156,8 @@ func (n *nodeMsgs) process(outerMsg *pb.Msg) applyable {
 		epoch = innerMsg.Prepare.Epoch
 	case *pb.Msg_Commit:
 		epoch = innerMsg.Commit.Epoch
+	case *pb.Msg_FetchSequenceState:
+		epoch = innerMsg.FetchSequenceState.Epoch
 	case *pb.Msg_Suspect:
 		return current // TODO, at least detect past
 	case *pb.Msg_Checkpoint:
@@ -188,7 +205,7 @@ func (n *nodeMsgs) process(outerMsg *pb.Msg) applyable {
 	case *pb.Msg_EpochChangeAck:
 		return current // TODO, decide if this is actually current
 	case *pb.Msg_NewEpoch:
-		if innerMsg.NewEpoch.Config.Number%uint64(len(n.networkConfig.Nodes)) != uint64(n.id) {
+		if n.networkConfig.Nodes[innerMsg.NewEpoch.Config.Number%uint64(len(n.networkConfig.Nodes))] != uint64(n.id) {
 			return invalid
 		}
 		return current // TODO, decide if this is actually current
@@ -197,10 +214,8 @@ func (n *nodeMsgs) process(outerMsg *pb.Msg) applyable {
 	case *pb.Msg_NewEpochReady:
 		return current // TODO, decide if this is actually current
 	default:
-		n.oddities.invalidMessage(n.id, outerMsg)
-		// TODO don't panic here, just return, left here for dev
-		// as only a byzantine node with custom protos gets us here.
-		panic("unknown message type")
+		// Only a byzantine node with custom protos gets us here.
+		return invalid
 	}
 
 	if n.epochMsgs == nil {
@@ -220,46 +235,75 @@ func (n *nodeMsgs) process(outerMsg *pb.Msg) applyable {
 }
 
 func (n *nodeMsgs) next() *pb.Msg {
-	e := n.buffer.Front()
-	if e == nil {
-		return nil
-	}
-
-	for e != nil {
-		msg := e.Value.(*pb.Msg)
+	mbi := n.buffer.iterator()
+	for msg := mbi.next(); msg != nil; msg = mbi.next() {
 		switch n.process(msg) {
 		case past:
 			n.oddities.alreadyProcessed(n.id, msg)
-			x := e
-			e = e.Next() // get next before removing current
-			n.buffer.Remove(x)
+			mbi.remove()
+		case invalid:
+			n.oddities.invalidMessage(n.id, msg)
+			mbi.remove()
 		case current:
-			n.buffer.Remove(e)
+			mbi.remove()
 			return msg
 		case future:
 			// TODO, this is too aggressive, but useful for debugging
 			n.myConfig.Logger.Debug("deferring apply as it's from the future", logBasics(n.id, msg)...)
-			e = e.Next()
 		}
 	}
 
 	return nil
 }
 
+// lowestAwaitedSeqNo returns the lowest sequence for which a Preprepare,
+// Prepare, or Commit from this node must be applied before its later messages
+// may be.  When messages may be delivered out of order, there is none.
+func (n *nodeMsgs) lowestAwaitedSeqNo() (uint64, bool) {
+	if n.epochMsgs == nil || n.myConfig.OutOfOrderDelivery {
+		return 0, false
+	}
+
+	n.epochMsgs.skipBelowWatermarks()
+
+	var lowest uint64
+	found := false
+	for bucket, next := range n.epochMsgs.next {
+		column := next.commit
+		if next.prepare < column {
+			column = next.prepare
+		}
+
+		seqNo := n.epochMsgs.epochConfig.colBucketToSeq(column, bucket)
+		if !found || seqNo < lowest {
+			lowest = seqNo
+			found = true
+		}
+	}
+
+	return lowest, found
+}
+
 func (n *nodeMsgs) processCheckpoint(msg *pb.Checkpoint) applyable {
+	if n.myConfig.OutOfOrderDelivery && n.epochMsgs != nil {
+		result := n.epochMsgs.processOutOfOrder(msg.SeqNo, receivedCheckpoint)
+		if result == current && msg.SeqNo >= n.nextCheckpoint {
+			n.nextCheckpoint = msg.SeqNo + uint64(n.networkConfig.CheckpointInterval)
+		}
+		return result
+	}
+
 	switch {
 	case n.nextCheckpoint > msg.SeqNo:
 		return past
+	case n.epochMsgs == nil:
+		return future
 	case n.nextCheckpoint == msg.SeqNo:
-		if n.epochMsgs == nil {
-			return future
-		}
-		for _, next := range n.epochMsgs.next {
-			if next.commit < n.epochMsgs.epochConfig.seqToColumn(msg.SeqNo) {
-				return future
-			}
-		}
-
+		n.nextCheckpoint = msg.SeqNo + uint64(n.networkConfig.CheckpointInterval)
+		return current
+	case len(n.epochMsgs.epoch.sequences) > 0 && msg.SeqNo <= n.epochMsgs.epoch.highWatermark():
+		// An earlier checkpoint from this node may have been lost, but each
+		// checkpoint stands alone, so there is no need to wait for it.
 		n.nextCheckpoint = msg.SeqNo + uint64(n.networkConfig.CheckpointInterval)
 		return current
 	default:
@@ -296,10 +340,38 @@ func newEpochMsgs(nodeID NodeID, clientWindows *clientWindows, epoch *epoch, myC
 		epochConfig:   epoch.config,
 		epoch:         epoch,
 		next:          next,
+		received:      map[uint64]receivedMsgs{},
+	}
+}
+
+// skipBelowWatermarks advances the next expected messages past the sequences
+// which have fallen below the watermarks, as once a checkpoint is stable, any
+// lost messages for the sequences it covers are no longer needed.
+func (n *epochMsgs) skipBelowWatermarks() {
+	if len(n.epoch.sequences) == 0 || n.epoch.lowWatermark() < n.epochConfig.initialSequence {
+		return
+	}
+
+	lowWatermark := n.epoch.lowWatermark()
+	for bucket, next := range n.next {
+		column := n.epochConfig.seqToColumn(lowWatermark)
+		if n.epochConfig.colBucketToSeq(column, bucket) < lowWatermark {
+			column++
+		}
+
+		if next.prepare < column {
+			next.prepare = column
+		}
+
+		if next.commit < column {
+			next.commit = column
+		}
 	}
 }
 
 func (n *epochMsgs) process(outerMsg *pb.Msg) applyable {
+	n.skipBelowWatermarks()
+
 	switch innerMsg := outerMsg.Type.(type) {
 	case *pb.Msg_Preprepare:
 		return n.processPreprepare(innerMsg.Preprepare)
@@ -309,6 +381,8 @@ func (n *epochMsgs) process(outerMsg *pb.Msg) applyable {
 		return n.processCommit(innerMsg.Commit)
 	case *pb.Msg_ForwardRequest:
 		return current
+	case *pb.Msg_FetchSequenceState:
+		return current
 	case *pb.Msg_Suspect:
 		// TODO, do we care about duplicates?
 		return current
@@ -321,11 +395,19 @@ func (n *epochMsgs) process(outerMsg *pb.Msg) applyable {
 }
 
 func (n *epochMsgs) processPreprepare(msg *pb.Preprepare) applyable {
-	next, ok := n.next[n.epochConfig.seqToBucket(msg.SeqNo)]
+	bucket := n.epochConfig.seqToBucket(msg.SeqNo)
+	next, ok := n.next[bucket]
 	if !ok {
 		return invalid
 	}
 
+	for _, batchEntry := range msg.Batch {
+		if clientReqToBucket(batchEntry.ClientId, batchEntry.ReqNo, len(n.epochConfig.buckets)) != bucket {
+			// The leader may only propose requests from its own bucket
+			return invalid
+		}
+	}
+
 	if msg.SeqNo > n.epoch.highWatermark() {
 		return future
 	}
@@ -350,6 +432,18 @@ func (n *epochMsgs) processPreprepare(msg *pb.Preprepare) applyable {
 			// all requests arrive, it does not handle byzantine behavior.
 			return future
 		}
+
+		if _, ok := request.digests[string(batchEntry.Digest)]; !ok && n.myConfig.OutOfOrderDelivery {
+			// The leader's ack for this request may yet be in flight
+			return future
+		}
+	}
+
+	if n.myConfig.OutOfOrderDelivery {
+		if !next.leader {
+			return invalid
+		}
+		return n.processOutOfOrder(msg.SeqNo, receivedPrepare)
 	}
 
 	switch {
@@ -375,6 +469,13 @@ func (n *epochMsgs) processPrepare(msg *pb.Prepare) applyable {
 		return future
 	}
 
+	if n.myConfig.OutOfOrderDelivery {
+		if next.leader {
+			return invalid
+		}
+		return n.processOutOfOrder(msg.SeqNo, receivedPrepare)
+	}
+
 	switch {
 	case next.leader:
 		return invalid
@@ -398,6 +499,10 @@ func (n *epochMsgs) processCommit(msg *pb.Commit) applyable {
 		return future
 	}
 
+	if n.myConfig.OutOfOrderDelivery {
+		return n.processOutOfOrder(msg.SeqNo, receivedCommit)
+	}
+
 	switch {
 	case next.commit > n.epochConfig.seqToColumn(msg.SeqNo):
 		return past
@@ -409,11 +514,43 @@ func (n *epochMsgs) processCommit(msg *pb.Commit) applyable {
 	}
 }
 
+// processOutOfOrder accepts a message for any sequence within the watermarks,
+// so long as no message of the same kind has been received from this node for
+// that sequence.
+func (n *epochMsgs) processOutOfOrder(seqNo uint64, kind receivedMsgs) applyable {
+	lowWatermark := n.epoch.lowWatermark()
+
+	switch {
+	case seqNo < lowWatermark:
+		return past
+	case seqNo > n.epoch.highWatermark():
+		return future
+	}
+
+	if len(n.received) > len(n.epoch.sequences) {
+		// Some of the sequences have fallen below the watermarks
+		for receivedSeqNo := range n.received {
+			if receivedSeqNo < lowWatermark {
+				delete(n.received, receivedSeqNo)
+			}
+		}
+	}
+
+	received := n.received[seqNo]
+	if received&kind != 0 {
+		return past
+	}
+
+	n.received[seqNo] = received | kind
+	return current
+}
+
 func (n *nodeMsgs) status() *NodeStatus {
 	if n.epochMsgs == nil {
 		return &NodeStatus{
 			ID:             uint64(n.id),
 			LastCheckpoint: uint64(n.nextCheckpoint) - uint64(n.networkConfig.CheckpointInterval),
+			BufferStatuses: n.buffer.status(),
 		}
 	}
 
@@ -431,5 +568,6 @@ func (n *nodeMsgs) status() *NodeStatus {
 	return &NodeStatus{
 		ID:             uint64(n.id),
 		BucketStatuses: bucketStatuses,
+		BufferStatuses: n.buffer.status(),
 	}
 }
@@ -56,7 +56,7 @@ var _ = Describe("NodeMsg", func() {
 	})
 
 	JustBeforeEach(func() {
-		nodeMsgs = newNodeMsgs(nodeID, networkConfig, myConfig, clientWindows, o)
+		nodeMsgs = newNodeMsgs(nodeID, 0, networkConfig, myConfig, clientWindows, o)
 		Expect(nodeMsgs).NotTo(BeNil())
 	})
 
@@ -73,6 +73,14 @@ var _ = Describe("NodeMsg", func() {
 			Expect(nodeMsgs.buffer.Len()).To(BeZero())
 		})
 
+		It("discards invalid messages", func() {
+			nodeMsgs.ingest(&pb.Msg{})
+			nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Suspect{Suspect: &pb.Suspect{Epoch: 5}}})
+			Expect(nodeMsgs.next().Type).To(BeAssignableToTypeOf(&pb.Msg_Suspect{}))
+			Expect(nodeMsgs.buffer.Len()).To(BeZero())
+			Expect(o.nodes[nodeID].invalid).To(Equal(uint64(1)))
+		})
+
 		It("returns at first current message", func() {
 			nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Prepare{Prepare: &pb.Prepare{Epoch: 4}}})
 			nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Suspect{Suspect: &pb.Suspect{Epoch: 5}}})
@@ -94,13 +102,47 @@ var _ = Describe("NodeMsg", func() {
 			})
 
 			It("drops oldest msg", func() {
-				nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Preprepare{}})
+				nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Preprepare{Preprepare: &pb.Preprepare{Epoch: 5}}})
 				nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Prepare{Prepare: &pb.Prepare{Epoch: 5}}})
 				Expect(nodeMsgs.buffer.Len()).To(Equal(1))
 				nodeMsgs.setActiveEpoch(&epoch{config: &epochConfig{number: 6}})
 				Expect(nodeMsgs.next()).To(BeNil())
 				Expect(nodeMsgs.buffer.Len()).To(Equal(0))
 			})
+
+			It("does not drop messages of other kinds", func() {
+				nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Suspect{Suspect: &pb.Suspect{Epoch: 6}}})
+				nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Checkpoint{Checkpoint: &pb.Checkpoint{SeqNo: 4}}})
+				for i := uint64(1); i <= 3; i++ {
+					nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Prepare{Prepare: &pb.Prepare{SeqNo: i, Epoch: 6}}})
+				}
+				Expect(nodeMsgs.buffer.Len()).To(Equal(3))
+
+				status := nodeMsgs.status()
+				Expect(status.BufferStatuses).To(HaveLen(4))
+				Expect(status.BufferStatuses[epochBufferKind].Dropped).To(Equal(uint64(2)))
+				Expect(status.BufferStatuses[checkpointBufferKind].Msgs).To(Equal(1))
+				Expect(status.BufferStatuses[checkpointBufferKind].Dropped).To(BeZero())
+				Expect(status.BufferStatuses[epochChangeBufferKind].Msgs).To(Equal(1))
+				Expect(status.BufferStatuses[epochChangeBufferKind].Dropped).To(BeZero())
+			})
+		})
+
+		When("the buffer is limited in bytes", func() {
+			BeforeEach(func() {
+				myConfig.BufferSizeBytes = 20
+			})
+
+			It("drops the oldest messages until the rest fit", func() {
+				nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Prepare{Prepare: &pb.Prepare{SeqNo: 1, Epoch: 6, Digest: []byte("digest")}}})
+				nodeMsgs.ingest(&pb.Msg{Type: &pb.Msg_Prepare{Prepare: &pb.Prepare{SeqNo: 2, Epoch: 6, Digest: []byte("digest")}}})
+				Expect(nodeMsgs.buffer.Len()).To(Equal(1))
+
+				status := nodeMsgs.status()
+				Expect(status.BufferStatuses[epochBufferKind].Bytes).To(BeNumerically("<=", 20))
+				Expect(status.BufferStatuses[epochBufferKind].Dropped).To(Equal(uint64(1)))
+				Expect(status.BufferStatuses[epochBufferKind].DroppedBytes).To(Equal(uint64(status.BufferStatuses[epochBufferKind].Bytes)))
+			})
 		})
 	})
 
@@ -110,5 +152,127 @@ var _ = Describe("NodeMsg", func() {
 })
 
 var _ = Describe("EpochMsgs", func() {
+	var (
+		cws       *clientWindows
+		myConfig  *Config
+		epochMsgs *epochMsgs
+	)
+
+	BeforeEach(func() {
+		networkConfig := &pb.NetworkConfig{
+			CheckpointInterval: 2,
+			F:                  0,
+			Nodes:              []uint64{0},
+			NumberOfBuckets:    4,
+			MaxEpochLength:     10,
+			ClientWindowWidth:  100,
+		}
+
+		cws = &clientWindows{
+			windows:       map[string]*clientWindow{},
+			networkConfig: networkConfig,
+			myConfig:      &Config{},
+		}
+		cw := cws.newWindow(1)
+		cws.insert([]byte("client"), cw)
+		for reqNo := uint64(1); reqNo <= 100; reqNo++ {
+			cw.allocate(&pb.Request{ClientId: []byte("client"), ReqNo: reqNo}, uint64ToBytes(reqNo))
+		}
 
+		sequences := make([]*sequence, 8)
+		for i := range sequences {
+			sequences[i] = &sequence{seqNo: uint64(i + 1)}
+		}
+
+		myConfig = &Config{}
+
+		epochMsgs = newEpochMsgs(0, cws, &epoch{
+			config: &epochConfig{
+				number:          1,
+				initialSequence: 1,
+				networkConfig:   networkConfig,
+				leaders:         []uint64{0},
+				buckets:         map[BucketID]NodeID{0: 0, 1: 0, 2: 0, 3: 0},
+			},
+			sequences: sequences,
+		}, myConfig)
+	})
+
+	reqNoInBucket := func(inBucket bool) uint64 {
+		for reqNo := uint64(1); reqNo <= 100; reqNo++ {
+			if (clientReqToBucket([]byte("client"), reqNo, 4) == 0) == inBucket {
+				return reqNo
+			}
+		}
+		panic("no such request")
+	}
+
+	It("assigns the requests of a client across the buckets", func() {
+		buckets := map[BucketID]struct{}{}
+		for reqNo := uint64(1); reqNo <= 100; reqNo++ {
+			buckets[clientReqToBucket([]byte("client"), reqNo, 4)] = struct{}{}
+		}
+		Expect(buckets).To(HaveLen(4))
+	})
+
+	It("accepts preprepares containing only requests from the bucket", func() {
+		reqNo := reqNoInBucket(true)
+		Expect(epochMsgs.processPreprepare(&pb.Preprepare{
+			SeqNo: 1,
+			Epoch: 1,
+			Batch: []*pb.RequestAck{
+				{ClientId: []byte("client"), ReqNo: reqNo, Digest: uint64ToBytes(reqNo)},
+			},
+		})).To(Equal(current))
+	})
+
+	It("rejects preprepares containing requests from other buckets", func() {
+		reqNo := reqNoInBucket(false)
+		Expect(epochMsgs.processPreprepare(&pb.Preprepare{
+			SeqNo: 1,
+			Epoch: 1,
+			Batch: []*pb.RequestAck{
+				{ClientId: []byte("client"), ReqNo: reqNo, Digest: uint64ToBytes(reqNo)},
+			},
+		})).To(Equal(invalid))
+	})
+
+	When("messages may be delivered out of order", func() {
+		BeforeEach(func() {
+			myConfig.OutOfOrderDelivery = true
+		})
+
+		It("accepts commits for any sequence within the watermarks", func() {
+			Expect(epochMsgs.processCommit(&pb.Commit{SeqNo: 7, Epoch: 1})).To(Equal(current))
+			Expect(epochMsgs.processCommit(&pb.Commit{SeqNo: 3, Epoch: 1})).To(Equal(current))
+			Expect(epochMsgs.processCommit(&pb.Commit{SeqNo: 9, Epoch: 1})).To(Equal(future))
+		})
+
+		It("discards duplicate commits", func() {
+			Expect(epochMsgs.processCommit(&pb.Commit{SeqNo: 5, Epoch: 1})).To(Equal(current))
+			Expect(epochMsgs.processCommit(&pb.Commit{SeqNo: 5, Epoch: 1})).To(Equal(past))
+		})
+
+		It("accepts preprepares ahead of the next expected sequence", func() {
+			reqNo := reqNoInBucket(true)
+			Expect(epochMsgs.processPreprepare(&pb.Preprepare{
+				SeqNo: 5,
+				Epoch: 1,
+				Batch: []*pb.RequestAck{
+					{ClientId: []byte("client"), ReqNo: reqNo, Digest: uint64ToBytes(reqNo)},
+				},
+			})).To(Equal(current))
+		})
+
+		It("defers preprepares whose requests have not been acknowledged", func() {
+			reqNo := reqNoInBucket(true)
+			Expect(epochMsgs.processPreprepare(&pb.Preprepare{
+				SeqNo: 5,
+				Epoch: 1,
+				Batch: []*pb.RequestAck{
+					{ClientId: []byte("client"), ReqNo: reqNo, Digest: []byte("unknown")},
+				},
+			})).To(Equal(future))
+		})
+	})
 })
@@ -60,6 +60,13 @@ func logBasics(source NodeID, msg *pb.Msg) []zap.Field {
 			zap.String(MsgTypeLog, "checkpoint"),
 			zap.Uint64(SeqNoLog, msg.SeqNo),
 		)
+	case *pb.Msg_FetchSequenceState:
+		msg := innerMsg.FetchSequenceState
+		fields = append(fields,
+			zap.String(MsgTypeLog, "fetchsequencestate"),
+			zap.Uint64(SeqNoLog, msg.SeqNo),
+			zap.Uint64(EpochLog, msg.Epoch),
+		)
 	case *pb.Msg_ForwardRequest:
 		msg := innerMsg.ForwardRequest
 		fields = append(fields,
@@ -80,13 +87,17 @@ func logBasics(source NodeID, msg *pb.Msg) []zap.Field {
 // or detrimental to the state machine, but which may represent
 // byzantine behavior, misconfiguration, or bugs.
 type oddities struct {
-	logger Logger
-	nodes  map[NodeID]*oddity
+	logger   Logger
+	nodes    map[NodeID]*oddity
+	diverged uint64
+	lagging  uint64
 }
 
 type oddity struct {
 	invalid          uint64
 	alreadyProcessed uint64
+	rejected         uint64
+	byzantine        uint64
 	// aboveWatermarks uint64
 	// belowWatermarks uint64
 	// wrongEpoch      uint64
@@ -127,3 +138,50 @@ func (o *oddities) invalidMessage(source NodeID, msg *pb.Msg) {
 	o.logger.Error("invalid message", logBasics(source, msg)...)
 	o.getNode(source).invalid++
 }
+
+func (o *oddities) rejectedMessage(source NodeID, msg *pb.Msg, err error) {
+	o.logger.Warn("rejected message", append(logBasics(source, msg), zap.Error(err))...)
+	o.getNode(source).rejected++
+}
+
+// divergedCheckpoint records that my value for a checkpoint disagrees with the
+// value an intersection quorum of the network agrees upon.  This indicates
+// either a fault of our own, or a violation of the byzantine assumptions.
+func (o *oddities) divergedCheckpoint(seqNo uint64, myValue, networkValue []byte) {
+	o.logger.Error("checkpoint diverged from the network",
+		zap.Uint64(SeqNoLog, seqNo),
+		zap.Binary("MyValue", myValue),
+		zap.Binary("NetworkValue", networkValue),
+	)
+	o.diverged++
+}
+
+// clientWindowsLag records that a batch the network agreed upon contains a
+// request which our client windows do not cover.  This indicates that our
+// client windows lag behind those of the network.
+func (o *oddities) clientWindowsLag(seqNo uint64, requestAck *pb.RequestAck, err error) {
+	o.logger.Warn("client windows lag the network",
+		zap.Uint64(SeqNoLog, seqNo),
+		zap.Binary("ClientID", requestAck.ClientId),
+		zap.Uint64(ReqNoLog, requestAck.ReqNo),
+		zap.Error(err),
+	)
+	o.lagging++
+}
+
+// evidence records a message which only a byzantine node could have sent,
+// and returns the actions to report it to the user.
+func (o *oddities) evidence(source NodeID, msg *pb.Msg, reason string) *Actions {
+	o.logger.Error("byzantine message", append(logBasics(source, msg), zap.String("Reason", reason))...)
+	o.getNode(source).byzantine++
+
+	return &Actions{
+		Evidence: []*Evidence{
+			{
+				Source: uint64(source),
+				Msg:    msg,
+				Reason: reason,
+			},
+		},
+	}
+}
@@ -12,10 +12,30 @@ import (
 
 	pb "github.com/IBM/mirbft/mirbftpb"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrCorruptStorage is the cause of the error returned when the entries
+	// loaded from Storage are malformed or inconsistent with one another.
+	ErrCorruptStorage = fmt.Errorf("corrupt storage")
+
+	// ErrStaleEntry is the cause of the error returned when an entry would
+	// regress an entry which has already been persisted.
+	ErrStaleEntry = fmt.Errorf("entry does not supersede the persisted entry")
+
+	// ErrNonContiguousCommit is the cause of the error with which the node
+	// stops if it would commit a sequence other than the one following the
+	// last committed sequence.
+	ErrNonContiguousCommit = fmt.Errorf("commit does not follow the last committed sequence")
 )
 
 //go:generate counterfeiter -o mock/storage.go -fake-name Storage . Storage
 
+// Storage is the interface through which previously persisted entries are
+// read back into the state machine at startup.  Entries must be returned in
+// the order they were supplied through Actions.Persisted, with io.EOF returned
+// for the first index beyond the last entry.
 type Storage interface {
 	Load(index uint64) (*pb.Persisted, error)
 }
@@ -26,10 +46,40 @@ type persisted struct {
 	checkpoints   map[uint64]*pb.Checkpoint        // Seq -> Checkpoint
 	lastCommitted uint64                           // Seq
 
+	lastEpochChange *pb.EpochChange // The highest epoch change we have sent
+	lastNEntry      *pb.NEntry      // The highest new epoch config we have echoed or readied
+
+	// err is set once a sequence fails to commit in order, after which the
+	// persisted state no longer reflects what the state machine committed,
+	// and the node must stop.
+	err error
+
+	// discarded is the number of entries which were discarded rather than
+	// persisted, as they would have regressed a persisted entry.
+	discarded uint64
+
 	networkConfig *pb.NetworkConfig
 	myConfig      *Config
 }
 
+func newPersisted(myConfig *Config) *persisted {
+	return &persisted{
+		pSet:        map[uint64]*pb.PEntry{},
+		qSet:        map[uint64]map[uint64]*pb.QEntry{},
+		checkpoints: map[uint64]*pb.Checkpoint{},
+		myConfig:    myConfig,
+	}
+}
+
+// load reads all entries from storage.  Once loaded, the lowest checkpoint
+// is assumed to be the last stable checkpoint, and the highest checkpoint is
+// assumed to be the last sequence the application has committed.  If the
+// lowest checkpoint reconfigured the network, its network config is in
+// effect.  As a sequence may only be preprepared or prepared within the
+// watermarks of a stable checkpoint, and a checkpoint is only persisted once
+// committed, any entry beyond the watermarks of the highest checkpoint
+// indicates the storage is inconsistent, and an error caused by
+// ErrCorruptStorage is returned.
 func (p *persisted) load(storage Storage) error {
 	var data *pb.Persisted
 	var err error
@@ -42,48 +92,126 @@ func (p *persisted) load(storage Storage) error {
 		}
 
 		if err != nil {
-			return errors.Errorf("failed to load persisted from Storage: %s", err)
+			return errors.WithMessage(err, "failed to load persisted from Storage")
 		}
 
-		p.add(data)
+		if err := p.addEntry(data); err != nil {
+			return errors.Wrapf(ErrCorruptStorage, "entry %d is invalid: %s", index, err)
+		}
 		index++
 	}
 
-	p.checkpoints[0] = &pb.Checkpoint{
-		SeqNo: 0,
-		Value: []byte("TODO, get from state"),
+	var lowWatermark uint64
+	first := true
+	for seqNo := range p.checkpoints {
+		if seqNo > p.lastCommitted {
+			p.lastCommitted = seqNo
+		}
+
+		if first || seqNo < lowWatermark {
+			lowWatermark = seqNo
+			first = false
+		}
+	}
+
+	p.truncate(lowWatermark)
+
+	if cp, ok := p.checkpoints[lowWatermark]; ok && cp.NetworkConfig != nil {
+		// The lowest checkpoint is stable, so the network config it carries
+		// is in effect, even if we crashed before persisting it on its own.
+		p.networkConfig = cp.NetworkConfig
+	}
+
+	if len(p.checkpoints) == 0 || p.networkConfig == nil {
+		// Nothing to check against, the caller rejects such storage
+		return nil
+	}
+
+	highWatermark := p.lastCommitted + 3*uint64(p.networkConfig.CheckpointInterval)
+	for seqNo := range p.qSet {
+		if seqNo > highWatermark {
+			return errors.Wrapf(ErrCorruptStorage, "qentry for seq_no=%d is beyond the high watermark %d of the last checkpoint", seqNo, highWatermark)
+		}
+	}
+
+	for seqNo := range p.pSet {
+		if seqNo > highWatermark {
+			return errors.Wrapf(ErrCorruptStorage, "pentry for seq_no=%d is beyond the high watermark %d of the last checkpoint", seqNo, highWatermark)
+		}
+
+		if _, ok := p.qSet[seqNo]; !ok {
+			return errors.Wrapf(ErrCorruptStorage, "pentry for seq_no=%d has no corresponding qentry", seqNo)
+		}
 	}
 
 	return nil
 }
 
+// add persists the entry, returning the action to write it to storage.  The
+// state machine should never attempt to regress an entry, but should it, the
+// entry already persisted supersedes the new one, so the new one is discarded.
 func (p *persisted) add(persisted *pb.Persisted) *Actions {
+	if err := p.addEntry(persisted); err != nil {
+		p.myConfig.Logger.Error("discarding entry which may not be persisted", zap.Error(err))
+		p.discarded++
+		return &Actions{}
+	}
+
+	return &Actions{
+		Persisted: []*pb.Persisted{persisted},
+	}
+}
+
+func (p *persisted) addEntry(persisted *pb.Persisted) error {
 	switch d := persisted.Type.(type) {
 	case *pb.Persisted_Pentry:
-		p.addPEntry(d.Pentry)
+		if d.Pentry == nil {
+			return errors.Errorf("pentry is nil")
+		}
+		return p.addPEntry(d.Pentry)
 	case *pb.Persisted_Qentry:
+		if d.Qentry == nil {
+			return errors.Errorf("qentry is nil")
+		}
 		p.addQEntry(d.Qentry)
 	case *pb.Persisted_Checkpoint:
+		if d.Checkpoint == nil {
+			return errors.Errorf("checkpoint is nil")
+		}
 		p.addCheckpoint(d.Checkpoint)
+	case *pb.Persisted_NetworkConfig:
+		if d.NetworkConfig == nil {
+			return errors.Errorf("network config is nil")
+		}
+		p.networkConfig = d.NetworkConfig
+	case *pb.Persisted_Ecentry:
+		if d.Ecentry == nil || d.Ecentry.EpochChange == nil {
+			return errors.Errorf("ecentry is nil")
+		}
+		return p.addECEntry(d.Ecentry)
+	case *pb.Persisted_Nentry:
+		if d.Nentry == nil || d.Nentry.Config == nil {
+			return errors.Errorf("nentry is nil")
+		}
+		return p.addNEntry(d.Nentry)
 	default:
-		panic("unrecognized data type")
+		return errors.Errorf("unrecognized data type %T", persisted.Type)
 	}
 
-	return &Actions{
-		Persisted: []*pb.Persisted{persisted},
-	}
+	return nil
 }
 
-func (p *persisted) addPEntry(pEntry *pb.PEntry) {
+func (p *persisted) addPEntry(pEntry *pb.PEntry) error {
 	if p.pSet == nil {
 		p.pSet = map[uint64]*pb.PEntry{}
 	}
 
 	if oldEntry, ok := p.pSet[pEntry.SeqNo]; ok && oldEntry.Epoch >= pEntry.Epoch {
-		panic("dev sanity test, remove me")
+		return errors.Wrapf(ErrStaleEntry, "pentry for seq_no=%d from epoch %d, but already have epoch %d", pEntry.SeqNo, pEntry.Epoch, oldEntry.Epoch)
 	}
 
 	p.pSet[pEntry.SeqNo] = pEntry
+	return nil
 }
 
 func (p *persisted) addQEntry(qEntry *pb.QEntry) {
@@ -108,12 +236,45 @@ func (p *persisted) addCheckpoint(cp *pb.Checkpoint) {
 	p.checkpoints[cp.SeqNo] = cp
 }
 
-func (p *persisted) setLastCommitted(seqNo uint64) {
+func (p *persisted) addECEntry(ecEntry *pb.ECEntry) error {
+	if p.lastEpochChange != nil && p.lastEpochChange.NewEpoch >= ecEntry.EpochChange.NewEpoch {
+		return errors.Wrapf(ErrStaleEntry, "ecentry for epoch %d, but already have epoch %d", ecEntry.EpochChange.NewEpoch, p.lastEpochChange.NewEpoch)
+	}
+
+	p.lastEpochChange = ecEntry.EpochChange
+	return nil
+}
+
+func (p *persisted) addNEntry(nEntry *pb.NEntry) error {
+	if p.lastNEntry != nil {
+		switch {
+		case p.lastNEntry.Config.Number > nEntry.Config.Number:
+			return errors.Wrapf(ErrStaleEntry, "nentry for epoch %d, but already have epoch %d", nEntry.Config.Number, p.lastNEntry.Config.Number)
+		case p.lastNEntry.Config.Number == nEntry.Config.Number && (p.lastNEntry.Ready || !nEntry.Ready):
+			return errors.Wrapf(ErrStaleEntry, "nentry for epoch %d with ready=%t, but already have ready=%t", nEntry.Config.Number, nEntry.Ready, p.lastNEntry.Ready)
+		}
+	}
+
+	p.lastNEntry = nEntry
+	return nil
+}
+
+// setLastCommitted records that the sequence has committed.  Sequences must
+// commit in order, so this returns an error caused by ErrNonContiguousCommit
+// if the sequence does not follow the last committed sequence.  The error is
+// also retained, so that the node stops with it once the current event has
+// been processed.  The caller must not emit the commit.
+func (p *persisted) setLastCommitted(seqNo uint64) error {
 	if p.lastCommitted+1 != seqNo {
-		panic(fmt.Sprintf("dev sanity test, remove me: lastCommitted=%d >= seqNo=%d", p.lastCommitted, seqNo))
+		err := errors.Wrapf(ErrNonContiguousCommit, "cannot commit seq_no=%d after seq_no=%d", seqNo, p.lastCommitted)
+		if p.err == nil {
+			p.err = err
+		}
+		return err
 	}
 
 	p.lastCommitted = seqNo
+	return nil
 }
 
 func (p *persisted) truncate(lowWatermark uint64) {
@@ -137,14 +298,27 @@ func (p *persisted) truncate(lowWatermark uint64) {
 	}
 }
 
-func (p *persisted) constructEpochChange(newEpoch uint64, ct *checkpointTracker) *pb.EpochChange {
+// constructEpochChange builds our epoch change for the new epoch from the
+// persisted entries.  The last active config, if known, is reported so that the
+// leaders of the new epoch may be derived from it.
+func (p *persisted) constructEpochChange(newEpoch uint64, lastActiveConfig *epochConfig, ct *checkpointTracker) *pb.EpochChange {
 	epochChange := &pb.EpochChange{
 		NewEpoch: newEpoch,
 	}
 
+	if lastActiveConfig != nil {
+		epochChange.LastActiveConfig = lastActiveConfig.summary()
+	}
+
 	var highestStableCheckpoint *pb.Checkpoint
 	var checkpoints []*pb.Checkpoint
-	for seqNo, cp := range p.checkpoints {
+	for seqNo, persistedCP := range p.checkpoints {
+		// The network config is only persisted, it is not part of the
+		// checkpoint the network agrees upon.
+		cp := &pb.Checkpoint{
+			SeqNo: persistedCP.SeqNo,
+			Value: persistedCP.Value,
+		}
 		pcp := ct.checkpoint(seqNo)
 		if pcp.stable && (highestStableCheckpoint == nil || highestStableCheckpoint.SeqNo < seqNo) {
 			highestStableCheckpoint = cp
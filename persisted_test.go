@@ -0,0 +1,401 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/IBM/mirbft/mock"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("RestartNode", func() {
+	var (
+		doneC   chan struct{}
+		config  *mirbft.Config
+		storage *mock.Storage
+		entries []*pb.Persisted
+	)
+
+	BeforeEach(func() {
+		doneC = make(chan struct{})
+
+		logger, err := zap.NewDevelopment()
+		Expect(err).NotTo(HaveOccurred())
+
+		config = &mirbft.Config{
+			ID:     1,
+			Logger: logger,
+			BatchParameters: mirbft.BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}
+
+		entries = []*pb.Persisted{
+			{
+				Type: &pb.Persisted_NetworkConfig{
+					NetworkConfig: mirbft.StandardInitialNetworkConfig(4),
+				},
+			},
+			{
+				Type: &pb.Persisted_Checkpoint{
+					Checkpoint: &pb.Checkpoint{SeqNo: 20, Value: []byte("cp20")},
+				},
+			},
+			{
+				Type: &pb.Persisted_Checkpoint{
+					Checkpoint: &pb.Checkpoint{SeqNo: 40, Value: []byte("cp40")},
+				},
+			},
+		}
+
+		for seqNo := uint64(21); seqNo <= 45; seqNo++ {
+			digest := []byte(fmt.Sprintf("digest-%d", seqNo))
+			entries = append(entries,
+				&pb.Persisted{
+					Type: &pb.Persisted_Qentry{
+						Qentry: &pb.QEntry{
+							Epoch:  2,
+							SeqNo:  seqNo,
+							Digest: digest,
+							Requests: []*pb.ForwardRequest{
+								{
+									Request: &pb.Request{
+										ClientId: []byte("client"),
+										ReqNo:    seqNo,
+										Data:     []byte("data"),
+									},
+									Digest: []byte(fmt.Sprintf("request-%d", seqNo)),
+								},
+							},
+						},
+					},
+				},
+				&pb.Persisted{
+					Type: &pb.Persisted_Pentry{
+						Pentry: &pb.PEntry{
+							Epoch:  2,
+							SeqNo:  seqNo,
+							Digest: digest,
+						},
+					},
+				},
+			)
+		}
+
+		storage = &mock.Storage{}
+		storage.LoadStub = func(index uint64) (*pb.Persisted, error) {
+			if index >= uint64(len(entries)) {
+				return nil, io.EOF
+			}
+			return entries[index], nil
+		}
+	})
+
+	AfterEach(func() {
+		close(doneC)
+	})
+
+	It("reconstructs the state machine from storage", func() {
+		node, err := mirbft.RestartNode(config, doneC, storage)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Replicas).To(HaveLen(4))
+
+		status, err := node.Status(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(status.EpochChanger.LastActiveEpoch).To(Equal(uint64(2)))
+		Expect(status.EpochChanger.EpochTargets).To(HaveLen(1))
+		Expect(status.EpochChanger.EpochTargets[0].Number).To(Equal(uint64(3)))
+
+		Expect(status.Checkpoints).To(HaveLen(2))
+		Expect(status.Checkpoints[0].SeqNo).To(Equal(uint64(20)))
+		Expect(status.Checkpoints[1].SeqNo).To(Equal(uint64(40)))
+
+		Expect(status.ClientWindows).To(HaveLen(1))
+		Expect(status.ClientWindows[0].ClientID).To(Equal([]byte("client")))
+		Expect(status.ClientWindows[0].LowWatermark).To(Equal(uint64(41)))
+
+		for _, nodeStatus := range status.Nodes {
+			Expect(nodeStatus.LastCheckpoint).To(Equal(uint64(20)))
+		}
+	})
+
+	It("persists a new epoch change before broadcasting it", func() {
+		node, err := mirbft.RestartNode(config, doneC, storage)
+		Expect(err).NotTo(HaveOccurred())
+
+		var actions mirbft.Actions
+		Eventually(node.Ready()).Should(Receive(&actions))
+		Expect(actions.Persisted).To(HaveLen(1))
+		ecEntry := actions.Persisted[0].Type.(*pb.Persisted_Ecentry).Ecentry
+		Expect(ecEntry.EpochChange.NewEpoch).To(Equal(uint64(3)))
+		Expect(actions.Broadcast).To(BeEmpty())
+	})
+
+	When("the node crashed during an epoch change", func() {
+		var epochChange *pb.EpochChange
+
+		BeforeEach(func() {
+			epochChange = &pb.EpochChange{
+				NewEpoch: 5,
+				Checkpoints: []*pb.Checkpoint{
+					{SeqNo: 20, Value: []byte("cp20")},
+				},
+			}
+
+			entries = append(entries, &pb.Persisted{
+				Type: &pb.Persisted_Ecentry{
+					Ecentry: &pb.ECEntry{
+						EpochChange: epochChange,
+					},
+				},
+			})
+		})
+
+		It("resumes the epoch change with the same message", func() {
+			node, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, err := node.Status(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.EpochChanger.LastActiveEpoch).To(Equal(uint64(2)))
+			Expect(status.EpochChanger.EpochTargets).To(HaveLen(1))
+			Expect(status.EpochChanger.EpochTargets[0].Number).To(Equal(uint64(5)))
+
+			Expect(node.Tick()).To(Succeed())
+			var actions mirbft.Actions
+			Eventually(node.Ready()).Should(Receive(&actions))
+			Expect(actions.Persisted).To(BeEmpty())
+			Expect(actions.Broadcast).To(HaveLen(1))
+			Expect(actions.Broadcast[0].Type.(*pb.Msg_EpochChange).EpochChange).To(Equal(epochChange))
+		})
+
+		When("the node had already sent a ready for that epoch", func() {
+			BeforeEach(func() {
+				entries = append(entries, &pb.Persisted{
+					Type: &pb.Persisted_Nentry{
+						Nentry: &pb.NEntry{
+							Config: &pb.EpochConfig{
+								Number:             5,
+								StartingCheckpoint: &pb.Checkpoint{SeqNo: 20, Value: []byte("cp20")},
+								Leaders:            []uint64{0, 1, 2, 3},
+							},
+							Ready: true,
+						},
+					},
+				})
+			})
+
+			It("considers that epoch active and moves to the next", func() {
+				node, err := mirbft.RestartNode(config, doneC, storage)
+				Expect(err).NotTo(HaveOccurred())
+
+				status, err := node.Status(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.EpochChanger.LastActiveEpoch).To(Equal(uint64(5)))
+				Expect(status.EpochChanger.EpochTargets).To(HaveLen(1))
+				Expect(status.EpochChanger.EpochTargets[0].Number).To(Equal(uint64(6)))
+			})
+		})
+	})
+
+	When("the storage does not contain a network config", func() {
+		BeforeEach(func() {
+			entries = entries[1:]
+		})
+
+		It("returns an error", func() {
+			_, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(err).To(MatchError("failed to restart node: no network config in storage"))
+		})
+	})
+
+	When("the storage contains an entry which regresses an earlier one", func() {
+		BeforeEach(func() {
+			entries = append(entries, &pb.Persisted{
+				Type: &pb.Persisted_Pentry{
+					Pentry: &pb.PEntry{
+						Epoch:  1,
+						SeqNo:  45,
+						Digest: []byte("digest-45"),
+					},
+				},
+			})
+		})
+
+		It("returns a corrupt storage error", func() {
+			_, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(errors.Cause(err)).To(Equal(mirbft.ErrCorruptStorage))
+			Expect(err).To(MatchError(fmt.Sprintf("failed to restart node: entry %d is invalid: pentry for seq_no=45 from epoch 1, but already have epoch 2: entry does not supersede the persisted entry: corrupt storage", len(entries)-1)))
+		})
+	})
+
+	When("the storage retains the genesis checkpoint", func() {
+		BeforeEach(func() {
+			entries = append([]*pb.Persisted{
+				entries[0],
+				{
+					Type: &pb.Persisted_Checkpoint{
+						Checkpoint: &pb.Checkpoint{SeqNo: 0, Value: []byte("cp0")},
+					},
+				},
+			}, entries[1:]...)
+		})
+
+		It("considers the genesis checkpoint the last stable checkpoint", func() {
+			node, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, err := node.Status(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Checkpoints).To(HaveLen(3))
+			Expect(status.Checkpoints[0].SeqNo).To(Equal(uint64(0)))
+
+			for _, nodeStatus := range status.Nodes {
+				Expect(nodeStatus.LastCheckpoint).To(Equal(uint64(0)))
+			}
+		})
+	})
+
+	When("the node crashed after persisting a reconfiguring checkpoint", func() {
+		BeforeEach(func() {
+			entries[1] = &pb.Persisted{
+				Type: &pb.Persisted_Checkpoint{
+					Checkpoint: &pb.Checkpoint{
+						SeqNo:         20,
+						Value:         []byte("cp20"),
+						NetworkConfig: mirbft.StandardInitialNetworkConfig(5),
+					},
+				},
+			}
+		})
+
+		It("restarts with the network config of the checkpoint", func() {
+			node, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(node.Replicas).To(HaveLen(5))
+
+			status, err := node.Status(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Nodes).To(HaveLen(5))
+		})
+	})
+
+	When("the network config specifies the client window width", func() {
+		BeforeEach(func() {
+			networkConfig := mirbft.StandardInitialNetworkConfig(4)
+			networkConfig.ClientWindowWidth = 250
+			entries[0] = &pb.Persisted{
+				Type: &pb.Persisted_NetworkConfig{
+					NetworkConfig: networkConfig,
+				},
+			}
+		})
+
+		It("sizes the restored client windows accordingly", func() {
+			node, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(err).NotTo(HaveOccurred())
+
+			status, err := node.Status(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.ClientWindows).To(HaveLen(1))
+			Expect(status.ClientWindows[0].HighWatermark - status.ClientWindows[0].LowWatermark).To(Equal(uint64(249)))
+		})
+	})
+
+	When("the network config has no client window width", func() {
+		BeforeEach(func() {
+			networkConfig := mirbft.StandardInitialNetworkConfig(4)
+			networkConfig.ClientWindowWidth = 0
+			entries[0] = &pb.Persisted{
+				Type: &pb.Persisted_NetworkConfig{
+					NetworkConfig: networkConfig,
+				},
+			}
+		})
+
+		It("returns an error", func() {
+			_, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(err).To(MatchError("failed to restart node: client window width must be greater than zero"))
+		})
+	})
+
+	When("the storage contains a pentry beyond the watermarks of the last checkpoint", func() {
+		BeforeEach(func() {
+			entries = append(entries, &pb.Persisted{
+				Type: &pb.Persisted_Pentry{
+					Pentry: &pb.PEntry{
+						Epoch:  2,
+						SeqNo:  200,
+						Digest: []byte("digest-200"),
+					},
+				},
+			})
+		})
+
+		It("returns a corrupt storage error", func() {
+			_, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(errors.Cause(err)).To(Equal(mirbft.ErrCorruptStorage))
+			Expect(err).To(MatchError(ContainSubstring("pentry for seq_no=200 is beyond the high watermark")))
+		})
+	})
+
+	When("the storage contains a pentry without a qentry", func() {
+		BeforeEach(func() {
+			entries = append(entries, &pb.Persisted{
+				Type: &pb.Persisted_Pentry{
+					Pentry: &pb.PEntry{
+						Epoch:  2,
+						SeqNo:  46,
+						Digest: []byte("digest-46"),
+					},
+				},
+			})
+		})
+
+		It("returns a corrupt storage error", func() {
+			_, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(errors.Cause(err)).To(Equal(mirbft.ErrCorruptStorage))
+			Expect(err).To(MatchError("failed to restart node: pentry for seq_no=46 has no corresponding qentry: corrupt storage"))
+		})
+	})
+
+	When("the storage contains an entry of no known type", func() {
+		BeforeEach(func() {
+			entries = append(entries, &pb.Persisted{})
+		})
+
+		It("returns a corrupt storage error", func() {
+			_, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(errors.Cause(err)).To(Equal(mirbft.ErrCorruptStorage))
+		})
+	})
+
+	When("the storage returns an error", func() {
+		BeforeEach(func() {
+			storage.LoadStub = nil
+			storage.LoadReturns(nil, fmt.Errorf("disk on fire"))
+		})
+
+		It("returns an error", func() {
+			_, err := mirbft.RestartNode(config, doneC, storage)
+			Expect(err).To(MatchError("failed to restart node: failed to load persisted from Storage: disk on fire"))
+		})
+	})
+})
@@ -9,6 +9,7 @@ package mirbft
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 )
 
 func uint64ToBytes(value uint64) []byte {
@@ -17,6 +18,18 @@ func uint64ToBytes(value uint64) []byte {
 	return byteValue
 }
 
+// clientReqToBucket deterministically assigns a client request to a bucket
+// according to a hash of its client ID and request number.  This spreads the
+// requests of each client across the buckets, and allows every node to verify
+// that a leader only proposes requests from the buckets it leads.
+func clientReqToBucket(clientID []byte, reqNo uint64, numberOfBuckets int) BucketID {
+	h := fnv.New64a()
+	h.Write(uint64ToBytes(uint64(len(clientID))))
+	h.Write(clientID)
+	h.Write(uint64ToBytes(reqNo))
+	return BucketID(h.Sum64() % uint64(numberOfBuckets))
+}
+
 type proposer struct {
 	myConfig               *Config
 	clientWindowProcessors map[string]*clientWindowProcessor
@@ -31,10 +44,41 @@ type clientWindowProcessor struct {
 	clientWindow  *clientWindow
 }
 
+// proposalQuantumBytes is the number of bytes of requests, multiplied by the
+// client's weight, which each client may contribute to a bucket per round.
+const proposalQuantumBytes = 1024
+
 type proposalBucket struct {
-	queue     []*clientRequest
-	sizeBytes int
-	pending   [][]*clientRequest
+	queue       []*clientRequest
+	sizeBytes   int
+	ticksQueued int
+	pending     [][]*clientRequest
+
+	// Requests which are ready to propose, but not yet queued, are held per
+	// client and queued via deficit round robin, so that no client may starve
+	// the others.
+	ready    map[string]*readyQueue
+	active   []*readyQueue
+	cursor   int
+	credited bool
+}
+
+type readyQueue struct {
+	clientID string
+	requests []*clientRequest
+	deficit  int
+}
+
+// cut moves the queued requests, if any, into a pending batch.
+func (prb *proposalBucket) cut() {
+	if len(prb.queue) == 0 {
+		return
+	}
+
+	prb.pending = append(prb.pending, prb.queue)
+	prb.queue = nil
+	prb.sizeBytes = 0
+	prb.ticksQueued = 0
 }
 
 func newProposer(myConfig *Config, clientWindows *clientWindows, buckets map[BucketID]NodeID) *proposer {
@@ -43,7 +87,9 @@ func newProposer(myConfig *Config, clientWindows *clientWindows, buckets map[Buc
 		if nodeID != NodeID(myConfig.ID) {
 			continue
 		}
-		proposalBuckets[bucketID] = &proposalBucket{}
+		proposalBuckets[bucketID] = &proposalBucket{
+			ready: map[string]*readyQueue{},
+		}
 	}
 
 	clientWindowProcessors := map[string]*clientWindowProcessor{}
@@ -66,8 +112,6 @@ func newProposer(myConfig *Config, clientWindows *clientWindows, buckets map[Buc
 
 func (p *proposer) stepAllClientWindows() {
 	for _, clientID := range p.clientWindows.clients {
-		// TODO, this logic favors clients with lower IDs, we really should
-		// remember where we last left off to prevent starvation
 		p.stepClientWindow([]byte(clientID))
 	}
 }
@@ -96,9 +140,7 @@ func (p *proposer) stepClientWindow(clientID []byte) {
 
 		rwp.lastProcessed++
 
-		// TODO, maybe offset the bucket ID by something in the client ID so not all start in bucket 1?
-		// maybe some sort of client index?
-		bucket := BucketID(reqNo % uint64(p.totalBuckets))
+		bucket := clientReqToBucket(clientID, reqNo, p.totalBuckets)
 		proposalBucket, ok := p.proposalBuckets[bucket]
 		if !ok {
 			// I don't lead this bucket this epoch
@@ -110,15 +152,109 @@ func (p *proposer) stepClientWindow(clientID []byte) {
 			continue
 		}
 
-		proposalBucket.queue = append(proposalBucket.queue, request.strongRequest)
-		proposalBucket.sizeBytes += len(request.strongRequest.data.Data)
-		if proposalBucket.sizeBytes >= p.myConfig.BatchParameters.CutSizeBytes {
-			proposalBucket.pending = append(proposalBucket.pending, proposalBucket.queue)
-			proposalBucket.queue = nil
-			proposalBucket.sizeBytes = 0
+		rq, ok := proposalBucket.ready[string(clientID)]
+		if !ok {
+			rq = &readyQueue{
+				clientID: string(clientID),
+			}
+			proposalBucket.ready[string(clientID)] = rq
+			proposalBucket.active = append(proposalBucket.active, rq)
 		}
+		rq.requests = append(rq.requests, request.strongRequest)
 	}
 
+	for _, proposalBucket := range p.proposalBuckets {
+		p.fill(proposalBucket)
+	}
+}
+
+func (p *proposer) weight(clientID string) int {
+	weight, ok := p.myConfig.ClientWeights[clientID]
+	if !ok || weight < 1 {
+		return 1
+	}
+	return weight
+}
+
+// fill queues ready requests into the bucket, visiting the clients in deficit
+// round robin order, until a batch is pending or no requests remain.  Because
+// the clients' turns are only consumed as batches are proposed, a client
+// with many ready requests cannot push ahead of the others.
+func (p *proposer) fill(proposalBucket *proposalBucket) {
+	for len(proposalBucket.pending) == 0 && len(proposalBucket.active) > 0 {
+		if proposalBucket.cursor >= len(proposalBucket.active) {
+			proposalBucket.cursor = 0
+		}
+
+		rq := proposalBucket.active[proposalBucket.cursor]
+		if !proposalBucket.credited {
+			rq.deficit += p.weight(rq.clientID) * proposalQuantumBytes
+			proposalBucket.credited = true
+		}
+
+		for len(rq.requests) > 0 && len(proposalBucket.pending) == 0 {
+			request := rq.requests[0]
+			sizeBytes := len(request.data.Data)
+			if sizeBytes > rq.deficit {
+				break
+			}
+
+			rq.deficit -= sizeBytes
+			rq.requests = rq.requests[1:]
+			p.enqueue(proposalBucket, request)
+		}
+
+		if len(rq.requests) == 0 {
+			// An idle client may not accumulate a deficit
+			delete(proposalBucket.ready, rq.clientID)
+			proposalBucket.active = append(proposalBucket.active[:proposalBucket.cursor], proposalBucket.active[proposalBucket.cursor+1:]...)
+			proposalBucket.credited = false
+			continue
+		}
+
+		if len(proposalBucket.pending) > 0 {
+			// Resume this client's turn once the batch is proposed
+			break
+		}
+
+		proposalBucket.cursor++
+		proposalBucket.credited = false
+	}
+}
+
+func (p *proposer) enqueue(proposalBucket *proposalBucket, request *clientRequest) {
+	batchParameters := p.myConfig.BatchParameters
+	sizeBytes := len(request.data.Data)
+	if batchParameters.MaxSizeBytes > 0 && proposalBucket.sizeBytes+sizeBytes > batchParameters.MaxSizeBytes {
+		proposalBucket.cut()
+	}
+
+	proposalBucket.queue = append(proposalBucket.queue, request)
+	proposalBucket.sizeBytes += sizeBytes
+	if proposalBucket.sizeBytes >= batchParameters.CutSizeBytes ||
+		(batchParameters.CutSizeRequests > 0 && len(proposalBucket.queue) >= batchParameters.CutSizeRequests) {
+		proposalBucket.cut()
+	}
+}
+
+// tick ages the queue of every bucket, cutting those which have waited for
+// the batch timeout.  It returns whether any bucket has a pending batch.
+func (p *proposer) tick() bool {
+	timeoutTicks := p.myConfig.BatchParameters.BatchTimeoutTicks
+
+	hasPending := false
+	for _, proposalBucket := range p.proposalBuckets {
+		if len(proposalBucket.queue) > 0 {
+			proposalBucket.ticksQueued++
+			if timeoutTicks > 0 && proposalBucket.ticksQueued >= timeoutTicks {
+				proposalBucket.cut()
+			}
+		}
+
+		hasPending = hasPending || len(proposalBucket.pending) > 0
+	}
+
+	return hasPending
 }
 
 func (p *proposer) hasOutstanding(bucket BucketID) bool {
@@ -134,18 +270,15 @@ func (p *proposer) hasPending(bucket BucketID) bool {
 func (p *proposer) next(bucket BucketID) []*clientRequest {
 	proposalBucket := p.proposalBuckets[bucket]
 
-	if len(proposalBucket.pending) > 0 {
-		n := proposalBucket.pending[0]
-		proposalBucket.pending = proposalBucket.pending[1:]
-		return n
-	}
-
-	if len(proposalBucket.queue) > 0 {
-		n := proposalBucket.queue
-		proposalBucket.queue = nil
-		proposalBucket.sizeBytes = 0
-		return n
+	if len(proposalBucket.pending) == 0 {
+		if len(proposalBucket.queue) == 0 {
+			panic("called next when nothing outstanding")
+		}
+		proposalBucket.cut()
 	}
 
-	panic("called next when nothing outstanding")
+	n := proposalBucket.pending[0]
+	proposalBucket.pending = proposalBucket.pending[1:]
+	p.fill(proposalBucket)
+	return n
 }
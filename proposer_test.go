@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pb "github.com/IBM/mirbft/mirbftpb"
+)
+
+var _ = Describe("proposer", func() {
+	var (
+		myConfig *Config
+		cws      *clientWindows
+		p        *proposer
+	)
+
+	BeforeEach(func() {
+		myConfig = &Config{
+			ID: 0,
+		}
+
+		cws = &clientWindows{
+			windows: map[string]*clientWindow{},
+			networkConfig: &pb.NetworkConfig{
+				Nodes:             []uint64{0},
+				ClientWindowWidth: 100,
+			},
+			myConfig: myConfig,
+		}
+		cws.insert([]byte("client"), cws.newWindow(1))
+	})
+
+	JustBeforeEach(func() {
+		p = newProposer(myConfig, cws, map[BucketID]NodeID{0: 0})
+	})
+
+	readyClientRequest := func(clientID string, reqNo uint64, sizeBytes int) {
+		cw, ok := cws.clientWindow([]byte(clientID))
+		if !ok {
+			cw = cws.newWindow(1)
+			cws.insert([]byte(clientID), cw)
+		}
+		digest := uint64ToBytes(reqNo)
+		cw.allocate(&pb.Request{
+			ClientId: []byte(clientID),
+			ReqNo:    reqNo,
+			Data:     make([]byte, sizeBytes),
+		}, digest)
+		Expect(cw.ack(0, reqNo, digest)).To(Succeed())
+	}
+
+	addRequest := func(reqNo uint64, sizeBytes int) {
+		readyClientRequest("client", reqNo, sizeBytes)
+		p.stepClientWindow([]byte("client"))
+	}
+
+	batchSizes := func() []int {
+		var sizes []int
+		for p.hasPending(0) {
+			sizes = append(sizes, len(p.next(0)))
+		}
+		return sizes
+	}
+
+	When("cutting on request count", func() {
+		BeforeEach(func() {
+			myConfig.BatchParameters = BatchParameters{
+				CutSizeBytes:    1000,
+				CutSizeRequests: 3,
+			}
+		})
+
+		It("cuts once enough requests are queued", func() {
+			for i := uint64(1); i <= 7; i++ {
+				addRequest(i, 10)
+			}
+			Expect(batchSizes()).To(Equal([]int{3, 3}))
+			Expect(p.proposalBuckets[0].queue).To(HaveLen(1))
+		})
+	})
+
+	When("limiting the batch size", func() {
+		BeforeEach(func() {
+			myConfig.BatchParameters = BatchParameters{
+				CutSizeBytes: 1000,
+				MaxSizeBytes: 25,
+			}
+		})
+
+		It("splits the queue rather than exceed the limit", func() {
+			addRequest(1, 10)
+			addRequest(2, 10)
+			addRequest(3, 10)
+			addRequest(4, 40)
+			addRequest(5, 10)
+			Expect(batchSizes()).To(Equal([]int{2, 1, 1}))
+			Expect(p.proposalBuckets[0].queue).To(HaveLen(1))
+			Expect(p.proposalBuckets[0].sizeBytes).To(Equal(10))
+		})
+	})
+
+	When("a batch timeout is configured", func() {
+		BeforeEach(func() {
+			myConfig.BatchParameters = BatchParameters{
+				CutSizeBytes:      1000,
+				BatchTimeoutTicks: 2,
+			}
+		})
+
+		It("cuts a partial batch once it has waited long enough", func() {
+			Expect(p.tick()).To(BeFalse())
+
+			addRequest(1, 10)
+			Expect(p.tick()).To(BeFalse())
+			Expect(p.hasPending(0)).To(BeFalse())
+
+			Expect(p.tick()).To(BeTrue())
+			Expect(batchSizes()).To(Equal([]int{1}))
+			Expect(p.proposalBuckets[0].queue).To(BeEmpty())
+		})
+
+		It("restarts the timeout after a cut", func() {
+			addRequest(1, 10)
+			Expect(p.tick()).To(BeFalse())
+			Expect(p.next(0)).To(HaveLen(1))
+
+			addRequest(2, 10)
+			Expect(p.tick()).To(BeFalse())
+			Expect(p.tick()).To(BeTrue())
+		})
+	})
+
+	When("several clients have requests ready", func() {
+		var (
+			batchClients func() []string
+		)
+
+		BeforeEach(func() {
+			myConfig.BatchParameters = BatchParameters{
+				CutSizeBytes: 1,
+			}
+
+			batchClients = func() []string {
+				var clients []string
+				for p.hasPending(0) {
+					for _, request := range p.next(0) {
+						clients = append(clients, string(request.data.ClientId))
+					}
+				}
+				return clients
+			}
+		})
+
+		It("does not let a chatty client starve the others", func() {
+			for i := uint64(1); i <= 4; i++ {
+				readyClientRequest("chatty", i, proposalQuantumBytes)
+			}
+			readyClientRequest("quiet", 1, proposalQuantumBytes)
+			readyClientRequest("quiet", 2, proposalQuantumBytes)
+			p.stepAllClientWindows()
+
+			Expect(batchClients()).To(Equal([]string{
+				"chatty", "quiet", "chatty", "quiet", "chatty", "chatty",
+			}))
+		})
+
+		It("shares the bucket according to the client weights", func() {
+			myConfig.ClientWeights = map[string]int{
+				"chatty": 2,
+			}
+
+			for i := uint64(1); i <= 4; i++ {
+				readyClientRequest("chatty", i, proposalQuantumBytes)
+			}
+			readyClientRequest("quiet", 1, proposalQuantumBytes)
+			readyClientRequest("quiet", 2, proposalQuantumBytes)
+			p.stepAllClientWindows()
+
+			Expect(batchClients()).To(Equal([]string{
+				"chatty", "chatty", "quiet", "chatty", "chatty", "quiet",
+			}))
+		})
+
+		It("shares the bucket by bytes rather than by requests", func() {
+			for i := uint64(1); i <= 4; i++ {
+				readyClientRequest("small", i, proposalQuantumBytes/2)
+			}
+			readyClientRequest("large", 1, proposalQuantumBytes)
+			readyClientRequest("large", 2, proposalQuantumBytes)
+			p.stepAllClientWindows()
+
+			Expect(batchClients()).To(Equal([]string{
+				"large", "small", "small", "large", "small", "small",
+			}))
+		})
+	})
+})
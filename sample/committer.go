@@ -0,0 +1,185 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sample
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+)
+
+// Committer applies the commits produced by a node in sequence order, and
+// returns a result for each checkpoint reached.
+type Committer interface {
+	Commit([]*mirbft.Commit) []*mirbft.CheckpointResult
+}
+
+// ConcurrentLog is a Log which may apply entries which do not conflict with
+// one another concurrently.
+type ConcurrentLog interface {
+	Log
+
+	// Conflicts returns whether the later entry must be applied only once
+	// the earlier entry has been applied.
+	Conflicts(earlier, later *pb.QEntry) bool
+}
+
+// RingCommitter is an alternative to SerialCommitter which holds the commits
+// which arrive out of order in a ring, rather than a map.  Because the state
+// machine never commits beyond the high watermark, a ring the width of the
+// log, three checkpoint intervals, need never be grown unless the checkpoint
+// interval is reconfigured.  If the Log is a ConcurrentLog, then consecutive
+// entries which do not conflict are applied concurrently, up to Workers at a
+// time, though never across a checkpoint.  The zero value has no ring, and
+// grows one as the commits arrive.
+type RingCommitter struct {
+	Log                Log
+	LastCommittedSeqNo uint64
+	Workers            int // If zero, runtime.NumCPU() workers are used
+
+	ring  []*mirbft.Commit
+	group []*mirbft.Commit
+}
+
+// NewRingCommitter creates a RingCommitter with a ring the width of the log
+// for the given network config.
+func NewRingCommitter(log Log, networkConfig *pb.NetworkConfig, lastCommittedSeqNo uint64) *RingCommitter {
+	return &RingCommitter{
+		Log:                log,
+		LastCommittedSeqNo: lastCommittedSeqNo,
+		ring:               make([]*mirbft.Commit, 3*networkConfig.CheckpointInterval),
+	}
+}
+
+func (rc *RingCommitter) slot(seqNo uint64) **mirbft.Commit {
+	return &rc.ring[seqNo%uint64(len(rc.ring))]
+}
+
+// grow resizes the ring so that it can hold the commits up to width beyond
+// the last committed sequence.
+func (rc *RingCommitter) grow(width uint64) {
+	if width < 2*uint64(len(rc.ring)) {
+		width = 2 * uint64(len(rc.ring))
+	}
+
+	oldRing := rc.ring
+	rc.ring = make([]*mirbft.Commit, width)
+	for _, commit := range oldRing {
+		if commit != nil {
+			*rc.slot(commit.QEntry.SeqNo) = commit
+		}
+	}
+}
+
+// independent returns whether the commit may be applied concurrently with
+// the commits of the group.
+func (rc *RingCommitter) independent(group []*mirbft.Commit, commit *mirbft.Commit) bool {
+	concurrentLog, ok := rc.Log.(ConcurrentLog)
+	if !ok {
+		return len(group) == 0
+	}
+
+	for _, earlier := range group {
+		if concurrentLog.Conflicts(earlier.QEntry, commit.QEntry) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (rc *RingCommitter) apply(group []*mirbft.Commit) {
+	if len(group) == 1 {
+		rc.Log.Apply(group[0].QEntry)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(group))
+	for _, commit := range group {
+		go func(entry *pb.QEntry) {
+			defer wg.Done()
+			rc.Log.Apply(entry)
+		}(commit.QEntry)
+	}
+	wg.Wait()
+}
+
+func (rc *RingCommitter) Commit(commits []*mirbft.Commit) []*mirbft.CheckpointResult {
+	for _, commit := range commits {
+		seqNo := commit.QEntry.SeqNo
+		if seqNo <= rc.LastCommittedSeqNo {
+			// Already applied, for instance, via state transfer.
+			continue
+		}
+
+		if seqNo-rc.LastCommittedSeqNo > uint64(len(rc.ring)) {
+			rc.grow(seqNo - rc.LastCommittedSeqNo)
+		}
+
+		*rc.slot(seqNo) = commit
+	}
+
+	if len(rc.ring) == 0 {
+		// Nothing has arrived to grow the ring, so there is nothing to apply
+		return nil
+	}
+
+	workers := rc.Workers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var results []*mirbft.CheckpointResult
+
+	for {
+		group := rc.group[:0]
+		for len(group) < workers {
+			seqNo := rc.LastCommittedSeqNo + 1 + uint64(len(group))
+			commit := *rc.slot(seqNo)
+			// Note, once the group spans the whole ring, the slot wraps
+			// around to a commit which is already in the group.
+			if commit == nil || commit.QEntry.SeqNo != seqNo || !rc.independent(group, commit) {
+				break
+			}
+
+			group = append(group, commit)
+
+			if commit.Checkpoint {
+				break
+			}
+		}
+
+		if len(group) == 0 {
+			break
+		}
+
+		rc.apply(group)
+
+		for _, commit := range group {
+			*rc.slot(commit.QEntry.SeqNo) = nil
+		}
+		rc.LastCommittedSeqNo += uint64(len(group))
+
+		if group[len(group)-1].Checkpoint {
+			results = append(results, &mirbft.CheckpointResult{
+				SeqNo: rc.LastCommittedSeqNo,
+				Value: rc.Log.Snap(),
+			})
+		}
+
+		// Retain the backing array for the next group, but not the commits.
+		for i := range group {
+			group[i] = nil
+		}
+		rc.group = group[:0]
+	}
+
+	return results
+}
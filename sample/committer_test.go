@@ -0,0 +1,254 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sample_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/IBM/mirbft/sample"
+)
+
+// recordingLog records the order in which entries are applied, and snaps to
+// the number of entries applied.
+type recordingLog struct {
+	mutex   sync.Mutex
+	applied []uint64
+}
+
+func (rl *recordingLog) Apply(entry *pb.QEntry) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.applied = append(rl.applied, entry.SeqNo)
+}
+
+func (rl *recordingLog) Snap() []byte {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(len(rl.applied)))
+	return value
+}
+
+// keyedLog is a ConcurrentLog whose entries conflict when their digests match.
+// It holds each entry being applied briefly, so that concurrent application
+// may be observed.
+type keyedLog struct {
+	recordingLog
+	inflight    int
+	maxInflight int
+	overlapped  bool // Whether conflicting entries were ever applied concurrently
+	applying    map[string]struct{}
+}
+
+func (kl *keyedLog) Apply(entry *pb.QEntry) {
+	kl.mutex.Lock()
+	kl.inflight++
+	if kl.inflight > kl.maxInflight {
+		kl.maxInflight = kl.inflight
+	}
+	if _, ok := kl.applying[string(entry.Digest)]; ok {
+		kl.overlapped = true
+	}
+	kl.applying[string(entry.Digest)] = struct{}{}
+	kl.mutex.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	kl.mutex.Lock()
+	kl.inflight--
+	delete(kl.applying, string(entry.Digest))
+	kl.applied = append(kl.applied, entry.SeqNo)
+	kl.mutex.Unlock()
+}
+
+func (kl *keyedLog) Conflicts(earlier, later *pb.QEntry) bool {
+	return bytes.Equal(earlier.Digest, later.Digest)
+}
+
+// independentLog is a ConcurrentLog whose entries never conflict.
+type independentLog struct {
+	recordingLog
+}
+
+func (il *independentLog) Conflicts(earlier, later *pb.QEntry) bool {
+	return false
+}
+
+func commitsFor(seqNos ...uint64) []*mirbft.Commit {
+	commits := make([]*mirbft.Commit, len(seqNos))
+	for i, seqNo := range seqNos {
+		commits[i] = &mirbft.Commit{
+			QEntry: &pb.QEntry{SeqNo: seqNo},
+		}
+	}
+	return commits
+}
+
+func seqNoRange(first, last uint64) []uint64 {
+	var seqNos []uint64
+	for seqNo := first; seqNo <= last; seqNo++ {
+		seqNos = append(seqNos, seqNo)
+	}
+	return seqNos
+}
+
+var _ = Describe("RingCommitter", func() {
+	var (
+		log           *recordingLog
+		networkConfig *pb.NetworkConfig
+		committer     *sample.RingCommitter
+	)
+
+	BeforeEach(func() {
+		log = &recordingLog{}
+		networkConfig = mirbft.StandardInitialNetworkConfig(4)
+		networkConfig.CheckpointInterval = 2
+		committer = sample.NewRingCommitter(log, networkConfig, 0)
+		committer.Workers = 1
+	})
+
+	It("holds commits which arrive out of order until their predecessors arrive", func() {
+		Expect(committer.Commit(commitsFor(3, 2))).To(BeEmpty())
+		Expect(log.applied).To(BeEmpty())
+
+		committer.Commit(commitsFor(1))
+		Expect(log.applied).To(Equal([]uint64{1, 2, 3}))
+		Expect(committer.LastCommittedSeqNo).To(Equal(uint64(3)))
+	})
+
+	It("ignores commits which were already applied", func() {
+		committer.LastCommittedSeqNo = 5
+		committer.Commit(commitsFor(4, 5, 6))
+		Expect(log.applied).To(Equal([]uint64{6}))
+	})
+
+	It("grows the ring to hold commits beyond its width", func() {
+		committer.Commit(commitsFor(seqNoRange(2, 20)...))
+		Expect(log.applied).To(BeEmpty())
+
+		committer.Commit(commitsFor(1))
+		Expect(log.applied).To(Equal(seqNoRange(1, 20)))
+	})
+
+	It("wraps around the ring as the commits advance", func() {
+		for round := uint64(0); round < 5; round++ {
+			first := round*5 + 1
+			committer.Commit(commitsFor(seqNoRange(first+1, first+4)...))
+			committer.Commit(commitsFor(first))
+		}
+		Expect(log.applied).To(Equal(seqNoRange(1, 25)))
+	})
+
+	It("returns a checkpoint result for each checkpoint reached", func() {
+		commits := commitsFor(seqNoRange(1, 6)...)
+		commits[1].Checkpoint = true
+		commits[5].Checkpoint = true
+
+		results := committer.Commit(commits)
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].SeqNo).To(Equal(uint64(2)))
+		Expect(binary.BigEndian.Uint64(results[0].Value)).To(Equal(uint64(2)))
+		Expect(results[1].SeqNo).To(Equal(uint64(6)))
+		Expect(binary.BigEndian.Uint64(results[1].Value)).To(Equal(uint64(6)))
+	})
+
+	When("the committer is the zero value", func() {
+		BeforeEach(func() {
+			committer = &sample.RingCommitter{
+				Log:     log,
+				Workers: 1,
+			}
+		})
+
+		It("grows a ring as the commits arrive", func() {
+			Expect(committer.Commit(nil)).To(BeEmpty())
+
+			committer.Commit(commitsFor(2))
+			committer.Commit(commitsFor(1, 3))
+			Expect(log.applied).To(Equal([]uint64{1, 2, 3}))
+		})
+	})
+
+	When("the log is a ConcurrentLog", func() {
+		var keyed *keyedLog
+
+		BeforeEach(func() {
+			keyed = &keyedLog{
+				applying: map[string]struct{}{},
+			}
+			committer = sample.NewRingCommitter(keyed, networkConfig, 0)
+			committer.Workers = 4
+		})
+
+		It("applies entries which do not conflict concurrently", func() {
+			commits := commitsFor(seqNoRange(1, 6)...)
+			for i, commit := range commits {
+				commit.QEntry.Digest = []byte{byte(i)}
+			}
+
+			committer.Commit(commits)
+			Expect(keyed.applied).To(ConsistOf(seqNoRange(1, 6)))
+			Expect(keyed.maxInflight).To(BeNumerically(">", 1))
+			Expect(keyed.maxInflight).To(BeNumerically("<=", 4))
+		})
+
+		It("applies conflicting entries in sequence", func() {
+			commits := commitsFor(seqNoRange(1, 6)...)
+			for i, commit := range commits {
+				commit.QEntry.Digest = []byte{byte(i % 2)}
+			}
+
+			committer.Commit(commits)
+			Expect(keyed.overlapped).To(BeFalse())
+			Expect(keyed.applied).To(ConsistOf(seqNoRange(1, 6)))
+
+			var odd, even []uint64
+			for _, seqNo := range keyed.applied {
+				if seqNo%2 == 1 {
+					odd = append(odd, seqNo)
+				} else {
+					even = append(even, seqNo)
+				}
+			}
+			Expect(odd).To(Equal([]uint64{1, 3, 5}))
+			Expect(even).To(Equal([]uint64{2, 4, 6}))
+		})
+
+		It("never applies entries concurrently across a checkpoint", func() {
+			commits := commitsFor(seqNoRange(1, 6)...)
+			for i, commit := range commits {
+				commit.QEntry.Digest = []byte{byte(i)}
+			}
+			commits[2].Checkpoint = true
+
+			results := committer.Commit(commits)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].SeqNo).To(Equal(uint64(3)))
+			Expect(binary.BigEndian.Uint64(results[0].Value)).To(Equal(uint64(3)))
+			Expect(keyed.applied[:3]).To(ConsistOf(uint64(1), uint64(2), uint64(3)))
+		})
+
+		It("does not apply an entry twice once the group spans the ring", func() {
+			networkConfig.CheckpointInterval = 1
+			independent := &independentLog{}
+			committer = sample.NewRingCommitter(independent, networkConfig, 0)
+			committer.Workers = 8
+
+			committer.Commit(commitsFor(seqNoRange(1, 3)...))
+			Expect(independent.applied).To(ConsistOf(uint64(1), uint64(2), uint64(3)))
+			Expect(committer.LastCommittedSeqNo).To(Equal(uint64(3)))
+		})
+	})
+})
@@ -0,0 +1,225 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sample
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+)
+
+// Processor consumes the actions produced by a node, and returns the results
+// which should be added back to it.
+type Processor interface {
+	Process(*mirbft.Actions) *mirbft.ActionResults
+}
+
+// ParallelProcessor is an alternative to SerialProcessor which overlaps the
+// stages of processing.  The hashing, signing, verification, and validation
+// requests are spread across a pool of workers, and the network sends which do
+// not depend on the persisted state are performed while persisting.  Messages
+// which attest to persisted state, such as the Preprepares, Prepares, and
+// Commits for the QEntries and PEntries, are only sent once the Persister has
+// returned.  The results are returned in the same order as the requests in the
+// actions.
+type ParallelProcessor struct {
+	Link      Link
+	Hasher    Hasher
+	Signer    Signer    // Required only if the network requires signatures
+	Verifier  Verifier  // Required only if the network requires signatures
+	Validator Validator // If nil, all requests are considered valid
+	Persister Persister // If nil, nothing is persisted
+	Committer Committer
+	Node      *mirbft.Node
+	Workers   int // If zero, runtime.NumCPU() workers are used
+}
+
+// requiresPersistence returns whether the message may depend upon the state
+// persisted in the same set of actions.  Requests for data, acknowledgements
+// of client requests, and suspicions are safe to send before persisting, while
+// all other messages must wait.
+func requiresPersistence(msg *pb.Msg) bool {
+	switch msg.Type.(type) {
+	case *pb.Msg_RequestAck, *pb.Msg_FetchRequest, *pb.Msg_ForwardRequest, *pb.Msg_FetchBatch, *pb.Msg_FetchSequenceState, *pb.Msg_Suspect:
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *ParallelProcessor) broadcast(msg *pb.Msg) {
+	for _, replica := range c.Node.Replicas {
+		if replica.ID == c.Node.Config.ID {
+			c.Node.Step(context.TODO(), replica.ID, msg)
+		} else {
+			c.Link.Send(replica.ID, msg)
+		}
+	}
+}
+
+// transmit sends the broadcasts and unicasts for which persisted matches
+// requiresPersistence, preserving their relative order.
+func (c *ParallelProcessor) transmit(actions *mirbft.Actions, persisted bool) {
+	for _, broadcast := range actions.Broadcast {
+		if requiresPersistence(broadcast) == persisted {
+			c.broadcast(broadcast)
+		}
+	}
+
+	for _, unicast := range actions.Unicast {
+		if requiresPersistence(unicast.Msg) == persisted {
+			c.Link.Send(unicast.Target, unicast.Msg)
+		}
+	}
+}
+
+func (c *ParallelProcessor) hash(req *mirbft.HashRequest) *mirbft.HashResult {
+	h := c.Hasher()
+	for _, data := range req.Data {
+		h.Write(data)
+	}
+
+	return &mirbft.HashResult{
+		Request: req,
+		Digest:  h.Sum(nil),
+	}
+}
+
+func (c *ParallelProcessor) sign(req *mirbft.SignRequest) *mirbft.SignResult {
+	return &mirbft.SignResult{
+		Request:   req,
+		Signature: c.Signer(req.Data),
+	}
+}
+
+func (c *ParallelProcessor) verify(req *mirbft.VerifySignature) *mirbft.VerifySignatureResult {
+	return &mirbft.VerifySignatureResult{
+		Request: req,
+		Valid:   c.Verifier(req.Signer, req.Data, req.Signature),
+	}
+}
+
+func (c *ParallelProcessor) validate(req *mirbft.ValidateRequest) *mirbft.ValidateResult {
+	return &mirbft.ValidateResult{
+		Request: req,
+		Valid:   c.Validator == nil || c.Validator(req.Request),
+	}
+}
+
+// apply hashes, signs, verifies, and validates the requests of the actions
+// across the workers, storing each result at the index of its request.
+func (c *ParallelProcessor) apply(actions *mirbft.Actions, actionResults *mirbft.ActionResults) {
+	workers := c.Workers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	total := len(actions.Hash) + len(actions.Sign) + len(actions.Verify) + len(actions.Validate)
+	if workers > total {
+		workers = total
+	}
+
+	workC := make(chan int, total)
+	for i := 0; i < total; i++ {
+		workC <- i
+	}
+	close(workC)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range workC {
+				if j < len(actions.Hash) {
+					actionResults.Digests[j] = c.hash(actions.Hash[j])
+					continue
+				}
+
+				j -= len(actions.Hash)
+				if j < len(actions.Sign) {
+					actionResults.Signatures[j] = c.sign(actions.Sign[j])
+					continue
+				}
+
+				j -= len(actions.Sign)
+				if j < len(actions.Verify) {
+					actionResults.Verifications[j] = c.verify(actions.Verify[j])
+					continue
+				}
+
+				j -= len(actions.Verify)
+				actionResults.Validations[j] = c.validate(actions.Validate[j])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *ParallelProcessor) Process(actions *mirbft.Actions) *mirbft.ActionResults {
+	if actions.Replicas != nil {
+		c.Node.Replicas = actions.Replicas
+	}
+
+	checkSigning(actions, c.Signer, c.Verifier)
+
+	actionResults := &mirbft.ActionResults{
+		Digests:       make([]*mirbft.HashResult, len(actions.Hash)),
+		Signatures:    make([]*mirbft.SignResult, len(actions.Sign)),
+		Verifications: make([]*mirbft.VerifySignatureResult, len(actions.Verify)),
+		Validations:   make([]*mirbft.ValidateResult, len(actions.Validate)),
+	}
+
+	var persistErr error
+	persistedC := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		defer close(persistedC)
+		if c.Persister != nil {
+			persistErr = c.Persister.Persist(actions)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		c.transmit(actions, false)
+		<-persistedC
+		if persistErr != nil {
+			return
+		}
+		c.transmit(actions, true)
+	}()
+
+	go func() {
+		defer wg.Done()
+		c.apply(actions, actionResults)
+	}()
+
+	// Commits may reference QEntries persisted in these same actions,
+	// so they are not applied until persisting completes.
+	<-persistedC
+	if persistErr == nil {
+		actionResults.Checkpoints = c.Committer.Commit(actions.Commits)
+	}
+
+	wg.Wait()
+
+	if persistErr != nil {
+		// As with the SerialProcessor, the node may not safely continue
+		// once it has failed to persist its state.
+		panic(persistErr)
+	}
+
+	return actionResults
+}
@@ -0,0 +1,219 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sample
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+)
+
+type blockingPersister struct {
+	persistingC chan struct{}
+	releaseC    chan struct{}
+}
+
+func (bp *blockingPersister) Persist(actions *mirbft.Actions) error {
+	close(bp.persistingC)
+	<-bp.releaseC
+	return nil
+}
+
+type recordingLink struct {
+	mutex sync.Mutex
+	sent  []*pb.Msg
+}
+
+func (rl *recordingLink) Send(dest uint64, msg *pb.Msg) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.sent = append(rl.sent, msg)
+}
+
+func (rl *recordingLink) messages() []*pb.Msg {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	return append([]*pb.Msg(nil), rl.sent...)
+}
+
+type recordingCommitter struct {
+	commits []*mirbft.Commit
+}
+
+func (rc *recordingCommitter) Commit(commits []*mirbft.Commit) []*mirbft.CheckpointResult {
+	rc.commits = append(rc.commits, commits...)
+	return nil
+}
+
+var _ = DescribeTable("requiresPersistence",
+	func(msg *pb.Msg, expected bool) {
+		Expect(requiresPersistence(msg)).To(Equal(expected))
+	},
+	Entry("preprepare", &pb.Msg{Type: &pb.Msg_Preprepare{Preprepare: &pb.Preprepare{}}}, true),
+	Entry("prepare", &pb.Msg{Type: &pb.Msg_Prepare{Prepare: &pb.Prepare{}}}, true),
+	Entry("commit", &pb.Msg{Type: &pb.Msg_Commit{Commit: &pb.Commit{}}}, true),
+	Entry("checkpoint", &pb.Msg{Type: &pb.Msg_Checkpoint{Checkpoint: &pb.Checkpoint{}}}, true),
+	Entry("epoch change", &pb.Msg{Type: &pb.Msg_EpochChange{EpochChange: &pb.EpochChange{}}}, true),
+	Entry("new epoch", &pb.Msg{Type: &pb.Msg_NewEpoch{NewEpoch: &pb.NewEpoch{}}}, true),
+	Entry("forward batch", &pb.Msg{Type: &pb.Msg_ForwardBatch{ForwardBatch: &pb.ForwardBatch{}}}, true),
+	Entry("request ack", &pb.Msg{Type: &pb.Msg_RequestAck{RequestAck: &pb.RequestAck{}}}, false),
+	Entry("fetch request", &pb.Msg{Type: &pb.Msg_FetchRequest{FetchRequest: &pb.RequestAck{}}}, false),
+	Entry("forward request", &pb.Msg{Type: &pb.Msg_ForwardRequest{ForwardRequest: &pb.ForwardRequest{}}}, false),
+	Entry("fetch batch", &pb.Msg{Type: &pb.Msg_FetchBatch{FetchBatch: &pb.FetchBatch{}}}, false),
+	Entry("fetch sequence state", &pb.Msg{Type: &pb.Msg_FetchSequenceState{FetchSequenceState: &pb.FetchSequenceState{}}}, false),
+	Entry("suspect", &pb.Msg{Type: &pb.Msg_Suspect{Suspect: &pb.Suspect{}}}, false),
+)
+
+var _ = Describe("ParallelProcessor", func() {
+	var (
+		persister *blockingPersister
+		link      *recordingLink
+		committer *recordingCommitter
+		processor *ParallelProcessor
+		actions   *mirbft.Actions
+
+		prepare, requestAck, commit, fetchBatch *pb.Msg
+	)
+
+	BeforeEach(func() {
+		persister = &blockingPersister{
+			persistingC: make(chan struct{}),
+			releaseC:    make(chan struct{}),
+		}
+		link = &recordingLink{}
+		committer = &recordingCommitter{}
+
+		processor = &ParallelProcessor{
+			Link:      link,
+			Hasher:    sha256.New,
+			Persister: persister,
+			Committer: committer,
+			Signer: func(data [][]byte) []byte {
+				return append([]byte("signed-"), data[0]...)
+			},
+			Verifier: func(signer uint64, data [][]byte, signature []byte) bool {
+				return signer%2 == 0
+			},
+			Validator: func(req *pb.Request) bool {
+				return req.ReqNo%2 == 0
+			},
+			Node: &mirbft.Node{
+				// The node is not among its replicas, so nothing is
+				// stepped to it, and every broadcast goes to the link.
+				Config:   &mirbft.Config{ID: 9},
+				Replicas: []mirbft.Replica{{ID: 0}, {ID: 1}},
+			},
+			Workers: 3,
+		}
+
+		prepare = &pb.Msg{Type: &pb.Msg_Prepare{Prepare: &pb.Prepare{SeqNo: 1}}}
+		requestAck = &pb.Msg{Type: &pb.Msg_RequestAck{RequestAck: &pb.RequestAck{ReqNo: 1}}}
+		commit = &pb.Msg{Type: &pb.Msg_Commit{Commit: &pb.Commit{SeqNo: 1}}}
+		fetchBatch = &pb.Msg{Type: &pb.Msg_FetchBatch{FetchBatch: &pb.FetchBatch{SeqNo: 1}}}
+
+		actions = &mirbft.Actions{
+			Broadcast: []*pb.Msg{prepare, requestAck},
+			Unicast: []mirbft.Unicast{
+				{Target: 1, Msg: commit},
+				{Target: 1, Msg: fetchBatch},
+			},
+			Commits: []*mirbft.Commit{
+				{QEntry: &pb.QEntry{SeqNo: 1}},
+			},
+		}
+
+		for i := 0; i < 20; i++ {
+			actions.Hash = append(actions.Hash, &mirbft.HashRequest{
+				Data: [][]byte{[]byte(fmt.Sprintf("hash-%d", i))},
+			})
+			actions.Sign = append(actions.Sign, &mirbft.SignRequest{
+				Data: [][]byte{[]byte(fmt.Sprintf("sign-%d", i))},
+			})
+			actions.Verify = append(actions.Verify, &mirbft.VerifySignature{
+				Signer: uint64(i),
+				Data:   [][]byte{[]byte(fmt.Sprintf("verify-%d", i))},
+			})
+			actions.Validate = append(actions.Validate, &mirbft.ValidateRequest{
+				Request: &pb.Request{ReqNo: uint64(i)},
+			})
+		}
+	})
+
+	process := func() <-chan *mirbft.ActionResults {
+		resultsC := make(chan *mirbft.ActionResults, 1)
+		go func() {
+			defer GinkgoRecover()
+			resultsC <- processor.Process(actions)
+		}()
+		return resultsC
+	}
+
+	It("sends only the messages which do not depend on persistence until persisted", func() {
+		resultsC := process()
+
+		Eventually(persister.persistingC).Should(BeClosed())
+		Eventually(link.messages).Should(Equal([]*pb.Msg{requestAck, requestAck, fetchBatch}))
+		Consistently(link.messages).Should(HaveLen(3))
+		Expect(resultsC).NotTo(Receive())
+		Expect(committer.commits).To(BeEmpty())
+
+		close(persister.releaseC)
+		Eventually(resultsC).Should(Receive())
+		Expect(link.messages()).To(Equal([]*pb.Msg{requestAck, requestAck, fetchBatch, prepare, prepare, commit}))
+		Expect(committer.commits).To(Equal(actions.Commits))
+	})
+
+	It("returns each result at the index of its request", func() {
+		close(persister.releaseC)
+
+		var results *mirbft.ActionResults
+		Eventually(process()).Should(Receive(&results))
+
+		Expect(results.Digests).To(HaveLen(20))
+		for i, result := range results.Digests {
+			Expect(result.Request).To(Equal(actions.Hash[i]))
+			digest := sha256.Sum256([]byte(fmt.Sprintf("hash-%d", i)))
+			Expect(result.Digest).To(Equal(digest[:]))
+		}
+
+		Expect(results.Signatures).To(HaveLen(20))
+		for i, result := range results.Signatures {
+			Expect(result.Request).To(Equal(actions.Sign[i]))
+			Expect(result.Signature).To(Equal([]byte(fmt.Sprintf("signed-sign-%d", i))))
+		}
+
+		Expect(results.Verifications).To(HaveLen(20))
+		for i, result := range results.Verifications {
+			Expect(result.Request).To(Equal(actions.Verify[i]))
+			Expect(result.Valid).To(Equal(i%2 == 0))
+		}
+
+		Expect(results.Validations).To(HaveLen(20))
+		for i, result := range results.Validations {
+			Expect(result.Request).To(Equal(actions.Validate[i]))
+			Expect(result.Valid).To(Equal(i%2 == 0))
+		}
+	})
+
+	When("no Signer is configured", func() {
+		BeforeEach(func() {
+			processor.Signer = nil
+		})
+
+		It("rejects actions which require signing", func() {
+			Expect(func() { processor.Process(actions) }).To(Panic())
+			Expect(persister.persistingC).NotTo(BeClosed())
+		})
+	})
+})
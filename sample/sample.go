@@ -16,10 +16,39 @@ import (
 
 type Hasher func() hash.Hash
 
+// Validator returns whether a client request may be ordered.
+type Validator func(*pb.Request) bool
+
+// Signer signs data on behalf of this node.  It is only required when the
+// network config requires signatures.
+type Signer func(data [][]byte) []byte
+
+// Verifier returns whether signature is a valid signature by the node signer
+// over data.  It is only required when the network config requires signatures.
+type Verifier func(signer uint64, data [][]byte, signature []byte) bool
+
+// checkSigning panics if the actions require signing or verification which
+// has not been configured, as the node cannot make progress without it.
+func checkSigning(actions *mirbft.Actions, signer Signer, verifier Verifier) {
+	if len(actions.Sign) > 0 && signer == nil {
+		panic("the network config requires signatures, but no Signer is configured")
+	}
+
+	if len(actions.Verify) > 0 && verifier == nil {
+		panic("the network config requires signatures, but no Verifier is configured")
+	}
+}
+
 type Link interface {
 	Send(dest uint64, msg *pb.Msg)
 }
 
+// Persister durably writes the entries of Actions.Persisted, for instance,
+// the write-ahead log of the wal package.
+type Persister interface {
+	Persist(*mirbft.Actions) error
+}
+
 type Log interface {
 	Apply(*pb.QEntry)
 	Snap() (id []byte)
@@ -35,8 +64,8 @@ type SerialCommitter struct {
 func (sc *SerialCommitter) Commit(commits []*mirbft.Commit) []*mirbft.CheckpointResult {
 	for _, commit := range commits {
 		// Note, this pattern is easy to understand, but memory inefficient.
-		// A ring buffer of size equal to the log size would produce far less
-		// garbage.
+		// The RingCommitter, which holds commits in a ring of size equal to
+		// the log size, produces far less garbage.
 		sc.OutstandingSeqNos[commit.QEntry.SeqNo] = commit
 	}
 
@@ -66,12 +95,24 @@ func (sc *SerialCommitter) Commit(commits []*mirbft.Commit) []*mirbft.Checkpoint
 type SerialProcessor struct {
 	Link      Link
 	Hasher    Hasher
-	Committer *SerialCommitter
+	Signer    Signer    // Required only if the network requires signatures
+	Verifier  Verifier  // Required only if the network requires signatures
+	Validator Validator // If nil, all requests are considered valid
+	Persister Persister // If nil, nothing is persisted
+	Committer Committer
 	Node      *mirbft.Node
 }
 
 func (c *SerialProcessor) Persist(actions *mirbft.Actions) {
-	// TODO we need to persist the PSet, QSet, and some others here
+	if c.Persister == nil {
+		return
+	}
+
+	if err := c.Persister.Persist(actions); err != nil {
+		// The messages we are about to transmit may depend on the state
+		// we failed to persist, so it is not safe to continue.
+		panic(err)
+	}
 }
 
 func (c *SerialProcessor) Transmit(actions *mirbft.Actions) {
@@ -92,7 +133,10 @@ func (c *SerialProcessor) Transmit(actions *mirbft.Actions) {
 
 func (c *SerialProcessor) Apply(actions *mirbft.Actions) *mirbft.ActionResults {
 	actionResults := &mirbft.ActionResults{
-		Digests: make([]*mirbft.HashResult, len(actions.Hash)),
+		Digests:       make([]*mirbft.HashResult, len(actions.Hash)),
+		Signatures:    make([]*mirbft.SignResult, len(actions.Sign)),
+		Verifications: make([]*mirbft.VerifySignatureResult, len(actions.Verify)),
+		Validations:   make([]*mirbft.ValidateResult, len(actions.Validate)),
 	}
 
 	for i, req := range actions.Hash {
@@ -107,12 +151,38 @@ func (c *SerialProcessor) Apply(actions *mirbft.Actions) *mirbft.ActionResults {
 		}
 	}
 
+	for i, req := range actions.Sign {
+		actionResults.Signatures[i] = &mirbft.SignResult{
+			Request:   req,
+			Signature: c.Signer(req.Data),
+		}
+	}
+
+	for i, req := range actions.Verify {
+		actionResults.Verifications[i] = &mirbft.VerifySignatureResult{
+			Request: req,
+			Valid:   c.Verifier(req.Signer, req.Data, req.Signature),
+		}
+	}
+
+	for i, req := range actions.Validate {
+		actionResults.Validations[i] = &mirbft.ValidateResult{
+			Request: req,
+			Valid:   c.Validator == nil || c.Validator(req.Request),
+		}
+	}
+
 	actionResults.Checkpoints = c.Committer.Commit(actions.Commits)
 
 	return actionResults
 }
 
 func (c *SerialProcessor) Process(actions *mirbft.Actions) *mirbft.ActionResults {
+	if actions.Replicas != nil {
+		c.Node.Replicas = actions.Replicas
+	}
+
+	checkSigning(actions, c.Signer, c.Verifier)
 	c.Persist(actions)
 	c.Transmit(actions)
 	return c.Apply(actions)
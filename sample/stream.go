@@ -0,0 +1,293 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sample
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ConsensusServer implements the Consensus service on behalf of a node, and
+// may be registered with a gRPC server via pb.RegisterConsensusServer.
+type ConsensusServer struct {
+	Node *mirbft.Node
+
+	// Authenticate returns the ID of the node which opened a stream, for
+	// instance by inspecting the TLS peer of its context.
+	Authenticate func(ctx context.Context) (uint64, error)
+
+	mutex     sync.Mutex
+	proposers map[string]*serialProposer
+}
+
+// serialProposer serializes the proposals of a client, as a ClientProposer
+// may not be used concurrently.
+type serialProposer struct {
+	mutex    sync.Mutex
+	proposer *mirbft.ClientProposer
+}
+
+var _ pb.ConsensusServer = &ConsensusServer{}
+
+// Step delivers the messages received over the stream to the node, attributed
+// to the authenticated node which opened it, until the stream ends.
+func (cs *ConsensusServer) Step(stream pb.Consensus_StepServer) error {
+	source, err := cs.Authenticate(stream.Context())
+	if err != nil {
+		return errors.WithMessage(err, "could not authenticate stream")
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := cs.Node.Step(stream.Context(), source, msg); err != nil {
+			return errors.WithMessagef(err, "could not step message from node %d", source)
+		}
+	}
+}
+
+// Submit proposes the request to the node, returning once the request has been
+// accepted into the client window.
+func (cs *ConsensusServer) Submit(ctx context.Context, req *pb.Request) (*pb.SubmitResponse, error) {
+	sp, err := cs.proposer(ctx, req.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	if err := sp.proposer.Propose(ctx, req); err != nil {
+		return nil, errors.WithMessagef(err, "could not propose request %d", req.ReqNo)
+	}
+
+	return &pb.SubmitResponse{}, nil
+}
+
+// proposer returns the proposer for the client, creating it on first use, as a
+// node may only have one ClientProposer for each client.
+func (cs *ConsensusServer) proposer(ctx context.Context, clientID []byte) (*serialProposer, error) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if sp, ok := cs.proposers[string(clientID)]; ok {
+		return sp, nil
+	}
+
+	proposer, err := cs.Node.ClientProposer(ctx, clientID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "could not create client proposer")
+	}
+
+	if cs.proposers == nil {
+		cs.proposers = map[string]*serialProposer{}
+	}
+
+	sp := &serialProposer{
+		proposer: proposer,
+	}
+	cs.proposers[string(clientID)] = sp
+
+	return sp, nil
+}
+
+// StreamLink is a Link which sends the messages for each node over the
+// Consensus_StepClient returned by the generated gRPC client.  Each node has a
+// queue of outbound messages, which a dedicated goroutine sends, so that Send
+// never blocks on opening a stream or on a slow node.  A message sent while the
+// queue for its node is full is discarded.  The stream to a node is opened on
+// first use, and if opening or sending fails, the queue is discarded until the
+// retry interval elapses, and the stream is then reopened.  As with any Link,
+// messages may be lost, the state machine recovers from such losses.
+type StreamLink struct {
+	// Open opens a stream to the given node.  It is called by the goroutine
+	// which sends to the node, so it may block while dialing, though Close
+	// waits for it to return.
+	Open func(dest uint64) (pb.Consensus_StepClient, error)
+
+	// Logger is used to report stream failures and discarded messages.  If
+	// nil, nothing is logged.
+	Logger *zap.Logger
+
+	// QueueSize is the number of messages which may be queued for each node
+	// before further messages are discarded.  If zero, DefaultQueueSize is
+	// used.
+	QueueSize int
+
+	// RetryInterval is how long to wait before reopening a stream after
+	// it fails.  If zero, DefaultRetryInterval is used.
+	RetryInterval time.Duration
+
+	mutex   sync.Mutex
+	streams map[uint64]*linkStream
+	closeC  chan struct{}
+	wg      sync.WaitGroup
+}
+
+type linkStream struct {
+	dest   uint64
+	queueC chan *pb.Msg
+}
+
+func (sl *StreamLink) logger() *zap.Logger {
+	if sl.Logger == nil {
+		return zap.NewNop()
+	}
+	return sl.Logger
+}
+
+// linkStream returns the queue for the destination, starting the goroutine
+// which sends it on first use.  It returns nil once the link is closed.
+func (sl *StreamLink) linkStream(dest uint64) *linkStream {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	if sl.streams == nil {
+		sl.streams = map[uint64]*linkStream{}
+		sl.closeC = make(chan struct{})
+	}
+
+	select {
+	case <-sl.closeC:
+		return nil
+	default:
+	}
+
+	ls, ok := sl.streams[dest]
+	if !ok {
+		queueSize := sl.QueueSize
+		if queueSize == 0 {
+			queueSize = DefaultQueueSize
+		}
+
+		ls = &linkStream{
+			dest:   dest,
+			queueC: make(chan *pb.Msg, queueSize),
+		}
+		sl.streams[dest] = ls
+
+		sl.wg.Add(1)
+		go sl.run(ls, sl.closeC)
+	}
+
+	return ls
+}
+
+// Send queues the message for the destination, discarding it if the queue is
+// full.
+func (sl *StreamLink) Send(dest uint64, msg *pb.Msg) {
+	ls := sl.linkStream(dest)
+	if ls == nil {
+		return
+	}
+
+	select {
+	case ls.queueC <- msg:
+	default:
+		sl.logger().Debug("queue is full, discarding message", zap.Uint64("Dest", dest))
+	}
+}
+
+// Close stops sending, and waits for the streams to be closed.
+func (sl *StreamLink) Close() {
+	sl.mutex.Lock()
+	if sl.streams == nil {
+		sl.streams = map[uint64]*linkStream{}
+		sl.closeC = make(chan struct{})
+	}
+
+	select {
+	case <-sl.closeC:
+	default:
+		close(sl.closeC)
+	}
+	sl.mutex.Unlock()
+
+	sl.wg.Wait()
+}
+
+// run opens the stream to the destination, and sends its queue of messages
+// over it, reopening the stream whenever it fails, until the link is closed.
+func (sl *StreamLink) run(ls *linkStream, closeC <-chan struct{}) {
+	defer sl.wg.Done()
+
+	retryInterval := sl.RetryInterval
+	if retryInterval == 0 {
+		retryInterval = DefaultRetryInterval
+	}
+
+	for {
+		stream, err := sl.Open(ls.dest)
+		if err != nil {
+			sl.logger().Debug("could not open stream", zap.Uint64("Dest", ls.dest), zap.Error(err))
+		} else {
+			err = sl.send(stream, ls, closeC)
+			stream.CloseSend()
+			if err == nil {
+				return
+			}
+			sl.logger().Warn("could not send message, discarding stream", zap.Uint64("Dest", ls.dest), zap.Error(err))
+		}
+
+		if !sl.discard(ls, retryInterval, closeC) {
+			return
+		}
+	}
+}
+
+// send sends the queued messages over the stream until the link is closed, in
+// which case it returns nil, or the stream fails.
+func (sl *StreamLink) send(stream pb.Consensus_StepClient, ls *linkStream, closeC <-chan struct{}) error {
+	for {
+		select {
+		case msg := <-ls.queueC:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case <-closeC:
+			return nil
+		}
+	}
+}
+
+// discard drops the messages queued for the destination until the retry
+// interval elapses.  It returns false if the link was closed.
+func (sl *StreamLink) discard(ls *linkStream, retryInterval time.Duration, closeC <-chan struct{}) bool {
+	timer := time.NewTimer(retryInterval)
+	defer timer.Stop()
+
+	dropped := 0
+	defer func() {
+		if dropped > 0 {
+			sl.logger().Debug("discarded messages for unreachable node", zap.Uint64("Dest", ls.dest), zap.Int("Count", dropped))
+		}
+	}()
+
+	for {
+		select {
+		case <-ls.queueC:
+			dropped++
+		case <-timer.C:
+			return true
+		case <-closeC:
+			return false
+		}
+	}
+}
@@ -0,0 +1,347 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sample_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/IBM/mirbft/mock"
+	"github.com/IBM/mirbft/sample"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	recvC chan *pb.Msg
+}
+
+func (fss *fakeServerStream) Recv() (*pb.Msg, error) {
+	msg, ok := <-fss.recvC
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func (fss *fakeServerStream) Send(msg *pb.Msg) error {
+	return errors.New("unused")
+}
+
+func (fss *fakeServerStream) Context() context.Context {
+	return fss.ctx
+}
+
+type fakeClientStream struct {
+	grpc.ClientStream
+	mutex sync.Mutex
+	sent  []*pb.Msg
+	err   error
+}
+
+func (fcs *fakeClientStream) Send(msg *pb.Msg) error {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+	if fcs.err != nil {
+		return fcs.err
+	}
+	fcs.sent = append(fcs.sent, msg)
+	return nil
+}
+
+func (fcs *fakeClientStream) Recv() (*pb.Msg, error) {
+	return nil, errors.New("unused")
+}
+
+func (fcs *fakeClientStream) CloseSend() error {
+	return nil
+}
+
+func (fcs *fakeClientStream) fail(err error) {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+	fcs.err = err
+}
+
+func (fcs *fakeClientStream) sentCount() int {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+	return len(fcs.sent)
+}
+
+// recordingConsensusServer records the messages stepped to it over gRPC.
+type recordingConsensusServer struct {
+	steppedC chan *pb.Msg
+}
+
+func (rcs *recordingConsensusServer) Step(stream pb.Consensus_StepServer) error {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		rcs.steppedC <- msg
+	}
+}
+
+func (rcs *recordingConsensusServer) Submit(ctx context.Context, req *pb.Request) (*pb.SubmitResponse, error) {
+	return &pb.SubmitResponse{}, nil
+}
+
+var _ = Describe("ConsensusServer", func() {
+	var (
+		doneC  chan struct{}
+		node   *mirbft.Node
+		stream *fakeServerStream
+		server *sample.ConsensusServer
+	)
+
+	BeforeEach(func() {
+		doneC = make(chan struct{})
+
+		storage := &mock.Storage{}
+		storage.LoadReturns(nil, io.EOF)
+
+		var err error
+		node, err = mirbft.StartNewNode(&mirbft.Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: mirbft.BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}, doneC, mirbft.StandardInitialNetworkConfig(4), storage)
+		Expect(err).NotTo(HaveOccurred())
+
+		stream = &fakeServerStream{
+			ctx:   context.Background(),
+			recvC: make(chan *pb.Msg, 10),
+		}
+
+		server = &sample.ConsensusServer{
+			Node: node,
+			Authenticate: func(ctx context.Context) (uint64, error) {
+				return 2, nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		close(doneC)
+	})
+
+	It("steps the messages of a stream until it ends", func() {
+		stream.recvC <- &pb.Msg{Type: &pb.Msg_Suspect{Suspect: &pb.Suspect{Epoch: 0}}}
+		close(stream.recvC)
+		Expect(server.Step(stream)).To(Succeed())
+	})
+
+	It("returns an error for a malformed message", func() {
+		stream.recvC <- &pb.Msg{}
+		err := server.Step(stream)
+		Expect(err).To(MatchError(ContainSubstring("could not step message from node 2")))
+	})
+
+	It("serves the Consensus service over gRPC", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		grpcServer := grpc.NewServer()
+		defer grpcServer.Stop()
+		pb.RegisterConsensusServer(grpcServer, server)
+		go grpcServer.Serve(listener)
+
+		conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		_, err = pb.NewConsensusClient(conn).Submit(context.Background(), &pb.Request{
+			ClientId: []byte("client"),
+			ReqNo:    1,
+			Data:     []byte("data"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	When("the stream cannot be authenticated", func() {
+		BeforeEach(func() {
+			server.Authenticate = func(ctx context.Context) (uint64, error) {
+				return 0, errors.New("no client certificate")
+			}
+		})
+
+		It("returns an error without stepping", func() {
+			stream.recvC <- &pb.Msg{}
+			err := server.Step(stream)
+			Expect(err).To(MatchError("could not authenticate stream: no client certificate"))
+			Expect(stream.recvC).To(HaveLen(1))
+		})
+	})
+
+	It("proposes submitted requests, reusing the client proposer", func() {
+		for reqNo := uint64(1); reqNo <= 3; reqNo++ {
+			_, err := server.Submit(context.Background(), &pb.Request{
+				ClientId: []byte("client"),
+				ReqNo:    reqNo,
+				Data:     []byte("data"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+})
+
+var _ = Describe("StreamLink", func() {
+	var (
+		mutex    sync.Mutex
+		streams  []*fakeClientStream
+		blockedC chan struct{}
+		link     *sample.StreamLink
+	)
+
+	opened := func() []*fakeClientStream {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]*fakeClientStream(nil), streams...)
+	}
+
+	BeforeEach(func() {
+		streams = nil
+		blockedC = make(chan struct{})
+		link = &sample.StreamLink{
+			RetryInterval: 10 * time.Millisecond,
+			Open: func(dest uint64) (pb.Consensus_StepClient, error) {
+				switch dest {
+				case 3:
+					return nil, errors.New("unreachable")
+				case 4:
+					<-blockedC
+					return nil, errors.New("timed out")
+				}
+				mutex.Lock()
+				defer mutex.Unlock()
+				stream := &fakeClientStream{}
+				streams = append(streams, stream)
+				return stream, nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		close(blockedC)
+		link.Close()
+	})
+
+	It("opens one stream per destination", func() {
+		link.Send(1, &pb.Msg{})
+		link.Send(1, &pb.Msg{})
+		link.Send(2, &pb.Msg{})
+		Eventually(opened).Should(HaveLen(2))
+		Eventually(func() int {
+			return opened()[0].sentCount() + opened()[1].sentCount()
+		}).Should(Equal(3))
+		Consistently(opened).Should(HaveLen(2))
+	})
+
+	It("reopens a stream which fails", func() {
+		link.Send(1, &pb.Msg{})
+		Eventually(opened).Should(HaveLen(1))
+		Eventually(opened()[0].sentCount).Should(Equal(1))
+		opened()[0].fail(errors.New("broken"))
+
+		Eventually(func() int {
+			link.Send(1, &pb.Msg{})
+			return len(opened())
+		}).Should(Equal(2))
+		Eventually(func() int {
+			link.Send(1, &pb.Msg{})
+			return opened()[1].sentCount()
+		}).Should(BeNumerically(">", 0))
+		Expect(opened()[0].sentCount()).To(Equal(1))
+	})
+
+	It("discards messages for destinations it cannot reach", func() {
+		link.Send(3, &pb.Msg{})
+		Consistently(opened).Should(BeEmpty())
+	})
+
+	It("does not block while a stream is being opened", func() {
+		sentC := make(chan struct{})
+		go func() {
+			for i := 0; i < 2*sample.DefaultQueueSize; i++ {
+				link.Send(4, &pb.Msg{})
+			}
+			link.Send(1, &pb.Msg{})
+			close(sentC)
+		}()
+
+		Eventually(sentC).Should(BeClosed())
+		Eventually(opened).Should(HaveLen(1))
+	})
+
+	When("sending over gRPC", func() {
+		var (
+			grpcServer *grpc.Server
+			recorder   *recordingConsensusServer
+			conn       *grpc.ClientConn
+		)
+
+		BeforeEach(func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+
+			recorder = &recordingConsensusServer{
+				steppedC: make(chan *pb.Msg, 10),
+			}
+			grpcServer = grpc.NewServer()
+			pb.RegisterConsensusServer(grpcServer, recorder)
+			go grpcServer.Serve(listener)
+
+			conn, err = grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+			Expect(err).NotTo(HaveOccurred())
+
+			client := pb.NewConsensusClient(conn)
+			link.Open = func(dest uint64) (pb.Consensus_StepClient, error) {
+				return client.Step(context.Background())
+			}
+		})
+
+		AfterEach(func() {
+			link.Close()
+			conn.Close()
+			grpcServer.Stop()
+		})
+
+		It("delivers the messages in order", func() {
+			for seqNo := uint64(1); seqNo <= 3; seqNo++ {
+				link.Send(1, &pb.Msg{
+					Type: &pb.Msg_Checkpoint{
+						Checkpoint: &pb.Checkpoint{SeqNo: seqNo},
+					},
+				})
+			}
+
+			for seqNo := uint64(1); seqNo <= 3; seqNo++ {
+				var msg *pb.Msg
+				Eventually(recorder.steppedC).Should(Receive(&msg))
+				Expect(msg.Type.(*pb.Msg_Checkpoint).Checkpoint.SeqNo).To(Equal(seqNo))
+			}
+		})
+	})
+})
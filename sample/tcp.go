@@ -0,0 +1,484 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sample
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultQueueSize        = 1000
+	DefaultMaxMsgBytes      = 64 * 1024 * 1024
+	DefaultRetryInterval    = 500 * time.Millisecond
+	DefaultHandshakeTimeout = 5 * time.Second
+	DefaultWriteTimeout     = 5 * time.Second
+)
+
+// Stepper receives the messages from the network, it is usually a *mirbft.Node.
+type Stepper interface {
+	Step(ctx context.Context, source uint64, msg *pb.Msg) error
+}
+
+// Handshake establishes the identity of the node at the other end of a newly
+// established connection.  It is invoked by both the dialing and the accepting
+// side, and should authenticate the remote node, for instance by inspecting
+// the peer certificates of a TLS connection.  The messages received over the
+// connection are attributed to the returned ID.
+type Handshake func(conn net.Conn, localID uint64) (remoteID uint64, err error)
+
+// UnauthenticatedHandshake exchanges node IDs without authenticating them.  It
+// is only suitable for testing, or for networks which are otherwise secured.
+func UnauthenticatedHandshake(conn net.Conn, localID uint64) (uint64, error) {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, localID)
+	if _, err := conn.Write(buf); err != nil {
+		return 0, errors.WithMessage(err, "could not send node ID")
+	}
+
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return 0, errors.WithMessage(err, "could not receive node ID")
+	}
+
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+type TCPTransportConfig struct {
+	// ID is the ID of this node.
+	ID uint64
+
+	// Peers are the addresses of the other nodes in the network, by ID.
+	Peers map[uint64]string
+
+	// Logger is used to report connection failures and discarded messages.
+	// If nil, nothing is logged.
+	Logger *zap.Logger
+
+	// Handshake authenticates each connection.  If nil, the
+	// UnauthenticatedHandshake is used.
+	Handshake Handshake
+
+	// Dial establishes the connection to a peer, for instance with TLS.
+	// If nil, a plain TCP connection is used.
+	Dial func(address string) (net.Conn, error)
+
+	// QueueSize is the number of messages which may be queued for each peer
+	// before Send blocks.  If zero, DefaultQueueSize is used.
+	QueueSize int
+
+	// MaxMsgBytes is the largest message which will be accepted from a peer.
+	// If zero, DefaultMaxMsgBytes is used.
+	MaxMsgBytes uint32
+
+	// RetryInterval is how long to wait before redialing a peer after a
+	// connection fails.  If zero, DefaultRetryInterval is used.
+	RetryInterval time.Duration
+
+	// HandshakeTimeout bounds how long the dial and handshake of a
+	// connection may take.  If zero, DefaultHandshakeTimeout is used.
+	HandshakeTimeout time.Duration
+
+	// WriteTimeout bounds how long a write to a peer may block.  A peer
+	// which does not read its messages within the timeout is treated as
+	// unreachable, its connection is closed, its queue discarded, and it is
+	// redialed.  If zero, DefaultWriteTimeout is used.
+	WriteTimeout time.Duration
+}
+
+// TCPTransport is a Link which sends length prefixed messages to each peer
+// over a dedicated outbound TCP connection, and delivers the messages it
+// receives over inbound connections to a Stepper, attributed to the node
+// authenticated by the handshake.  Each peer has a queue of outbound messages
+// which Send blocks on when full, so that a slow peer slows the sender, though
+// only until a write to it times out.  While a peer is unreachable its queue is
+// discarded, rather than blocking, and the connection is periodically
+// redialed.  As with any Link, messages may be lost, the state machine
+// recovers from such losses.
+type TCPTransport struct {
+	config   *TCPTransportConfig
+	stepper  Stepper
+	listener net.Listener
+	peers    map[uint64]*tcpPeer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mutex sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+type tcpPeer struct {
+	id      uint64
+	address string
+	queueC  chan *pb.Msg
+}
+
+// NewTCPTransport begins accepting connections on the listener, and dialing
+// the peers of the config.  The transport runs until closed.
+func NewTCPTransport(config *TCPTransportConfig, listener net.Listener, stepper Stepper) *TCPTransport {
+	queueSize := config.QueueSize
+	if queueSize == 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	if config.Logger == nil {
+		// Copy the config rather than modifying the caller's
+		withLogger := *config
+		withLogger.Logger = zap.NewNop()
+		config = &withLogger
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &TCPTransport{
+		config:   config,
+		stepper:  stepper,
+		listener: listener,
+		peers:    map[uint64]*tcpPeer{},
+		ctx:      ctx,
+		cancel:   cancel,
+		conns:    map[net.Conn]struct{}{},
+	}
+
+	for id, address := range config.Peers {
+		if id == config.ID {
+			continue
+		}
+
+		t.peers[id] = &tcpPeer{
+			id:      id,
+			address: address,
+			queueC:  make(chan *pb.Msg, queueSize),
+		}
+	}
+
+	t.wg.Add(1 + len(t.peers))
+	go t.accept()
+	for _, peer := range t.peers {
+		go t.runPeer(peer)
+	}
+
+	return t
+}
+
+// Send queues the message for the destination, blocking while its queue is
+// full.  Messages for unknown destinations are discarded.
+func (t *TCPTransport) Send(dest uint64, msg *pb.Msg) {
+	peer, ok := t.peers[dest]
+	if !ok {
+		t.config.Logger.Warn("discarding message for unknown node", zap.Uint64("Dest", dest))
+		return
+	}
+
+	select {
+	case peer.queueC <- msg:
+	case <-t.ctx.Done():
+	}
+}
+
+// Close stops the transport, closing the listener and every connection.
+func (t *TCPTransport) Close() error {
+	t.cancel()
+	err := t.listener.Close()
+
+	t.mutex.Lock()
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.mutex.Unlock()
+
+	t.wg.Wait()
+	return err
+}
+
+func (t *TCPTransport) handshakeTimeout() time.Duration {
+	if t.config.HandshakeTimeout == 0 {
+		return DefaultHandshakeTimeout
+	}
+	return t.config.HandshakeTimeout
+}
+
+func (t *TCPTransport) writeTimeout() time.Duration {
+	if t.config.WriteTimeout == 0 {
+		return DefaultWriteTimeout
+	}
+	return t.config.WriteTimeout
+}
+
+// track records the connection so that it is closed with the transport.  It
+// returns false if the transport has already been closed.
+func (t *TCPTransport) track(conn net.Conn) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.ctx.Err() != nil {
+		return false
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+func (t *TCPTransport) untrack(conn net.Conn) {
+	t.mutex.Lock()
+	delete(t.conns, conn)
+	t.mutex.Unlock()
+	conn.Close()
+}
+
+func (t *TCPTransport) handshake(conn net.Conn) (uint64, error) {
+	handshake := t.config.Handshake
+	if handshake == nil {
+		handshake = UnauthenticatedHandshake
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(t.handshakeTimeout())); err != nil {
+		return 0, err
+	}
+
+	remoteID, err := handshake(conn, t.config.ID)
+	if err != nil {
+		return 0, errors.WithMessage(err, "handshake failed")
+	}
+
+	return remoteID, conn.SetDeadline(time.Time{})
+}
+
+func (t *TCPTransport) accept() {
+	defer t.wg.Done()
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			if t.ctx.Err() != nil {
+				return
+			}
+
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+
+			t.config.Logger.Error("could not accept connection, no longer receiving", zap.Error(err))
+			return
+		}
+
+		if !t.track(conn) {
+			conn.Close()
+			return
+		}
+
+		t.wg.Add(1)
+		go t.serve(conn)
+	}
+}
+
+// serve delivers the messages received over an inbound connection to the
+// stepper until the connection fails.
+func (t *TCPTransport) serve(conn net.Conn) {
+	defer t.wg.Done()
+	defer t.untrack(conn)
+
+	remoteID, err := t.handshake(conn)
+	if err != nil {
+		t.config.Logger.Warn("rejecting connection", zap.Stringer("RemoteAddr", conn.RemoteAddr()), zap.Error(err))
+		return
+	}
+
+	if _, ok := t.peers[remoteID]; !ok {
+		t.config.Logger.Warn("rejecting connection from unknown node", zap.Stringer("RemoteAddr", conn.RemoteAddr()), zap.Uint64("NodeID", remoteID))
+		return
+	}
+
+	maxMsgBytes := t.config.MaxMsgBytes
+	if maxMsgBytes == 0 {
+		maxMsgBytes = DefaultMaxMsgBytes
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := readFrame(r, maxMsgBytes)
+		if err != nil {
+			if t.ctx.Err() == nil && err != io.EOF {
+				t.config.Logger.Warn("closing connection", zap.Uint64("NodeID", remoteID), zap.Error(err))
+			}
+			return
+		}
+
+		if err := t.stepper.Step(t.ctx, remoteID, msg); err != nil {
+			if t.ctx.Err() == nil {
+				t.config.Logger.Warn("could not step message, closing connection", zap.Uint64("NodeID", remoteID), zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+func (t *TCPTransport) dial(peer *tcpPeer) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if t.config.Dial != nil {
+		conn, err = t.config.Dial(peer.address)
+	} else {
+		conn, err = net.DialTimeout("tcp", peer.address, t.handshakeTimeout())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	remoteID, err := t.handshake(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if remoteID != peer.id {
+		conn.Close()
+		return nil, errors.Errorf("expected to connect to node %d, but connected to node %d", peer.id, remoteID)
+	}
+
+	return conn, nil
+}
+
+// runPeer maintains the outbound connection to a peer, and writes its queue
+// of messages to it.
+func (t *TCPTransport) runPeer(peer *tcpPeer) {
+	defer t.wg.Done()
+
+	retryInterval := t.config.RetryInterval
+	if retryInterval == 0 {
+		retryInterval = DefaultRetryInterval
+	}
+
+	for {
+		conn, err := t.dial(peer)
+		if err == nil && !t.track(conn) {
+			conn.Close()
+			return
+		}
+
+		if err != nil {
+			t.config.Logger.Debug("could not connect to node", zap.Uint64("NodeID", peer.id), zap.String("Address", peer.address), zap.Error(err))
+		} else {
+			err = t.write(conn, peer)
+			t.untrack(conn)
+			if t.ctx.Err() != nil {
+				return
+			}
+			t.config.Logger.Warn("connection to node failed", zap.Uint64("NodeID", peer.id), zap.Error(err))
+		}
+
+		if !t.discard(peer, retryInterval) {
+			return
+		}
+	}
+}
+
+// discard drops the messages queued for a peer until the retry interval
+// elapses, so that senders do not block on an unreachable peer.  It returns
+// false if the transport was closed.
+func (t *TCPTransport) discard(peer *tcpPeer, retryInterval time.Duration) bool {
+	timer := time.NewTimer(retryInterval)
+	defer timer.Stop()
+
+	dropped := 0
+	defer func() {
+		if dropped > 0 {
+			t.config.Logger.Debug("discarded messages for unreachable node", zap.Uint64("NodeID", peer.id), zap.Int("Count", dropped))
+		}
+	}()
+
+	for {
+		select {
+		case <-peer.queueC:
+			dropped++
+		case <-timer.C:
+			return true
+		case <-t.ctx.Done():
+			return false
+		}
+	}
+}
+
+// write sends the queued messages for the peer over the connection until the
+// transport is closed, or the connection fails.  The buffered frames are
+// flushed whenever the queue is empty.  Each message, and any flush it causes,
+// must be written within the write timeout, so that a peer which stops reading
+// does not block the senders indefinitely.
+func (t *TCPTransport) write(conn net.Conn, peer *tcpPeer) error {
+	w := bufio.NewWriter(conn)
+	for {
+		var msg *pb.Msg
+		select {
+		case msg = <-peer.queueC:
+		case <-t.ctx.Done():
+			return nil
+		}
+
+		if err := conn.SetWriteDeadline(time.Now().Add(t.writeTimeout())); err != nil {
+			return err
+		}
+
+		if err := writeFrame(w, msg); err != nil {
+			return err
+		}
+
+		if len(peer.queueC) == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeFrame(w *bufio.Writer, msg *pb.Msg) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.WithMessage(err, "could not marshal message")
+	}
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(data)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func readFrame(r *bufio.Reader, maxMsgBytes uint32) (*pb.Msg, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxMsgBytes {
+		return nil, errors.Errorf("message of %d bytes exceeds the maximum of %d bytes", size, maxMsgBytes)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	msg := &pb.Msg{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, errors.WithMessage(err, "could not unmarshal message")
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,304 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sample_test
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/IBM/mirbft/sample"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type steppedMsg struct {
+	source uint64
+	msg    *pb.Msg
+}
+
+type fakeStepper struct {
+	steppedC chan steppedMsg
+}
+
+func (fs *fakeStepper) Step(ctx context.Context, source uint64, msg *pb.Msg) error {
+	select {
+	case fs.steppedC <- steppedMsg{source: source, msg: msg}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var _ = Describe("TCPTransport", func() {
+	var (
+		listeners  []net.Listener
+		configs    []*sample.TCPTransportConfig
+		steppers   []*fakeStepper
+		transports []*sample.TCPTransport
+	)
+
+	checkpoint := func(seqNo uint64) *pb.Msg {
+		return &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{SeqNo: seqNo, Value: []byte("value")},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		listeners = make([]net.Listener, 3)
+		peers := map[uint64]string{}
+		for i := range listeners {
+			var err error
+			listeners[i], err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			peers[uint64(i)] = listeners[i].Addr().String()
+		}
+
+		configs = make([]*sample.TCPTransportConfig, len(listeners))
+		steppers = make([]*fakeStepper, len(listeners))
+		for i := range listeners {
+			configs[i] = &sample.TCPTransportConfig{
+				ID:            uint64(i),
+				Peers:         peers,
+				Logger:        zap.NewNop(),
+				RetryInterval: 10 * time.Millisecond,
+			}
+			steppers[i] = &fakeStepper{
+				steppedC: make(chan steppedMsg, 100),
+			}
+		}
+	})
+
+	JustBeforeEach(func() {
+		transports = make([]*sample.TCPTransport, len(listeners))
+		for i := range listeners {
+			transports[i] = sample.NewTCPTransport(configs[i], listeners[i], steppers[i])
+		}
+	})
+
+	AfterEach(func() {
+		for _, transport := range transports {
+			if transport != nil {
+				transport.Close()
+			}
+		}
+	})
+
+	It("delivers messages in order, attributed to their source", func() {
+		for seqNo := uint64(1); seqNo <= 10; seqNo++ {
+			transports[0].Send(1, checkpoint(seqNo))
+		}
+		transports[2].Send(1, checkpoint(20))
+
+		var fromZero []uint64
+		var fromTwo []uint64
+		for len(fromZero) < 10 || len(fromTwo) < 1 {
+			var stepped steppedMsg
+			Eventually(steppers[1].steppedC).Should(Receive(&stepped))
+			switch stepped.source {
+			case 0:
+				fromZero = append(fromZero, stepped.msg.Type.(*pb.Msg_Checkpoint).Checkpoint.SeqNo)
+			case 2:
+				fromTwo = append(fromTwo, stepped.msg.Type.(*pb.Msg_Checkpoint).Checkpoint.SeqNo)
+			default:
+				Fail("unexpected source")
+			}
+		}
+
+		Expect(fromZero).To(Equal([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
+		Expect(fromTwo).To(Equal([]uint64{20}))
+		Expect(steppers[0].steppedC).To(BeEmpty())
+		Expect(steppers[2].steppedC).To(BeEmpty())
+	})
+
+	It("reconnects to a peer which restarts", func() {
+		transports[0].Send(1, checkpoint(1))
+		Eventually(steppers[1].steppedC).Should(Receive())
+
+		address := listeners[1].Addr().String()
+		Expect(transports[1].Close()).To(Succeed())
+
+		var err error
+		listeners[1], err = net.Listen("tcp", address)
+		Expect(err).NotTo(HaveOccurred())
+		transports[1] = sample.NewTCPTransport(configs[1], listeners[1], steppers[1])
+
+		// Messages sent while the peer is unreachable may be lost, so
+		// keep sending until one arrives.
+		Eventually(func() bool {
+			transports[0].Send(1, checkpoint(2))
+			select {
+			case stepped := <-steppers[1].steppedC:
+				return proto.Equal(stepped.msg, checkpoint(2))
+			case <-time.After(10 * time.Millisecond):
+				return false
+			}
+		}).Should(BeTrue())
+	})
+
+	When("a peer is unreachable", func() {
+		BeforeEach(func() {
+			Expect(listeners[2].Close()).To(Succeed())
+			configs[0].QueueSize = 1
+		})
+
+		It("does not block sends to it", func() {
+			for seqNo := uint64(1); seqNo <= 10; seqNo++ {
+				transports[0].Send(2, checkpoint(seqNo))
+			}
+			transports[0].Send(1, checkpoint(11))
+			Eventually(steppers[1].steppedC).Should(Receive())
+		})
+	})
+
+	When("a peer stops reading", func() {
+		var (
+			stalled   net.Listener
+			acceptedC chan net.Conn
+			transport *sample.TCPTransport
+		)
+
+		BeforeEach(func() {
+			var err error
+			stalled, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+
+			// Accept connections as node 2, but never read from them
+			acceptedC = make(chan net.Conn, 10)
+			go func() {
+				for {
+					conn, err := stalled.Accept()
+					if err != nil {
+						return
+					}
+					if _, err := sample.UnauthenticatedHandshake(conn, 2); err != nil {
+						conn.Close()
+						continue
+					}
+					acceptedC <- conn
+				}
+			}()
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+
+			transport = sample.NewTCPTransport(&sample.TCPTransportConfig{
+				ID: 0,
+				Peers: map[uint64]string{
+					0: listener.Addr().String(),
+					2: stalled.Addr().String(),
+				},
+				QueueSize:     1,
+				RetryInterval: 10 * time.Millisecond,
+				WriteTimeout:  50 * time.Millisecond,
+			}, listener, &fakeStepper{})
+		})
+
+		AfterEach(func() {
+			transport.Close()
+			stalled.Close()
+			for {
+				select {
+				case conn := <-acceptedC:
+					conn.Close()
+				default:
+					return
+				}
+			}
+		})
+
+		It("times out, discards the queue, and reconnects", func() {
+			var first net.Conn
+			Eventually(acceptedC).Should(Receive(&first))
+			defer first.Close()
+
+			// Enough data to fill the socket buffers many times over
+			large := &pb.Msg{
+				Type: &pb.Msg_Checkpoint{
+					Checkpoint: &pb.Checkpoint{SeqNo: 1, Value: make([]byte, 1024*1024)},
+				},
+			}
+
+			sentC := make(chan struct{})
+			go func() {
+				for i := 0; i < 64; i++ {
+					transport.Send(2, large)
+				}
+				close(sentC)
+			}()
+
+			Eventually(sentC, 5*time.Second).Should(BeClosed())
+			Eventually(acceptedC, 5*time.Second).Should(Receive())
+		})
+	})
+
+	When("the handshake fails", func() {
+		BeforeEach(func() {
+			configs[1].Handshake = func(conn net.Conn, localID uint64) (uint64, error) {
+				return 0, errors.New("unauthorized")
+			}
+		})
+
+		It("delivers nothing", func() {
+			transports[0].Send(1, checkpoint(1))
+			transports[2].Send(1, checkpoint(1))
+			Consistently(steppers[1].steppedC, 100*time.Millisecond).Should(BeEmpty())
+		})
+	})
+
+	It("rejects connections from unknown nodes", func() {
+		conn, err := net.Dial("tcp", listeners[1].Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		_, err = sample.UnauthenticatedHandshake(conn, 7)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := proto.Marshal(checkpoint(1))
+		Expect(err).NotTo(HaveOccurred())
+		frame := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(frame, uint32(len(data)))
+		conn.Write(append(frame, data...))
+
+		Consistently(steppers[1].steppedC, 100*time.Millisecond).Should(BeEmpty())
+	})
+
+	When("a message exceeds the maximum size", func() {
+		BeforeEach(func() {
+			configs[1].MaxMsgBytes = 8
+		})
+
+		It("closes the connection", func() {
+			conn, err := net.Dial("tcp", listeners[1].Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			_, err = sample.UnauthenticatedHandshake(conn, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := proto.Marshal(checkpoint(1))
+			Expect(err).NotTo(HaveOccurred())
+			frame := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(frame, uint32(len(data)))
+			_, err = conn.Write(append(frame, data...))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(conn.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+			_, err = conn.Read(make([]byte, 1))
+			Expect(err).To(HaveOccurred())
+			Expect(steppers[1].steppedC).To(BeEmpty())
+		})
+	})
+})
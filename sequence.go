@@ -245,6 +245,51 @@ func (s *sequence) prepare() *Actions {
 	return actions
 }
 
+// sentMsgs returns the Preprepare or Prepare, and the Commit we have sent for
+// this sequence, so that they may be retransmitted to a node which lost them.
+func (s *sequence) sentMsgs() []*pb.Msg {
+	if s.state < Preprepared {
+		return nil
+	}
+
+	var msgs []*pb.Msg
+	if s.owner == NodeID(s.myConfig.ID) {
+		msgs = append(msgs, &pb.Msg{
+			Type: &pb.Msg_Preprepare{
+				Preprepare: &pb.Preprepare{
+					SeqNo: s.seqNo,
+					Epoch: s.epoch,
+					Batch: s.batch,
+				},
+			},
+		})
+	} else {
+		msgs = append(msgs, &pb.Msg{
+			Type: &pb.Msg_Prepare{
+				Prepare: &pb.Prepare{
+					SeqNo:  s.seqNo,
+					Epoch:  s.epoch,
+					Digest: s.digest,
+				},
+			},
+		})
+	}
+
+	if s.state < Prepared {
+		return msgs
+	}
+
+	return append(msgs, &pb.Msg{
+		Type: &pb.Msg_Commit{
+			Commit: &pb.Commit{
+				SeqNo:  s.seqNo,
+				Epoch:  s.epoch,
+				Digest: s.digest,
+			},
+		},
+	})
+}
+
 func (s *sequence) applyPrepareMsg(source NodeID, digest []byte) *Actions {
 	// TODO, if the digest is known, mark a mismatch as oddity
 	agreements := s.prepares[string(digest)]
@@ -22,6 +22,7 @@ type step struct {
 
 type clientReq struct {
 	clientID []byte
+	onCommit func(*CommitReceipt) // If nil, the registered function is unchanged
 	replyC   chan *clientWaiter
 }
 
@@ -44,7 +45,10 @@ type serializer struct {
 	stateMachine *stateMachine
 }
 
-func newSerializer(stateMachine *stateMachine, doneC <-chan struct{}) *serializer {
+// newSerializer starts the serializer go routine.  The initialActions are
+// made available to the consumer before any other event is processed, and are
+// typically used to persist the initial state of the state machine.
+func newSerializer(stateMachine *stateMachine, initialActions *Actions, doneC <-chan struct{}) *serializer {
 	s := &serializer{
 		actionsC:     make(chan Actions),
 		doneC:        doneC,
@@ -57,7 +61,7 @@ func newSerializer(stateMachine *stateMachine, doneC <-chan struct{}) *serialize
 		errC:         make(chan struct{}),
 		stateMachine: stateMachine,
 	}
-	go s.run()
+	go s.run(initialActions)
 	return s
 }
 
@@ -69,7 +73,8 @@ func (s *serializer) getExitErr() error {
 
 // run must be single threaded and is therefore hidden to prevent accidental capture
 // of other go routines.
-func (s *serializer) run() {
+func (s *serializer) run(actions *Actions) {
+	var stateMachineErr error
 	defer func() {
 		s.exitMutex.Lock()
 		defer s.exitMutex.Unlock()
@@ -80,21 +85,26 @@ func (s *serializer) run() {
 			} else {
 				s.exitErr = errors.Errorf("panic in statemachine: %v", r)
 			}
+		} else if stateMachineErr != nil {
+			s.exitErr = errors.WithMessage(stateMachineErr, "state machine stopped")
 		} else {
 			s.exitErr = ErrStopped
 		}
 		s.exitStatus = s.stateMachine.status()
 	}()
 
-	actions := &Actions{}
 	var actionsC chan<- Actions
+	if !actions.IsEmpty() {
+		actionsC = s.actionsC
+	}
+
 	for {
 		select {
 		case data := <-s.propC:
 			// s.stateMachine.myConfig.Logger.Debug("serializer receiving", zap.String("type", "proposal"))
 			actions.Append(s.stateMachine.propose(data))
 		case req := <-s.clientC:
-			req.replyC <- s.stateMachine.clientWaiter(req.clientID)
+			req.replyC <- s.stateMachine.clientWaiter(req.clientID, req.onCommit)
 		case step := <-s.stepC:
 			// s.stateMachine.myConfig.Logger.Debug("serializer receiving", zap.String("type", "step"))
 			actions.Append(s.stateMachine.step(NodeID(step.Source), step.Msg))
@@ -120,6 +130,10 @@ func (s *serializer) run() {
 			return
 		}
 
+		if stateMachineErr = s.stateMachine.err(); stateMachineErr != nil {
+			return
+		}
+
 		// We unconditionally re-enable the actions channel after any event is injected into the system
 		// which will mean some zero-length actions get sent to the consumer.  This isn't optimal,
 		// but, I've convinced myself that's okay for a couple reasons:
@@ -0,0 +1,177 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft
+
+import (
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// signatureData returns the data which the originator of the message signs,
+// along with the signature the message carries.  If the message is not of a
+// type which is signed, ok is false.
+func signatureData(msg *pb.Msg) (data [][]byte, signature []byte, ok bool) {
+	switch innerMsg := msg.Type.(type) {
+	case *pb.Msg_Checkpoint:
+		cp := innerMsg.Checkpoint
+		return [][]byte{
+			[]byte("Checkpoint"),
+			uint64ToBytes(cp.SeqNo),
+			cp.Value,
+		}, cp.Signature, true
+	case *pb.Msg_EpochChange:
+		ec := innerMsg.EpochChange
+		// Note, the hash data does not encode the length of each set, so we
+		// prefix them to prevent ambiguity.
+		data := [][]byte{
+			[]byte("EpochChange"),
+			uint64ToBytes(uint64(len(ec.Checkpoints))),
+			uint64ToBytes(uint64(len(ec.PSet))),
+			uint64ToBytes(uint64(len(ec.QSet))),
+		}
+		return append(data, epochChangeHashData(ec)...), ec.Signature, true
+	case *pb.Msg_NewEpoch:
+		ne := innerMsg.NewEpoch
+		return newEpochSignatureData(ne), ne.Signature, true
+	default:
+		return nil, nil, false
+	}
+}
+
+func newEpochSignatureData(newEpoch *pb.NewEpoch) [][]byte {
+	config := newEpoch.Config
+	data := [][]byte{
+		[]byte("NewEpoch"),
+		uint64ToBytes(config.Number),
+		uint64ToBytes(config.StartingCheckpoint.SeqNo),
+		config.StartingCheckpoint.Value,
+		uint64ToBytes(uint64(len(config.Leaders))),
+	}
+
+	for _, leader := range config.Leaders {
+		data = append(data, uint64ToBytes(leader))
+	}
+
+	data = append(data, uint64ToBytes(uint64(len(config.FinalPreprepares))))
+	data = append(data, config.FinalPreprepares...)
+
+	data = append(data, uint64ToBytes(uint64(len(newEpoch.EpochChanges))))
+	for _, remoteEpochChange := range newEpoch.EpochChanges {
+		data = append(data, uint64ToBytes(remoteEpochChange.NodeId), remoteEpochChange.Digest)
+	}
+
+	return data
+}
+
+// withSignature returns a copy of the message carrying the given signature.
+// The original message is not modified, as it may be referenced elsewhere,
+// for instance in persisted state.
+func withSignature(msg *pb.Msg, signature []byte) *pb.Msg {
+	signed := proto.Clone(msg).(*pb.Msg)
+	switch innerMsg := signed.Type.(type) {
+	case *pb.Msg_Checkpoint:
+		innerMsg.Checkpoint.Signature = signature
+	case *pb.Msg_EpochChange:
+		innerMsg.EpochChange.Signature = signature
+	case *pb.Msg_NewEpoch:
+		innerMsg.NewEpoch.Signature = signature
+	default:
+		panic("programming error, message type is not signed")
+	}
+	return signed
+}
+
+// signOutbound replaces any unsigned broadcast which requires a signature
+// with a request to sign it.  Once signed, the message is broadcast.
+func (sm *stateMachine) signOutbound(actions *Actions) *Actions {
+	if !sm.networkConfig.RequireSignatures {
+		return actions
+	}
+
+	var broadcast []*pb.Msg
+	for _, msg := range actions.Broadcast {
+		data, signature, ok := signatureData(msg)
+		if !ok || len(signature) > 0 {
+			broadcast = append(broadcast, msg)
+			continue
+		}
+
+		actions.Sign = append(actions.Sign, &SignRequest{
+			Data: data,
+			Msg:  msg,
+		})
+	}
+	actions.Broadcast = broadcast
+
+	return actions
+}
+
+// verifyInbound returns a request to verify the signature on the message, or
+// nil if the message requires no verification.
+func (sm *stateMachine) verifyInbound(source NodeID, msg *pb.Msg) *Actions {
+	if !sm.networkConfig.RequireSignatures || source == NodeID(sm.myConfig.ID) {
+		return nil
+	}
+
+	signer := source
+	signed := msg
+	if ack, ok := msg.Type.(*pb.Msg_EpochChangeAck); ok {
+		// The ack embeds the epoch change signed by its originator
+		signer = NodeID(ack.EpochChangeAck.Originator)
+		signed = &pb.Msg{
+			Type: &pb.Msg_EpochChange{
+				EpochChange: ack.EpochChangeAck.EpochChange,
+			},
+		}
+	}
+
+	data, signature, ok := signatureData(signed)
+	if !ok {
+		return nil
+	}
+
+	if len(signature) == 0 {
+		sm.oddities.rejectedMessage(source, msg, errors.Errorf("missing signature from node %d", signer))
+		return &Actions{}
+	}
+
+	return &Actions{
+		Verify: []*VerifySignature{
+			{
+				Source:    uint64(source),
+				Signer:    uint64(signer),
+				Data:      data,
+				Signature: signature,
+				Msg:       msg,
+			},
+		},
+	}
+}
+
+func (sm *stateMachine) applySignResult(result *SignResult) *Actions {
+	signed := withSignature(result.Request.Msg, result.Signature)
+	if cp, ok := signed.Type.(*pb.Msg_Checkpoint); ok {
+		sm.checkpointTracker.applySignature(cp.Checkpoint)
+	}
+
+	return &Actions{
+		Broadcast: []*pb.Msg{signed},
+	}
+}
+
+func (sm *stateMachine) applyVerifySignatureResult(result *VerifySignatureResult) *Actions {
+	request := result.Request
+	if !result.Valid {
+		// Note, we cannot consider this evidence against the source, as
+		// nothing guarantees that the source actually sent the message.
+		sm.oddities.rejectedMessage(NodeID(request.Source), request.Msg, errors.Errorf("invalid signature from node %d", request.Signer))
+		return &Actions{}
+	}
+
+	return sm.ingest(NodeID(request.Source), request.Msg)
+}
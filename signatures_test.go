@@ -0,0 +1,147 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pb "github.com/IBM/mirbft/mirbftpb"
+
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Signatures", func() {
+	var (
+		sm *stateMachine
+	)
+
+	BeforeEach(func() {
+		networkConfig := StandardInitialNetworkConfig(4)
+		networkConfig.RequireSignatures = true
+
+		sm = newGenesisStateMachine(networkConfig, &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+	})
+
+	It("signs checkpoints before broadcasting them", func() {
+		actions := sm.processResults(ActionResults{
+			Checkpoints: []*CheckpointResult{
+				{
+					SeqNo: 20,
+					Value: []byte("cp20"),
+				},
+			},
+		})
+		Expect(actions.Broadcast).To(BeEmpty())
+		Expect(actions.Sign).To(HaveLen(1))
+		signRequest := actions.Sign[0]
+		Expect(signRequest.Data).To(Equal([][]byte{
+			[]byte("Checkpoint"),
+			uint64ToBytes(20),
+			[]byte("cp20"),
+		}))
+
+		actions = sm.processResults(ActionResults{
+			Signatures: []*SignResult{
+				{
+					Request:   signRequest,
+					Signature: []byte("signature"),
+				},
+			},
+		})
+		Expect(actions.Sign).To(BeEmpty())
+		Expect(actions.Broadcast).To(HaveLen(1))
+		Expect(actions.Broadcast[0].Type.(*pb.Msg_Checkpoint).Checkpoint).To(Equal(&pb.Checkpoint{
+			SeqNo:     20,
+			Value:     []byte("cp20"),
+			Signature: []byte("signature"),
+		}))
+		Expect(signRequest.Msg.Type.(*pb.Msg_Checkpoint).Checkpoint.Signature).To(BeNil())
+	})
+
+	It("rejects unsigned messages", func() {
+		actions := sm.step(1, &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{SeqNo: 20, Value: []byte("cp20")},
+			},
+		})
+		Expect(actions.IsEmpty()).To(BeTrue())
+		Expect(sm.oddities.nodes[1].rejected).To(Equal(uint64(1)))
+		Expect(sm.nodeMsgs[1].buffer.Len()).To(BeZero())
+	})
+
+	It("ingests signed messages once verified", func() {
+		msg := &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{SeqNo: 20, Value: []byte("cp20"), Signature: []byte("signature")},
+			},
+		}
+
+		actions := sm.step(1, msg)
+		Expect(actions.Verify).To(HaveLen(1))
+		Expect(actions.Verify[0].Source).To(Equal(uint64(1)))
+		Expect(actions.Verify[0].Signer).To(Equal(uint64(1)))
+		Expect(actions.Verify[0].Signature).To(Equal([]byte("signature")))
+		Expect(sm.nodeMsgs[1].buffer.Len()).To(BeZero())
+
+		sm.processResults(ActionResults{
+			Verifications: []*VerifySignatureResult{
+				{
+					Request: actions.Verify[0],
+					Valid:   true,
+				},
+			},
+		})
+		Expect(sm.nodeMsgs[1].buffer.Len()).To(Equal(1))
+	})
+
+	It("drops messages whose signatures are invalid", func() {
+		actions := sm.step(1, &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{SeqNo: 20, Value: []byte("cp20"), Signature: []byte("forged")},
+			},
+		})
+		Expect(actions.Verify).To(HaveLen(1))
+
+		sm.processResults(ActionResults{
+			Verifications: []*VerifySignatureResult{
+				{
+					Request: actions.Verify[0],
+					Valid:   false,
+				},
+			},
+		})
+		Expect(sm.nodeMsgs[1].buffer.Len()).To(BeZero())
+		Expect(sm.oddities.nodes[1].rejected).To(Equal(uint64(1)))
+	})
+
+	It("verifies the originator's signature on epoch change acks", func() {
+		actions := sm.step(1, &pb.Msg{
+			Type: &pb.Msg_EpochChangeAck{
+				EpochChangeAck: &pb.EpochChangeAck{
+					Originator: 2,
+					EpochChange: &pb.EpochChange{
+						NewEpoch:  1,
+						Signature: []byte("signature"),
+					},
+				},
+			},
+		})
+		Expect(actions.Verify).To(HaveLen(1))
+		Expect(actions.Verify[0].Source).To(Equal(uint64(1)))
+		Expect(actions.Verify[0].Signer).To(Equal(uint64(2)))
+	})
+})
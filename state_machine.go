@@ -8,8 +8,12 @@ package mirbft
 
 import (
 	"bytes"
+	"fmt"
+	"sort"
 
 	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
 
 	"go.uber.org/zap"
 )
@@ -25,53 +29,116 @@ type stateMachine struct {
 	checkpointTracker *checkpointTracker
 	epochChanger      *epochChanger
 	persisted         *persisted
+	oddities          *oddities
+
+	// pendingNetworkConfig is set when a checkpoint which reconfigures the
+	// network has become stable, and is applied once the current message
+	// has been processed.
+	pendingNetworkConfig *pb.NetworkConfig
 }
 
-func newStateMachine(networkConfig *pb.NetworkConfig, myConfig *Config, persisted *persisted) *stateMachine {
+// newStateMachine constructs the state machine from the persisted state.  The
+// returned actions must be performed before any others, as they persist the
+// epoch change which the state machine will begin broadcasting.
+func newStateMachine(networkConfig *pb.NetworkConfig, myConfig *Config, persisted *persisted) (*stateMachine, *Actions) {
 	oddities := &oddities{
 		logger: myConfig.Logger,
 	}
 
+	checkpointTracker := newCheckpointTracker(persisted.checkpoints, networkConfig, myConfig)
+
 	nodeMsgs := map[NodeID]*nodeMsgs{}
 	clientWindows := &clientWindows{
 		windows:       map[string]*clientWindow{},
+		onCommit:      map[string]func(*CommitReceipt){},
 		networkConfig: networkConfig,
 		myConfig:      myConfig,
-	} // TODO, populate client windows from persisted
+	}
 	for _, id := range networkConfig.Nodes {
-		nodeMsgs[NodeID(id)] = newNodeMsgs(NodeID(id), networkConfig, myConfig, clientWindows, oddities)
+		nodeMsgs[NodeID(id)] = newNodeMsgs(NodeID(id), checkpointTracker.lowWatermark(), networkConfig, myConfig, clientWindows, oddities)
 	}
 
-	checkpointTracker := newCheckpointTracker(persisted.checkpoints, networkConfig, myConfig)
-	batchTracker := newBatchTracker() // TODO, populate batch tracker from persisted
+	batchTracker := newBatchTracker()
+
+	lastActiveEpoch, restarted := restoreFromPersisted(persisted, batchTracker, clientWindows)
 
-	checkpoints := make([]*pb.Checkpoint, len(persisted.checkpoints))
-	i := 0
-	for _, cp := range persisted.checkpoints {
-		checkpoints[i] = cp
-		i++
+	if nEntry := persisted.lastNEntry; nEntry != nil && nEntry.Ready && (!restarted || nEntry.Config.Number > lastActiveEpoch) {
+		// We sent a NewEpochReady for this epoch, so we must consider it active
+		// even if we never persisted any entries within it.
+		lastActiveEpoch = nEntry.Config.Number
+		restarted = true
 	}
-	// TODO, sort checkpoints?
-	epochChange, err := newParsedEpochChange(&pb.EpochChange{
-		Checkpoints: checkpoints,
-	})
-	if err != nil {
-		panic(err)
+
+	var lastActiveConfig *epochConfig
+	if nEntry := persisted.lastNEntry; nEntry != nil && nEntry.Ready && nEntry.Config.Number == lastActiveEpoch {
+		lastActiveConfig = parseEpochConfig(networkConfig, nEntry.Config)
 	}
 
 	epochChanger := &epochChanger{
+		lastActiveEpoch:   lastActiveEpoch,
+		lastActiveConfig:  lastActiveConfig,
 		persisted:         persisted,
 		myConfig:          myConfig,
+		oddities:          oddities,
 		networkConfig:     networkConfig,
 		targets:           map[uint64]*epochTarget{},
+		suspicions:        map[NodeID]uint64{},
+		futureTargets:     map[NodeID]uint64{},
 		batchTracker:      batchTracker,
 		clientWindows:     clientWindows,
 		checkpointTracker: checkpointTracker,
 	}
 
-	target := epochChanger.target(0)
-	target.myEpochChange = epochChange
-	target.myLeaderChoice = networkConfig.Nodes
+	actions := &Actions{}
+	var target *epochTarget
+	var epochChange *pb.EpochChange
+	switch lastEpochChange := persisted.lastEpochChange; {
+	case lastEpochChange != nil && (!restarted || lastEpochChange.NewEpoch > lastActiveEpoch):
+		// We crashed during an epoch change, we must resume it with the
+		// very same epoch change we sent before.
+		target = epochChanger.target(lastEpochChange.NewEpoch)
+		epochChange = lastEpochChange
+		if nEntry := persisted.lastNEntry; nEntry != nil && nEntry.Config.Number == target.number {
+			target.myEchoConfig = nEntry.Config
+		}
+	case restarted:
+		// We were active in some epoch before, but we have no way of knowing
+		// whether that epoch is still active, so, we attempt to join the next one.
+		target = epochChanger.target(lastActiveEpoch + 1)
+		epochChange = persisted.constructEpochChange(lastActiveEpoch+1, lastActiveConfig, checkpointTracker)
+	default:
+		checkpoints := make([]*pb.Checkpoint, 0, len(persisted.checkpoints))
+		for _, cp := range persisted.checkpoints {
+			checkpoints = append(checkpoints, &pb.Checkpoint{
+				SeqNo: cp.SeqNo,
+				Value: cp.Value,
+			})
+		}
+		sort.Slice(checkpoints, func(i, j int) bool {
+			return checkpoints[i].SeqNo < checkpoints[j].SeqNo
+		})
+		target = epochChanger.target(0)
+		epochChange = &pb.EpochChange{
+			Checkpoints: checkpoints,
+		}
+	}
+
+	if epochChange != persisted.lastEpochChange {
+		actions.Append(persisted.add(&pb.Persisted{
+			Type: &pb.Persisted_Ecentry{
+				Ecentry: &pb.ECEntry{
+					EpochChange: epochChange,
+				},
+			},
+		}))
+	}
+
+	parsedEpochChange, err := newParsedEpochChange(epochChange)
+	if err != nil {
+		panic(err)
+	}
+
+	target.myEpochChange = parsedEpochChange
 	epochChanger.pendingEpochTarget = target
 
 	return &stateMachine{
@@ -83,7 +150,113 @@ func newStateMachine(networkConfig *pb.NetworkConfig, myConfig *Config, persiste
 		nodeMsgs:          nodeMsgs,
 		clientWindows:     clientWindows,
 		persisted:         persisted,
+		oddities:          oddities,
+	}, actions
+}
+
+// restoreFromPersisted populates the batch tracker and client windows from
+// the QEntries and PEntries which survived a restart.  It returns the highest
+// epoch referenced by any entry, and whether any such entry existed.
+func restoreFromPersisted(persisted *persisted, batchTracker *batchTracker, clientWindows *clientWindows) (uint64, bool) {
+	seqNos := make([]uint64, 0, len(persisted.qSet))
+	for seqNo := range persisted.qSet {
+		seqNos = append(seqNos, seqNo)
+	}
+	sort.Slice(seqNos, func(i, j int) bool {
+		return seqNos[i] < seqNos[j]
+	})
+
+	var lastActiveEpoch uint64
+	restarted := false
+
+	for _, pEntry := range persisted.pSet {
+		restarted = true
+		if pEntry.Epoch > lastActiveEpoch {
+			lastActiveEpoch = pEntry.Epoch
+		}
+	}
+
+	type reqNoRange struct {
+		low, high uint64
+	}
+	reqNoRanges := map[string]*reqNoRange{}
+	var clientIDs [][]byte
+
+	for _, seqNo := range seqNos {
+		for epoch, qEntry := range persisted.qSet[seqNo] {
+			restarted = true
+			if epoch > lastActiveEpoch {
+				lastActiveEpoch = epoch
+			}
+
+			requestAcks := make([]*pb.RequestAck, len(qEntry.Requests))
+			for i, fr := range qEntry.Requests {
+				requestAcks[i] = &pb.RequestAck{
+					ClientId: fr.Request.ClientId,
+					ReqNo:    fr.Request.ReqNo,
+					Digest:   fr.Digest,
+				}
+
+				r, ok := reqNoRanges[string(fr.Request.ClientId)]
+				if !ok {
+					reqNoRanges[string(fr.Request.ClientId)] = &reqNoRange{
+						low:  fr.Request.ReqNo,
+						high: fr.Request.ReqNo,
+					}
+					clientIDs = append(clientIDs, fr.Request.ClientId)
+					continue
+				}
+
+				if fr.Request.ReqNo < r.low {
+					r.low = fr.Request.ReqNo
+				}
+
+				if fr.Request.ReqNo > r.high {
+					r.high = fr.Request.ReqNo
+				}
+			}
+
+			batchTracker.addBatch(seqNo, qEntry.Digest, requestAcks)
+		}
+	}
+
+	for _, clientID := range clientIDs {
+		r := reqNoRanges[string(clientID)]
+		highWatermark := r.low + clientWindows.networkConfig.ClientWindowWidth - 1
+		if r.high > highWatermark {
+			highWatermark = r.high
+		}
+		clientWindows.insert(clientID, newClientWindow(r.low, highWatermark, clientWindows.networkConfig, clientWindows.myConfig))
+	}
+
+	for _, seqNo := range seqNos {
+		var committedEpoch *uint64
+		if pEntry, ok := persisted.pSet[seqNo]; ok && seqNo <= persisted.lastCommitted {
+			committedEpoch = &pEntry.Epoch
+		}
+
+		for epoch, qEntry := range persisted.qSet[seqNo] {
+			for _, fr := range qEntry.Requests {
+				cw, _ := clientWindows.clientWindow(fr.Request.ClientId)
+				cw.allocate(fr.Request, fr.Digest)
+				cr := cw.request(fr.Request.ReqNo)
+				// Any request which was preprepared has already been acknowledged
+				// by a quorum of the network, so it is safe to consider it strong.
+				cr.strongRequest = cr.digests[string(fr.Digest)]
+				if committedEpoch != nil && *committedEpoch == epoch {
+					committedSeqNo := seqNo
+					cr.committed = &committedSeqNo
+				}
+			}
+		}
+	}
+
+	cwi := clientWindows.iterator()
+	for _, cw := cwi.next(); cw != nil; _, cw = cwi.next() {
+		cw.garbageCollect(persisted.lastCommitted)
 	}
+
+	return lastActiveEpoch, restarted
 }
 
 func (sm *stateMachine) propose(requestData *pb.Request) *Actions {
@@ -107,22 +280,56 @@ func (sm *stateMachine) propose(requestData *pb.Request) *Actions {
 }
 
 func (sm *stateMachine) step(source NodeID, outerMsg *pb.Msg) *Actions {
+	if actions := sm.verifyInbound(source, outerMsg); actions != nil {
+		// The message is ingested once its signature is verified
+		return actions
+	}
+
+	actions := sm.ingest(source, outerMsg)
+	actions.Append(sm.drainNodeMsgs())
+	return sm.signOutbound(actions)
+}
+
+// ingest buffers the message for processing, the caller must subsequently
+// drain the node messages.
+func (sm *stateMachine) ingest(source NodeID, outerMsg *pb.Msg) *Actions {
 	nodeMsgs, ok := sm.nodeMsgs[source]
 	if !ok {
-		sm.myConfig.Logger.Panic("received a message from a node ID that does not exist", zap.Int("source", int(source)))
+		// This may be a node which was removed by a reconfiguration, or one
+		// which has been added, but which we do not know about yet.
+		sm.myConfig.Logger.Warn("dropping message from a node ID that does not exist", zap.Int("source", int(source)))
+		return &Actions{}
+	}
+
+	actions := &Actions{}
+
+	if msg, ok := outerMsg.Type.(*pb.Msg_Checkpoint); ok && msg.Checkpoint.SeqNo > sm.checkpointTracker.highWatermark() {
+		// This checkpoint will not be processed until we have caught up, which
+		// we may never do unless we transfer state.
+		actions.Append(sm.applyFutureCheckpointMsg(source, msg.Checkpoint.SeqNo, msg.Checkpoint.Value))
 	}
 
 	nodeMsgs.ingest(outerMsg)
 
-	return sm.drainNodeMsgs()
+	return actions
 }
 
 func (sm *stateMachine) drainNodeMsgs() *Actions {
 	actions := &Actions{}
 
 	for {
+		if sm.pendingNetworkConfig != nil {
+			actions.Append(sm.reconfigure())
+		}
+
 		moreActions := false
 		for source, nodeMsgs := range sm.nodeMsgs {
+			if sm.pendingNetworkConfig != nil {
+				// The node messages will be replaced by the reconfiguration
+				moreActions = true
+				break
+			}
+
 			msg := nodeMsgs.next()
 			if msg == nil {
 				continue
@@ -138,19 +345,29 @@ func (sm *stateMachine) drainNodeMsgs() *Actions {
 				actions.Append(sm.activeEpoch.applyPrepareMsg(source, msg.SeqNo, msg.Digest))
 			case *pb.Msg_Commit:
 				msg := innerMsg.Commit
-				actions.Append(sm.activeEpoch.applyCommitMsg(source, msg.SeqNo, msg.Digest))
+				actions.Append(sm.applyCommitMsg(source, msg.SeqNo, msg.Digest))
 			case *pb.Msg_Checkpoint:
 				msg := innerMsg.Checkpoint
 				actions.Append(sm.checkpointMsg(source, msg.SeqNo, msg.Value))
 			case *pb.Msg_RequestAck:
 				msg := innerMsg.RequestAck
 				actions.Append(sm.applyRequestAckMsg(source, msg.ClientId, msg.ReqNo, msg.Digest))
+			case *pb.Msg_FetchRequest:
+				msg := innerMsg.FetchRequest
+				actions.Append(sm.clientWindows.replyFetchRequest(source, msg.ClientId, msg.ReqNo, msg.Digest))
 			case *pb.Msg_FetchBatch:
 				msg := innerMsg.FetchBatch
 				actions.Append(sm.batchTracker.replyFetchBatch(msg.SeqNo, msg.Digest))
 			case *pb.Msg_ForwardBatch:
 				msg := innerMsg.ForwardBatch
 				actions.Append(sm.batchTracker.applyForwardBatchMsg(source, msg.SeqNo, msg.Digest, msg.RequestAcks))
+			case *pb.Msg_FetchSequenceState:
+				if source == NodeID(sm.myConfig.ID) {
+					// We cannot have lost our own messages
+					continue
+				}
+				msg := innerMsg.FetchSequenceState
+				actions.Append(sm.activeEpoch.applyFetchSequenceStateMsg(source, msg.SeqNo))
 			case *pb.Msg_ForwardRequest:
 				if source == NodeID(sm.myConfig.ID) {
 					// We've already pre-processed this
@@ -162,9 +379,11 @@ func (sm *stateMachine) drainNodeMsgs() *Actions {
 					// TODO, make sure that we only allow one vote per replica for a reqno
 					if cr := cw.request(msg.Request.ReqNo); cr != nil {
 						req, ok := cr.digests[string(msg.Digest)]
-						req.agreements[source] = struct{}{}
-						if ok && req.data != nil {
-							continue
+						if ok {
+							req.agreements[source] = struct{}{}
+							if req.data != nil {
+								continue
+							}
 						}
 					}
 				}
@@ -182,13 +401,13 @@ func (sm *stateMachine) drainNodeMsgs() *Actions {
 					},
 				})
 			case *pb.Msg_Suspect:
-				sm.applySuspectMsg(source, innerMsg.Suspect.Epoch)
+				actions.Append(sm.applySuspectMsg(source, innerMsg.Suspect.Epoch))
 			case *pb.Msg_EpochChange:
 				actions.Append(sm.epochChanger.applyEpochChangeMsg(source, innerMsg.EpochChange))
 			case *pb.Msg_EpochChangeAck:
 				actions.Append(sm.epochChanger.applyEpochChangeAckMsg(source, innerMsg.EpochChangeAck))
 			case *pb.Msg_NewEpoch:
-				actions.Append(sm.epochChanger.applyNewEpochMsg(innerMsg.NewEpoch))
+				actions.Append(sm.epochChanger.applyNewEpochMsg(source, innerMsg.NewEpoch))
 			case *pb.Msg_NewEpochEcho:
 				actions.Append(sm.epochChanger.applyNewEpochEchoMsg(source, innerMsg.NewEpochEcho))
 			case *pb.Msg_NewEpochReady:
@@ -209,6 +428,14 @@ func (sm *stateMachine) applyPreprepareMsg(source NodeID, msg *pb.Preprepare) *A
 	return sm.activeEpoch.applyPreprepareMsg(source, msg.SeqNo, msg.Batch)
 }
 
+func (sm *stateMachine) applyCommitMsg(source NodeID, seqNo uint64, digest []byte) *Actions {
+	actions := sm.activeEpoch.applyCommitMsg(source, seqNo, digest)
+	if len(actions.Commits) > 0 {
+		sm.epochChanger.epochCommitted()
+	}
+	return actions
+}
+
 func (sm *stateMachine) applySuspectMsg(source NodeID, epoch uint64) *Actions {
 	epochChange := sm.epochChanger.applySuspectMsg(source, epoch)
 	if epochChange == nil {
@@ -220,28 +447,36 @@ func (sm *stateMachine) applySuspectMsg(source NodeID, epoch uint64) *Actions {
 	}
 	sm.activeEpoch = nil
 
-	return &Actions{
-		Broadcast: []*pb.Msg{
-			{
-				Type: &pb.Msg_EpochChange{
-					EpochChange: epochChange,
-				},
+	// Note, as the persisted entries are written before any messages are
+	// sent, the epoch change may be broadcast along with its entry.
+	actions := sm.persisted.add(&pb.Persisted{
+		Type: &pb.Persisted_Ecentry{
+			Ecentry: &pb.ECEntry{
+				EpochChange: epochChange,
 			},
 		},
-	}
+	})
+	actions.Append(sm.epochChanger.pendingEpochTarget.repeatEpochChangeBroadcast())
+
+	return actions
 }
 
 func (sm *stateMachine) applyNewEpochReadyMsg(source NodeID, msg *pb.NewEpochReady) *Actions {
 	actions := sm.epochChanger.applyNewEpochReadyMsg(source, msg)
+	actions.Append(sm.activatePendingEpoch())
+	return actions
+}
 
+// activatePendingEpoch begins the pending epoch, if it is ready.
+func (sm *stateMachine) activatePendingEpoch() *Actions {
 	if sm.epochChanger.pendingEpochTarget.state != ready {
-		return actions
+		return &Actions{}
 	}
 
 	sm.activeEpoch = newEpoch(sm.persisted, sm.epochChanger.pendingEpochTarget.networkNewEpoch, sm.checkpointTracker, sm.clientWindows, sm.networkConfig, sm.myConfig)
-	actions.Append(sm.activeEpoch.drainProposer())
+	actions := sm.activeEpoch.drainProposer()
 	sm.epochChanger.pendingEpochTarget.state = idle
-	sm.epochChanger.lastActiveEpoch = sm.epochChanger.pendingEpochTarget.number
+	sm.epochChanger.setLastActiveConfig(sm.activeEpoch.config)
 	for _, nodeMsgs := range sm.nodeMsgs {
 		nodeMsgs.setActiveEpoch(sm.activeEpoch)
 	}
@@ -249,9 +484,35 @@ func (sm *stateMachine) applyNewEpochReadyMsg(source NodeID, msg *pb.NewEpochRea
 	return actions
 }
 
+func (sm *stateMachine) applyFutureCheckpointMsg(source NodeID, seqNo uint64, value []byte) *Actions {
+	target := sm.checkpointTracker.applyFutureCheckpointMsg(source, seqNo, value)
+	if target == nil || target.SeqNo <= sm.persisted.lastCommitted {
+		return &Actions{}
+	}
+
+	return sm.checkpointTracker.requestStateTransfer(target)
+}
+
 func (sm *stateMachine) checkpointMsg(source NodeID, seqNo uint64, value []byte) *Actions {
 	if !sm.checkpointTracker.applyCheckpointMsg(source, seqNo, value) {
-		return &Actions{}
+		cp := sm.checkpointTracker.checkpoint(seqNo)
+		if !cp.diverged() {
+			return &Actions{}
+		}
+
+		stableValue := cp.networkStableValue()
+		if stableValue == nil {
+			return &Actions{}
+		}
+
+		actions := sm.checkpointTracker.requestStateTransfer(&StateTarget{
+			SeqNo: seqNo,
+			Value: stableValue,
+		})
+		if actions.StateTransfer != nil {
+			sm.oddities.divergedCheckpoint(seqNo, cp.myValue, stableValue)
+		}
+		return actions
 	}
 
 	cwi := sm.clientWindows.iterator()
@@ -267,7 +528,27 @@ func (sm *stateMachine) checkpointMsg(source NodeID, seqNo uint64, value []byte)
 	}
 	sm.persisted.truncate(seqNo)
 	sm.checkpointTracker.truncate(seqNo)
+
+	if cp := sm.checkpointTracker.checkpoint(seqNo); cp.stable && cp.nextNetworkConfig != nil && !proto.Equal(cp.nextNetworkConfig, sm.networkConfig) {
+		// The active epoch ends here, the reconfiguration is applied
+		// as soon as we return to draining the node messages.
+		sm.pendingNetworkConfig = cp.nextNetworkConfig
+		return &Actions{Truncate: seqNo}
+	}
+
 	actions := sm.activeEpoch.moveWatermarks()
+	actions.Truncate = seqNo
+
+	if cp := sm.checkpointTracker.checkpoint(seqNo); cp.stable && sm.activeEpoch.ending && seqNo == sm.activeEpoch.config.plannedExpiration {
+		// The active epoch has ended gracefully, so we may begin the next
+		// one without waiting for anyone to suspect it.
+		actions.Append(sm.epochChanger.beginGracefulEpochChange(sm.activeEpoch.config, &pb.Checkpoint{
+			SeqNo: seqNo,
+			Value: cp.myValue,
+		}))
+		actions.Append(sm.activatePendingEpoch())
+	}
+
 	actions.Append(sm.drainNodeMsgs())
 	return actions
 }
@@ -275,16 +556,34 @@ func (sm *stateMachine) checkpointMsg(source NodeID, seqNo uint64, value []byte)
 func (sm *stateMachine) processResults(results ActionResults) *Actions {
 	actions := &Actions{}
 
+	if results.StateTransfer != nil {
+		actions.Append(sm.applyStateTransferResult(results.StateTransfer))
+	}
+
+	for _, signResult := range results.Signatures {
+		actions.Append(sm.applySignResult(signResult))
+	}
+
+	for _, verifyResult := range results.Verifications {
+		actions.Append(sm.applyVerifySignatureResult(verifyResult))
+	}
+
 	for _, checkpointResult := range results.Checkpoints {
+		if checkpointResult.SeqNo <= sm.checkpointTracker.lowWatermark() {
+			// We have already transferred state beyond this checkpoint
+			continue
+		}
+
 		// sm.myConfig.Logger.Debug("applying checkpoint result", zap.Int("index", i))
-		actions.Append(sm.checkpointTracker.applyCheckpointResult(checkpointResult.SeqNo, checkpointResult.Value))
+		actions.Append(sm.checkpointTracker.applyCheckpointResult(checkpointResult.SeqNo, checkpointResult.Value, checkpointResult.NetworkConfig))
 		// TODO, maybe push this into the checkpoint tracker?
-		sm.persisted.add(&pb.Persisted{Type: &pb.Persisted_Checkpoint{
+		actions.Append(sm.persisted.add(&pb.Persisted{Type: &pb.Persisted_Checkpoint{
 			Checkpoint: &pb.Checkpoint{
-				SeqNo: checkpointResult.SeqNo,
-				Value: checkpointResult.Value,
+				SeqNo:         checkpointResult.SeqNo,
+				Value:         checkpointResult.Value,
+				NetworkConfig: checkpointResult.NetworkConfig,
 			},
-		}})
+		}}))
 	}
 
 	for _, hashResult := range results.Digests {
@@ -294,7 +593,7 @@ func (sm *stateMachine) processResults(results ActionResults) *Actions {
 			batch := request.Batch
 			sm.batchTracker.addBatch(batch.SeqNo, hashResult.Digest, batch.RequestAcks)
 
-			if sm.activeEpoch != nil && batch.Epoch != sm.activeEpoch.config.number {
+			if sm.activeEpoch == nil || batch.Epoch != sm.activeEpoch.config.number {
 				continue
 			}
 
@@ -304,21 +603,23 @@ func (sm *stateMachine) processResults(results ActionResults) *Actions {
 			actions.Append(sm.activeEpoch.applyProcessResult(seqNo, hashResult.Digest))
 		case request.Request != nil:
 			request := request.Request
-			actions.Broadcast = append(actions.Broadcast, &pb.Msg{
-				Type: &pb.Msg_RequestAck{
-					RequestAck: &pb.RequestAck{
-						ClientId: request.Request.ClientId,
-						ReqNo:    request.Request.ReqNo,
-						Digest:   hashResult.Digest,
-					},
-				},
+			actions.Validate = append(actions.Validate, &ValidateRequest{
+				Source:  request.Source,
+				Request: request.Request,
+				Digest:  hashResult.Digest,
 			})
-			actions.Append(sm.applyDigestedValidRequest(hashResult.Digest, request.Request))
 		case request.VerifyRequest != nil:
 			request := request.VerifyRequest
 			if !bytes.Equal(request.ExpectedDigest, hashResult.Digest) {
-				panic("byzantine")
-				// XXX this should not panic, but put to make dev easier
+				actions.Append(sm.oddities.evidence(NodeID(request.Source), &pb.Msg{
+					Type: &pb.Msg_ForwardRequest{
+						ForwardRequest: &pb.ForwardRequest{
+							Request: request.Request,
+							Digest:  request.ExpectedDigest,
+						},
+					},
+				}, fmt.Sprintf("forwarded request hashes to %x, not the claimed %x", hashResult.Digest, request.ExpectedDigest)))
+				continue
 			}
 			actions.Append(sm.applyDigestedValidRequest(hashResult.Digest, request.Request))
 			if sm.epochChanger.pendingEpochTarget.state == fetching {
@@ -329,7 +630,18 @@ func (sm *stateMachine) processResults(results ActionResults) *Actions {
 			actions.Append(sm.epochChanger.applyEpochChangeDigest(epochChange, hashResult.Digest))
 		case request.VerifyBatch != nil:
 			verifyBatch := request.VerifyBatch
-			sm.batchTracker.applyVerifyBatchHashResult(hashResult.Digest, verifyBatch)
+			if err := sm.batchTracker.applyVerifyBatchHashResult(hashResult.Digest, verifyBatch); err != nil {
+				actions.Append(sm.oddities.evidence(NodeID(verifyBatch.Source), &pb.Msg{
+					Type: &pb.Msg_ForwardBatch{
+						ForwardBatch: &pb.ForwardBatch{
+							SeqNo:       verifyBatch.SeqNo,
+							Digest:      verifyBatch.ExpectedDigest,
+							RequestAcks: verifyBatch.RequestAcks,
+						},
+					},
+				}, err.Error()))
+				continue
+			}
 			if !sm.batchTracker.hasFetchInFlight() && sm.epochChanger.pendingEpochTarget.state == fetching {
 				actions.Append(sm.epochChanger.pendingEpochTarget.fetchNewEpochState())
 			}
@@ -338,8 +650,215 @@ func (sm *stateMachine) processResults(results ActionResults) *Actions {
 		}
 	}
 
+	for _, validateResult := range results.Validations {
+		actions.Append(sm.applyValidateResult(validateResult))
+	}
+
 	actions.Append(sm.drainNodeMsgs())
 
+	return sm.signOutbound(actions)
+}
+
+// applyValidateResult acknowledges a client request once the application has
+// validated it.  Invalid requests are dropped, so that they can only become
+// strong if enough other nodes acknowledge them.
+func (sm *stateMachine) applyValidateResult(result *ValidateResult) *Actions {
+	request := result.Request
+	if !result.Valid {
+		sm.myConfig.Logger.Debug("dropping client request which failed validation",
+			zap.Binary("ClientID", request.Request.ClientId),
+			zap.Uint64("ReqNo", request.Request.ReqNo),
+		)
+		return &Actions{}
+	}
+
+	actions := &Actions{
+		Broadcast: []*pb.Msg{
+			{
+				Type: &pb.Msg_RequestAck{
+					RequestAck: &pb.RequestAck{
+						ClientId: request.Request.ClientId,
+						ReqNo:    request.Request.ReqNo,
+						Digest:   request.Digest,
+					},
+				},
+			},
+		},
+	}
+	actions.Append(sm.applyDigestedValidRequest(request.Digest, request.Request))
+	return actions
+}
+
+// applyStateTransferResult moves the state machine to the checkpoint reached by
+// state transfer, discarding any state which precedes it.
+func (sm *stateMachine) applyStateTransferResult(result *StateTransferResult) *Actions {
+	target := sm.checkpointTracker.stateTransfer
+	if target == nil || result.Target == nil || target.SeqNo != result.Target.SeqNo || !bytes.Equal(target.Value, result.Target.Value) {
+		// This result does not correspond to the state transfer in progress
+		return &Actions{}
+	}
+
+	seqNo := target.SeqNo
+
+	sm.checkpointTracker.applyStateTransfer(target)
+
+	sm.persisted.lastCommitted = seqNo
+	actions := sm.persisted.add(&pb.Persisted{Type: &pb.Persisted_Checkpoint{
+		Checkpoint: &pb.Checkpoint{
+			SeqNo:         seqNo,
+			Value:         target.Value,
+			NetworkConfig: result.NetworkConfig,
+		},
+	}})
+	sm.persisted.truncate(seqNo)
+	actions.Truncate = seqNo
+	sm.batchTracker.truncate(seqNo)
+
+	transferredClients := map[string]struct{}{}
+	for _, clientWatermark := range result.ClientWatermarks {
+		transferredClients[string(clientWatermark.ClientID)] = struct{}{}
+		sm.clientWindows.reinitialize(clientWatermark.ClientID, clientWatermark.LowWatermark)
+	}
+
+	cwi := sm.clientWindows.iterator()
+	for clientID, cw := cwi.next(); cw != nil; clientID, cw = cwi.next() {
+		if _, ok := transferredClients[string(clientID)]; ok {
+			continue
+		}
+		cw.garbageCollect(seqNo)
+	}
+
+	for _, nodeMsgs := range sm.nodeMsgs {
+		nodeMsgs.moveLowWatermark(seqNo)
+	}
+
+	if result.NetworkConfig != nil && !proto.Equal(result.NetworkConfig, sm.networkConfig) {
+		// The transferred state reconfigured the network, so the active epoch
+		// may not be resumed, and we reconfigure before anything else is
+		// applied under the old config.
+		sm.activeEpoch = nil
+		sm.pendingNetworkConfig = result.NetworkConfig
+		actions.Append(sm.reconfigure())
+		return actions
+	}
+
+	if sm.activeEpoch != nil {
+		oldConfig := sm.activeEpoch.config
+		sm.activeEpoch = nil
+
+		// If the bucket assignment is unchanged by restarting the epoch at the new
+		// checkpoint, we may resume participating in it, otherwise we must wait
+		// for the next epoch change.
+		if seqNo < oldConfig.plannedExpiration && (seqNo+1-oldConfig.initialSequence)%uint64(len(oldConfig.buckets)) == 0 {
+			sm.activeEpoch = newEpoch(sm.persisted, &pb.EpochConfig{
+				Number: oldConfig.number,
+				StartingCheckpoint: &pb.Checkpoint{
+					SeqNo: seqNo,
+					Value: target.Value,
+				},
+				Leaders: oldConfig.leaders,
+			}, sm.checkpointTracker, sm.clientWindows, sm.networkConfig, sm.myConfig)
+			sm.activeEpoch.config.plannedExpiration = oldConfig.plannedExpiration
+			actions.Append(sm.activeEpoch.drainProposer())
+		}
+
+		for _, nodeMsgs := range sm.nodeMsgs {
+			nodeMsgs.setActiveEpoch(sm.activeEpoch)
+		}
+	}
+
+	if sm.epochChanger.pendingEpochTarget.state == fetching {
+		actions.Append(sm.epochChanger.pendingEpochTarget.fetchNewEpochState())
+	}
+
+	return actions
+}
+
+// reconfigure replaces the network config with the pending one.  Much like a
+// restart, every component whose state depends on the network config is rebuilt
+// from the persisted state, and the node begins an epoch change into the first
+// epoch under the new config.  The client windows and batches are retained, as
+// the reconfiguration does not change which requests have committed, as are the
+// checkpoints beyond the watermarks and any state transfer in progress.
+func (sm *stateMachine) reconfigure() *Actions {
+	networkConfig := sm.pendingNetworkConfig
+	sm.pendingNetworkConfig = nil
+
+	// Note, the reconfiguring checkpoint was persisted along with this config,
+	// so should we crash before this entry is persisted, we restart with it.
+	actions := sm.persisted.add(&pb.Persisted{
+		Type: &pb.Persisted_NetworkConfig{
+			NetworkConfig: networkConfig,
+		},
+	})
+	actions.Replicas = replicas(networkConfig)
+
+	sm.clientWindows.networkConfig = networkConfig
+	cwi := sm.clientWindows.iterator()
+	for _, cw := cwi.next(); cw != nil; _, cw = cwi.next() {
+		// The windows were already collected at the reconfiguring checkpoint,
+		// so this only resizes them to the new client window width.
+		cw.networkConfig = networkConfig
+		cw.garbageCollect(sm.checkpointTracker.lowWatermark())
+	}
+
+	checkpointTracker := newCheckpointTracker(sm.persisted.checkpoints, networkConfig, sm.myConfig)
+	checkpointTracker.retain(sm.checkpointTracker)
+
+	nodeMsgs := map[NodeID]*nodeMsgs{}
+	for _, id := range networkConfig.Nodes {
+		nodeMsgs[NodeID(id)] = newNodeMsgs(NodeID(id), checkpointTracker.lowWatermark(), networkConfig, sm.myConfig, sm.clientWindows, sm.oddities)
+		if oldNodeMsgs, ok := sm.nodeMsgs[NodeID(id)]; ok {
+			// Retain any messages the node has already sent us for the next epoch
+			nodeMsgs[NodeID(id)].buffer = oldNodeMsgs.buffer
+		}
+	}
+
+	epochChanger := &epochChanger{
+		lastActiveEpoch:   sm.epochChanger.lastActiveEpoch,
+		persisted:         sm.persisted,
+		myConfig:          sm.myConfig,
+		oddities:          sm.oddities,
+		networkConfig:     networkConfig,
+		targets:           map[uint64]*epochTarget{},
+		suspicions:        map[NodeID]uint64{},
+		futureTargets:     map[NodeID]uint64{},
+		batchTracker:      sm.batchTracker,
+		clientWindows:     sm.clientWindows,
+		checkpointTracker: checkpointTracker,
+	}
+
+	epochNumber := epochChanger.lastActiveEpoch + 1
+	if lastEpochChange := sm.persisted.lastEpochChange; lastEpochChange != nil && lastEpochChange.NewEpoch >= epochNumber {
+		// We have already sent an epoch change under the old config,
+		// so we must move beyond it.
+		epochNumber = lastEpochChange.NewEpoch + 1
+	}
+
+	epochChange := sm.persisted.constructEpochChange(epochNumber, nil, checkpointTracker)
+	actions.Append(sm.persisted.add(&pb.Persisted{
+		Type: &pb.Persisted_Ecentry{
+			Ecentry: &pb.ECEntry{
+				EpochChange: epochChange,
+			},
+		},
+	}))
+
+	parsedEpochChange, err := newParsedEpochChange(epochChange)
+	if err != nil {
+		panic(errors.WithMessage(err, "could not parse the epoch change I generated"))
+	}
+
+	target := epochChanger.target(epochNumber)
+	target.myEpochChange = parsedEpochChange
+	epochChanger.pendingEpochTarget = target
+
+	sm.networkConfig = networkConfig
+	sm.checkpointTracker = checkpointTracker
+	sm.nodeMsgs = nodeMsgs
+	sm.epochChanger = epochChanger
+	sm.activeEpoch = nil
+
 	return actions
 }
 
@@ -347,11 +866,24 @@ func (sm *stateMachine) applyRequestAckMsg(source NodeID, clientID []byte, reqNo
 	// TODO, we need to prevent DoS down this avenue by pre-filtering these
 	clientWindow, ok := sm.clientWindows.clientWindow(clientID)
 	if !ok {
-		clientWindow = newClientWindow(1, 100, sm.networkConfig, sm.myConfig) // XXX this should be configurable
+		clientWindow = sm.clientWindows.newWindow(1)
 		sm.clientWindows.insert(clientID, clientWindow)
 	}
 
-	clientWindow.ack(source, reqNo, digest)
+	if err := clientWindow.ack(source, reqNo, digest); err != nil {
+		// Note, this is not necessarily byzantine, the sender's view of the
+		// client window may simply differ from ours.
+		sm.oddities.rejectedMessage(source, &pb.Msg{
+			Type: &pb.Msg_RequestAck{
+				RequestAck: &pb.RequestAck{
+					ClientId: clientID,
+					ReqNo:    reqNo,
+					Digest:   digest,
+				},
+			},
+		}, err)
+		return &Actions{}
+	}
 
 	if sm.activeEpoch == nil {
 		return &Actions{}
@@ -365,7 +897,7 @@ func (sm *stateMachine) applyDigestedValidRequest(digest []byte, requestData *pb
 	clientID := requestData.ClientId
 	clientWindow, ok := sm.clientWindows.clientWindow(clientID)
 	if !ok {
-		clientWindow = newClientWindow(1, 100, sm.networkConfig, sm.myConfig) // XXX this should be configurable
+		clientWindow = sm.clientWindows.newWindow(1)
 		sm.clientWindows.insert(clientID, clientWindow)
 	}
 
@@ -379,28 +911,84 @@ func (sm *stateMachine) applyDigestedValidRequest(digest []byte, requestData *pb
 	return sm.activeEpoch.drainProposer()
 }
 
-func (sm *stateMachine) clientWaiter(clientID []byte) *clientWaiter {
+func (sm *stateMachine) clientWaiter(clientID []byte, onCommit func(*CommitReceipt)) *clientWaiter {
 	clientWindow, ok := sm.clientWindows.clientWindow(clientID)
 	if !ok {
-		clientWindow = newClientWindow(1, 100, sm.networkConfig, sm.myConfig) // XXX this should be configurable
+		clientWindow = sm.clientWindows.newWindow(1)
 		sm.clientWindows.insert(clientID, clientWindow)
 	}
 
+	if onCommit != nil {
+		sm.clientWindows.onCommit[string(clientID)] = onCommit
+	}
+
 	return clientWindow.clientWaiter
 }
 
 func (sm *stateMachine) tick() *Actions {
 	actions := &Actions{}
 
-	if sm.activeEpoch != nil {
+	if sm.activeEpoch != nil && sm.epochChanger.pendingEpochTarget.number == sm.activeEpoch.config.number {
+		// Once the active epoch has ended gracefully, it is up to the
+		// epoch changer to suspect the next one if it fails to begin.
 		actions.Append(sm.activeEpoch.tick())
+
+		if seqNo, ok := sm.activeEpoch.stalledSeqNo(); ok {
+			actions.Append(sm.fetchSequenceState(seqNo))
+
+			// If the network has moved beyond the stalled sequence, it may
+			// have garbage collected the messages we need, so we must
+			// transfer state instead.
+			if target := sm.checkpointTracker.networkStableTarget(seqNo); target != nil && target.SeqNo > sm.persisted.lastCommitted {
+				actions.Append(sm.checkpointTracker.requestStateTransfer(target))
+			}
+		}
 	}
 
 	actions.Append(sm.epochChanger.tick())
 
+	return sm.signOutbound(actions)
+}
+
+// fetchSequenceState asks the other nodes to retransmit the messages they have
+// sent for the sequence we have stalled at.  As a node's messages may not be
+// applied in order past one which was lost, if we await a message from a node
+// for an earlier sequence, we ask it for that sequence instead.
+func (sm *stateMachine) fetchSequenceState(seqNo uint64) *Actions {
+	actions := &Actions{}
+
+	for _, id := range sm.networkConfig.Nodes {
+		if id == sm.myConfig.ID {
+			continue
+		}
+
+		nodeSeqNo := seqNo
+		if awaitedSeqNo, ok := sm.nodeMsgs[NodeID(id)].lowestAwaitedSeqNo(); ok && awaitedSeqNo < nodeSeqNo {
+			nodeSeqNo = awaitedSeqNo
+		}
+
+		actions.Unicast = append(actions.Unicast, Unicast{
+			Target: id,
+			Msg: &pb.Msg{
+				Type: &pb.Msg_FetchSequenceState{
+					FetchSequenceState: &pb.FetchSequenceState{
+						Epoch: sm.activeEpoch.config.number,
+						SeqNo: nodeSeqNo,
+					},
+				},
+			},
+		})
+	}
+
 	return actions
 }
 
+// err returns the error the state machine cannot recover from, if any, in
+// which case the node must stop.
+func (sm *stateMachine) err() error {
+	return sm.persisted.err
+}
+
 func (sm *stateMachine) status() *Status {
 	clientWindowsStatus := make([]*ClientWindowStatus, len(sm.clientWindows.clients))
 
@@ -445,5 +1033,7 @@ func (sm *stateMachine) status() *Status {
 		Buckets:       buckets,
 		Checkpoints:   checkpoints,
 		Nodes:         nodes,
+
+		DiscardedEntries: sm.persisted.discarded,
 	}
 }
@@ -0,0 +1,641 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mirbft
+
+import (
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	pb "github.com/IBM/mirbft/mirbftpb"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func newGenesisStateMachine(networkConfig *pb.NetworkConfig, myConfig *Config) *stateMachine {
+	persisted := newPersisted(myConfig)
+	persisted.add(&pb.Persisted{
+		Type: &pb.Persisted_NetworkConfig{
+			NetworkConfig: networkConfig,
+		},
+	})
+	persisted.add(&pb.Persisted{
+		Type: &pb.Persisted_Checkpoint{
+			Checkpoint: &pb.Checkpoint{SeqNo: 0, Value: []byte("cp0")},
+		},
+	})
+
+	sm, _ := newStateMachine(networkConfig, myConfig, persisted)
+	return sm
+}
+
+// sliceStorage is a Storage which loads the entries of the slice.
+type sliceStorage []*pb.Persisted
+
+func (ss sliceStorage) Load(index uint64) (*pb.Persisted, error) {
+	if index >= uint64(len(ss)) {
+		return nil, io.EOF
+	}
+	return ss[index], nil
+}
+
+var _ = Describe("Reconfiguration", func() {
+	var (
+		myConfig         *Config
+		networkConfig    *pb.NetworkConfig
+		newNetworkConfig *pb.NetworkConfig
+		sm               *stateMachine
+	)
+
+	BeforeEach(func() {
+		myConfig = &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}
+
+		networkConfig = StandardInitialNetworkConfig(4)
+		newNetworkConfig = StandardInitialNetworkConfig(5)
+
+		sm = newGenesisStateMachine(networkConfig, myConfig)
+
+		sm.processResults(ActionResults{
+			Checkpoints: []*CheckpointResult{
+				{
+					SeqNo:         20,
+					Value:         []byte("cp20"),
+					NetworkConfig: newNetworkConfig,
+				},
+			},
+		})
+	})
+
+	It("applies the new network config once the checkpoint is stable", func() {
+		Expect(sm.checkpointMsg(0, 20, []byte("cp20")).IsEmpty()).To(BeTrue())
+		Expect(sm.checkpointMsg(1, 20, []byte("cp20")).IsEmpty()).To(BeTrue())
+		Expect(sm.networkConfig).To(Equal(networkConfig))
+
+		Expect(sm.checkpointMsg(2, 20, []byte("cp20"))).To(Equal(&Actions{Truncate: 20}))
+		Expect(sm.pendingNetworkConfig).To(Equal(newNetworkConfig))
+
+		actions := sm.drainNodeMsgs()
+		Expect(sm.pendingNetworkConfig).To(BeNil())
+		Expect(sm.networkConfig).To(Equal(newNetworkConfig))
+		Expect(sm.activeEpoch).To(BeNil())
+		Expect(sm.nodeMsgs).To(HaveLen(5))
+		Expect(sm.checkpointTracker.lowWatermark()).To(Equal(uint64(20)))
+		Expect(sm.persisted.networkConfig).To(Equal(newNetworkConfig))
+
+		Expect(actions.Replicas).To(HaveLen(5))
+		Expect(actions.Persisted).To(HaveLen(2))
+		Expect(actions.Persisted[0].Type.(*pb.Persisted_NetworkConfig).NetworkConfig).To(Equal(newNetworkConfig))
+		epochChange := actions.Persisted[1].Type.(*pb.Persisted_Ecentry).Ecentry.EpochChange
+		Expect(epochChange.NewEpoch).To(Equal(uint64(1)))
+		Expect(epochChange.Checkpoints).To(Equal([]*pb.Checkpoint{{SeqNo: 20, Value: []byte("cp20")}}))
+		Expect(sm.epochChanger.pendingEpochTarget.number).To(Equal(uint64(1)))
+
+		for _, nodeMsgs := range sm.nodeMsgs {
+			Expect(nodeMsgs.status().LastCheckpoint).To(Equal(uint64(20)))
+		}
+	})
+
+	It("persists the network config along with the reconfiguring checkpoint", func() {
+		Expect(sm.persisted.checkpoints[20].NetworkConfig).To(Equal(newNetworkConfig))
+
+		epochChange := sm.persisted.constructEpochChange(1, nil, sm.checkpointTracker)
+		for _, cp := range epochChange.Checkpoints {
+			Expect(cp.NetworkConfig).To(BeNil())
+		}
+	})
+
+	It("retains checkpoints beyond the watermarks and the state transfer in progress", func() {
+		target := &StateTarget{SeqNo: 1000, Value: []byte("cp1000")}
+		sm.ingest(1, &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{SeqNo: target.SeqNo, Value: target.Value},
+			},
+		})
+		sm.ingest(2, &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{SeqNo: target.SeqNo, Value: target.Value},
+			},
+		})
+		actions := sm.ingest(3, &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{SeqNo: target.SeqNo, Value: target.Value},
+			},
+		})
+		Expect(actions.StateTransfer).To(Equal(target))
+
+		sm.checkpointMsg(0, 20, []byte("cp20"))
+		sm.checkpointMsg(1, 20, []byte("cp20"))
+		sm.checkpointMsg(2, 20, []byte("cp20"))
+		sm.drainNodeMsgs()
+		Expect(sm.networkConfig).To(Equal(newNetworkConfig))
+
+		Expect(sm.checkpointTracker.stateTransfer).To(Equal(target))
+		Expect(sm.checkpointTracker.futureCheckpoints).To(HaveKey(target.SeqNo))
+
+		// A fourth agreement, from a node of the new config, is not a
+		// reason to transfer state a second time.
+		actions = sm.ingest(4, &pb.Msg{
+			Type: &pb.Msg_Checkpoint{
+				Checkpoint: &pb.Checkpoint{SeqNo: target.SeqNo, Value: target.Value},
+			},
+		})
+		Expect(actions.StateTransfer).To(BeNil())
+
+		actions = sm.applyStateTransferResult(&StateTransferResult{
+			Target: target,
+		})
+		Expect(sm.checkpointTracker.lowWatermark()).To(Equal(target.SeqNo))
+		Expect(sm.checkpointTracker.stateTransfer).To(BeNil())
+	})
+
+	When("the node restarts", func() {
+		var entries []*pb.Persisted
+
+		restart := func() *stateMachine {
+			persisted := newPersisted(myConfig)
+			Expect(persisted.load(sliceStorage(entries))).To(Succeed())
+			sm, _ := newStateMachine(persisted.networkConfig, myConfig, persisted)
+			return sm
+		}
+
+		BeforeEach(func() {
+			entries = []*pb.Persisted{
+				{
+					Type: &pb.Persisted_NetworkConfig{
+						NetworkConfig: networkConfig,
+					},
+				},
+				{
+					Type: &pb.Persisted_Checkpoint{
+						Checkpoint: &pb.Checkpoint{SeqNo: 0, Value: []byte("cp0")},
+					},
+				},
+				{
+					Type: &pb.Persisted_Checkpoint{
+						Checkpoint: &pb.Checkpoint{
+							SeqNo:         20,
+							Value:         []byte("cp20"),
+							NetworkConfig: newNetworkConfig,
+						},
+					},
+				},
+			}
+		})
+
+		It("applies the new network config once the reconfiguring checkpoint is stable", func() {
+			sm := restart()
+			Expect(sm.networkConfig).To(Equal(networkConfig))
+
+			sm.checkpointMsg(0, 20, []byte("cp20"))
+			sm.checkpointMsg(1, 20, []byte("cp20"))
+			sm.checkpointMsg(2, 20, []byte("cp20"))
+			actions := sm.drainNodeMsgs()
+			Expect(sm.networkConfig).To(Equal(newNetworkConfig))
+			Expect(actions.Replicas).To(HaveLen(5))
+		})
+
+		When("the reconfiguring checkpoint is the lowest retained", func() {
+			BeforeEach(func() {
+				entries = append(entries[:1], entries[2:]...)
+			})
+
+			It("restarts with the new network config", func() {
+				sm := restart()
+				Expect(sm.networkConfig).To(Equal(newNetworkConfig))
+				Expect(sm.nodeMsgs).To(HaveLen(5))
+				Expect(sm.checkpointTracker.checkpoint(20).nextNetworkConfig).To(BeNil())
+			})
+		})
+
+		When("the network config was persisted after the reconfiguring checkpoint", func() {
+			BeforeEach(func() {
+				entries = append(entries, &pb.Persisted{
+					Type: &pb.Persisted_NetworkConfig{
+						NetworkConfig: newNetworkConfig,
+					},
+				})
+			})
+
+			It("does not reconfigure a second time", func() {
+				sm := restart()
+				Expect(sm.networkConfig).To(Equal(newNetworkConfig))
+
+				sm.checkpointMsg(0, 20, []byte("cp20"))
+				sm.checkpointMsg(1, 20, []byte("cp20"))
+				sm.checkpointMsg(2, 20, []byte("cp20"))
+				Expect(sm.pendingNetworkConfig).To(BeNil())
+			})
+		})
+	})
+
+	It("applies a new network config reached by state transfer at once", func() {
+		target := &StateTarget{SeqNo: 40, Value: []byte("cp40")}
+		Expect(sm.checkpointTracker.requestStateTransfer(target).StateTransfer).To(Equal(target))
+
+		actions := sm.applyStateTransferResult(&StateTransferResult{
+			Target:        target,
+			NetworkConfig: newNetworkConfig,
+		})
+		Expect(sm.pendingNetworkConfig).To(BeNil())
+		Expect(sm.networkConfig).To(Equal(newNetworkConfig))
+		Expect(sm.nodeMsgs).To(HaveLen(5))
+		Expect(sm.checkpointTracker.lowWatermark()).To(Equal(uint64(40)))
+		Expect(sm.persisted.networkConfig).To(Equal(newNetworkConfig))
+		Expect(actions.Truncate).To(Equal(uint64(40)))
+		Expect(actions.Persisted[1].Type.(*pb.Persisted_NetworkConfig).NetworkConfig).To(Equal(newNetworkConfig))
+	})
+
+	When("the client window width changes", func() {
+		BeforeEach(func() {
+			newNetworkConfig = StandardInitialNetworkConfig(4)
+			newNetworkConfig.ClientWindowWidth = 200
+
+			sm = newGenesisStateMachine(networkConfig, myConfig)
+			sm.clientWindows.insert([]byte("client"), sm.clientWindows.newWindow(1))
+			sm.processResults(ActionResults{
+				Checkpoints: []*CheckpointResult{
+					{
+						SeqNo:         20,
+						Value:         []byte("cp20"),
+						NetworkConfig: newNetworkConfig,
+					},
+				},
+			})
+		})
+
+		It("resizes the existing client windows", func() {
+			cw, _ := sm.clientWindows.clientWindow([]byte("client"))
+			Expect(cw.highWatermark).To(Equal(uint64(100)))
+
+			sm.checkpointMsg(0, 20, []byte("cp20"))
+			sm.checkpointMsg(1, 20, []byte("cp20"))
+			sm.checkpointMsg(2, 20, []byte("cp20"))
+			sm.drainNodeMsgs()
+			Expect(sm.networkConfig).To(Equal(newNetworkConfig))
+
+			cw, _ = sm.clientWindows.clientWindow([]byte("client"))
+			Expect(cw.lowWatermark).To(Equal(uint64(1)))
+			Expect(cw.highWatermark).To(Equal(uint64(200)))
+			Expect(cw.clientRequests).To(HaveLen(200))
+			Expect(cw.clientWaiter.highWatermark).To(Equal(uint64(200)))
+		})
+	})
+
+	When("a node other than the highest is removed", func() {
+		BeforeEach(func() {
+			newNetworkConfig = StandardInitialNetworkConfig(3)
+			newNetworkConfig.Nodes = []uint64{0, 1, 3}
+
+			sm = newGenesisStateMachine(networkConfig, myConfig)
+			sm.processResults(ActionResults{
+				Checkpoints: []*CheckpointResult{
+					{
+						SeqNo:         20,
+						Value:         []byte("cp20"),
+						NetworkConfig: newNetworkConfig,
+					},
+				},
+			})
+			sm.checkpointMsg(0, 20, []byte("cp20"))
+			sm.checkpointMsg(1, 20, []byte("cp20"))
+			sm.checkpointMsg(2, 20, []byte("cp20"))
+			sm.drainNodeMsgs()
+			Expect(sm.networkConfig).To(Equal(newNetworkConfig))
+		})
+
+		It("chooses new epoch leaders only from the remaining nodes", func() {
+			Expect(sm.epochChanger.target(1).leader()).To(Equal(NodeID(1)))
+			Expect(sm.epochChanger.target(2).leader()).To(Equal(NodeID(3)))
+			Expect(sm.epochChanger.target(3).leader()).To(Equal(NodeID(0)))
+			Expect(sm.epochChanger.target(2).isLeader).To(BeFalse())
+			Expect(sm.epochChanger.target(3).isLeader).To(BeTrue())
+		})
+
+		It("accepts a NewEpoch only from the leader of its epoch", func() {
+			newEpoch := func(number uint64) *pb.Msg {
+				return &pb.Msg{
+					Type: &pb.Msg_NewEpoch{
+						NewEpoch: &pb.NewEpoch{
+							Config: &pb.EpochConfig{Number: number},
+						},
+					},
+				}
+			}
+
+			Expect(sm.nodeMsgs[3].process(newEpoch(2))).To(Equal(current))
+			Expect(sm.nodeMsgs[1].process(newEpoch(2))).To(Equal(invalid))
+		})
+	})
+
+	It("drops messages from nodes outside of the network config", func() {
+		actions := sm.step(7, &pb.Msg{
+			Type: &pb.Msg_Suspect{
+				Suspect: &pb.Suspect{Epoch: 0},
+			},
+		})
+		Expect(actions.IsEmpty()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Checkpoint divergence", func() {
+	var sm *stateMachine
+
+	BeforeEach(func() {
+		sm = newGenesisStateMachine(StandardInitialNetworkConfig(4), &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+
+		sm.processResults(ActionResults{
+			Checkpoints: []*CheckpointResult{
+				{
+					SeqNo: 20,
+					Value: []byte("mine"),
+				},
+			},
+		})
+	})
+
+	It("records an oddity and transfers state to the network value", func() {
+		Expect(sm.checkpointMsg(0, 20, []byte("mine")).IsEmpty()).To(BeTrue())
+		Expect(sm.checkpointMsg(1, 20, []byte("theirs")).IsEmpty()).To(BeTrue())
+		Expect(sm.checkpointMsg(2, 20, []byte("theirs")).IsEmpty()).To(BeTrue())
+		Expect(sm.oddities.diverged).To(BeZero())
+
+		actions := sm.checkpointMsg(3, 20, []byte("theirs"))
+		Expect(actions.StateTransfer).To(Equal(&StateTarget{
+			SeqNo: 20,
+			Value: []byte("theirs"),
+		}))
+		Expect(sm.oddities.diverged).To(Equal(uint64(1)))
+	})
+})
+
+var _ = Describe("Suspicion", func() {
+	var sm *stateMachine
+
+	BeforeEach(func() {
+		sm = newGenesisStateMachine(StandardInitialNetworkConfig(4), &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+	})
+
+	It("broadcasts the epoch change along with its entry", func() {
+		Expect(sm.applySuspectMsg(1, 0).IsEmpty()).To(BeTrue())
+		Expect(sm.applySuspectMsg(2, 0).IsEmpty()).To(BeTrue())
+
+		actions := sm.applySuspectMsg(3, 0)
+		Expect(actions.Persisted).To(HaveLen(1))
+		epochChange := actions.Persisted[0].Type.(*pb.Persisted_Ecentry).Ecentry.EpochChange
+		Expect(epochChange.NewEpoch).To(Equal(uint64(1)))
+		Expect(actions.Broadcast).To(Equal([]*pb.Msg{
+			{
+				Type: &pb.Msg_EpochChange{
+					EpochChange: epochChange,
+				},
+			},
+		}))
+	})
+})
+
+var _ = Describe("Evidence", func() {
+	var (
+		sm *stateMachine
+	)
+
+	BeforeEach(func() {
+		sm = newGenesisStateMachine(StandardInitialNetworkConfig(4), &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+	})
+
+	It("reports a forwarded request which does not match its digest", func() {
+		request := &pb.Request{
+			ClientId: []byte("client"),
+			ReqNo:    1,
+			Data:     []byte("data"),
+		}
+
+		actions := sm.processResults(ActionResults{
+			Digests: []*HashResult{
+				{
+					Digest: []byte("actual"),
+					Request: &HashRequest{
+						VerifyRequest: &VerifyRequest{
+							Source:         2,
+							Request:        request,
+							ExpectedDigest: []byte("claimed"),
+						},
+					},
+				},
+			},
+		})
+
+		Expect(actions.Evidence).To(HaveLen(1))
+		Expect(actions.Evidence[0].Source).To(Equal(uint64(2)))
+		Expect(actions.Evidence[0].Msg.Type.(*pb.Msg_ForwardRequest).ForwardRequest).To(Equal(&pb.ForwardRequest{
+			Request: request,
+			Digest:  []byte("claimed"),
+		}))
+		Expect(sm.oddities.nodes[2].byzantine).To(Equal(uint64(1)))
+
+		_, ok := sm.clientWindows.clientWindow([]byte("client"))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reports a forwarded batch which does not match its digest", func() {
+		sm.batchTracker.fetchInFlight["claimed"] = []uint64{3}
+
+		actions := sm.processResults(ActionResults{
+			Digests: []*HashResult{
+				{
+					Digest: []byte("actual"),
+					Request: &HashRequest{
+						VerifyBatch: &VerifyBatch{
+							Source:         1,
+							SeqNo:          3,
+							ExpectedDigest: []byte("claimed"),
+						},
+					},
+				},
+			},
+		})
+
+		Expect(actions.Evidence).To(HaveLen(1))
+		Expect(actions.Evidence[0].Source).To(Equal(uint64(1)))
+		Expect(actions.Evidence[0].Msg.Type.(*pb.Msg_ForwardBatch).ForwardBatch.SeqNo).To(Equal(uint64(3)))
+		Expect(sm.batchTracker.hasFetchInFlight()).To(BeTrue())
+	})
+
+	It("rejects acks outside of the client window", func() {
+		sm.clientWindows.insert([]byte("client"), sm.clientWindows.newWindow(1))
+
+		actions := sm.applyRequestAckMsg(1, []byte("client"), 500, []byte("digest"))
+		Expect(actions.IsEmpty()).To(BeTrue())
+		Expect(sm.oddities.nodes[1].rejected).To(Equal(uint64(1)))
+	})
+})
+
+var _ = Describe("Validation", func() {
+	var (
+		sm      *stateMachine
+		request *pb.Request
+	)
+
+	BeforeEach(func() {
+		sm = newGenesisStateMachine(StandardInitialNetworkConfig(4), &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+
+		request = &pb.Request{
+			ClientId: []byte("client"),
+			ReqNo:    1,
+			Data:     []byte("data"),
+		}
+	})
+
+	validate := func(valid bool) *Actions {
+		actions := sm.processResults(ActionResults{
+			Digests: []*HashResult{
+				{
+					Digest: []byte("digest"),
+					Request: &HashRequest{
+						Request: &Request{
+							Source:  0,
+							Request: request,
+						},
+					},
+				},
+			},
+		})
+		Expect(actions.Broadcast).To(BeEmpty())
+		Expect(actions.Validate).To(Equal([]*ValidateRequest{
+			{
+				Source:  0,
+				Request: request,
+				Digest:  []byte("digest"),
+			},
+		}))
+
+		return sm.processResults(ActionResults{
+			Validations: []*ValidateResult{
+				{
+					Request: actions.Validate[0],
+					Valid:   valid,
+				},
+			},
+		})
+	}
+
+	It("acknowledges requests once validated", func() {
+		actions := validate(true)
+		Expect(actions.Broadcast).To(HaveLen(1))
+		Expect(actions.Broadcast[0].Type.(*pb.Msg_RequestAck).RequestAck).To(Equal(&pb.RequestAck{
+			ClientId: []byte("client"),
+			ReqNo:    1,
+			Digest:   []byte("digest"),
+		}))
+
+		cw, ok := sm.clientWindows.clientWindow([]byte("client"))
+		Expect(ok).To(BeTrue())
+		Expect(cw.request(1).digests).To(HaveKey("digest"))
+	})
+
+	It("drops requests which fail validation", func() {
+		actions := validate(false)
+		Expect(actions.IsEmpty()).To(BeTrue())
+
+		_, ok := sm.clientWindows.clientWindow([]byte("client"))
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("Persistence errors", func() {
+	var (
+		sm *stateMachine
+	)
+
+	BeforeEach(func() {
+		sm = newGenesisStateMachine(StandardInitialNetworkConfig(4), &Config{
+			ID:     0,
+			Logger: zap.NewNop(),
+			BatchParameters: BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		})
+	})
+
+	It("reports entries discarded rather than persisted in the status", func() {
+		Expect(sm.status().DiscardedEntries).To(BeZero())
+
+		actions := sm.persisted.add(&pb.Persisted{
+			Type: &pb.Persisted_Ecentry{
+				Ecentry: &pb.ECEntry{
+					EpochChange: &pb.EpochChange{
+						NewEpoch: 0,
+					},
+				},
+			},
+		})
+		Expect(actions.IsEmpty()).To(BeTrue())
+		Expect(sm.status().DiscardedEntries).To(Equal(uint64(1)))
+	})
+
+	It("stops the node once a sequence fails to commit in order", func() {
+		Expect(sm.persisted.setLastCommitted(2)).To(HaveOccurred())
+
+		doneC := make(chan struct{})
+		defer close(doneC)
+		s := newSerializer(sm, &Actions{}, doneC)
+
+		Eventually(s.tickC).Should(BeSent(struct{}{}))
+		Eventually(s.errC).Should(BeClosed())
+		Expect(errors.Cause(s.getExitErr())).To(Equal(ErrNonContiguousCommit))
+		Expect(s.getExitErr()).To(MatchError("state machine stopped: cannot commit seq_no=2 after seq_no=0: commit does not follow the last committed sequence"))
+	})
+})
@@ -21,6 +21,11 @@ type Status struct {
 	Buckets       []*BucketStatus       `json:"buckets"`
 	Checkpoints   []*CheckpointStatus   `json:"checkpoints"`
 	ClientWindows []*ClientWindowStatus `json:"request_windows"`
+
+	// DiscardedEntries is the number of entries the state machine discarded
+	// rather than persist, as they would have regressed a persisted entry.
+	// This indicates a bug, and should always be zero.
+	DiscardedEntries uint64 `json:"discarded_entries"`
 }
 
 type BucketStatus struct {
@@ -37,9 +42,11 @@ type CheckpointStatus struct {
 }
 
 type EpochChangerStatus struct {
-	State           epochTargetState     `json:"state"` // TODO, export or untype
-	LastActiveEpoch uint64               `json:"last_active_epoch"`
-	EpochTargets    []*EpochTargetStatus `json:"epoch_targets"`
+	State                epochTargetState     `json:"state"` // TODO, export or untype
+	LastActiveEpoch      uint64               `json:"last_active_epoch"`
+	FailedEpochs         uint64               `json:"failed_epochs"`
+	NewEpochTimeoutTicks uint64               `json:"new_epoch_timeout_ticks"`
+	EpochTargets         []*EpochTargetStatus `json:"epoch_targets"`
 }
 
 type EpochTargetStatus struct {
@@ -64,6 +71,17 @@ type NodeStatus struct {
 	ID             uint64             `json:"id"`
 	BucketStatuses []NodeBucketStatus `json:"bucket_statuses"`
 	LastCheckpoint uint64             `json:"last_checkpoint"`
+	BufferStatuses []NodeBufferStatus `json:"buffer_statuses"`
+}
+
+// NodeBufferStatus describes the messages of one kind buffered from a node,
+// and how many have been dropped because the buffer was full.
+type NodeBufferStatus struct {
+	Kind         string `json:"kind"`
+	Msgs         int    `json:"msgs"`
+	Bytes        int    `json:"bytes"`
+	Dropped      uint64 `json:"dropped"`
+	DroppedBytes uint64 `json:"dropped_bytes"`
 }
 
 type NodeBucketStatus struct {
@@ -78,6 +96,7 @@ type ClientWindowStatus struct {
 	LowWatermark  uint64   `json:"low_watermark"`
 	HighWatermark uint64   `json:"high_watermark"`
 	Allocated     []uint64 `json:"allocated"`
+	Committed     []uint64 `json:"committed"` // The seqno each request committed at, or zero
 }
 
 func (s *Status) Pretty() string {
@@ -88,6 +107,7 @@ func (s *Status) Pretty() string {
 
 	buffer.WriteString("=== Epoch Changer ===\n")
 	buffer.WriteString(fmt.Sprintf("Change is in state: %d, last active epoch %d\n", s.EpochChanger.State, s.EpochChanger.LastActiveEpoch))
+	buffer.WriteString(fmt.Sprintf("New epoch timeout is %d ticks after %d failed epochs\n", s.EpochChanger.NewEpochTimeoutTicks, s.EpochChanger.FailedEpochs))
 	for _, et := range s.EpochChanger.EpochTargets {
 		buffer.WriteString(fmt.Sprintf("Target Epoch %d:\n", et.Number))
 		buffer.WriteString(fmt.Sprintf("  EpochChanges:\n"))
@@ -156,6 +176,10 @@ func (s *Status) Pretty() string {
 				buffer.WriteString(fmt.Sprintf("| Bucket=%d\n", bucket))
 			}
 		}
+
+		for _, bufferStatus := range nodeStatus.BufferStatuses {
+			buffer.WriteString(fmt.Sprintf("| Buffer=%s Msgs=%d Bytes=%d Dropped=%d DroppedBytes=%d\n", bufferStatus.Kind, bufferStatus.Msgs, bufferStatus.Bytes, bufferStatus.Dropped, bufferStatus.DroppedBytes))
+		}
 	}
 
 	hRule()
@@ -104,6 +104,7 @@ type TestConfig struct {
 	NodeCount   int
 	BucketCount int
 	MsgCount    int
+	Parallel    bool
 }
 
 func Uint64ToPtr(value uint64) *uint64 {
@@ -196,10 +197,26 @@ var _ = Describe("StressyTest", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		// The receipts are withheld until everything has committed, to
+		// show that a blocked OnCommit function does not stall the node.
+		var receiptsMutex sync.Mutex
+		receipts := map[uint64]*mirbft.CommitReceipt{}
+		releaseC := make(chan struct{})
+
 		clients := make([]*mirbft.ClientProposer, len(network.nodes))
 		for i, node := range network.nodes {
+			var options []mirbft.ClientProposerOption
+			if i == 0 {
+				options = append(options, mirbft.OnCommit(func(receipt *mirbft.CommitReceipt) {
+					<-releaseC
+					receiptsMutex.Lock()
+					defer receiptsMutex.Unlock()
+					receipts[receipt.ReqNo] = receipt
+				}))
+			}
+
 			var err error
-			clients[i], err = node.ClientProposer(ctx, []byte{})
+			clients[i], err = node.ClientProposer(ctx, []byte{}, options...)
 			Expect(err).NotTo(HaveOccurred())
 		}
 
@@ -221,6 +238,7 @@ var _ = Describe("StressyTest", func() {
 			proposals[string(proposalKey)] = proposalUint
 		}
 
+		var committed []*pb.QEntry
 		observations := map[uint64]struct{}{}
 		for j, fakeLog := range network.fakeLogs {
 			By(fmt.Sprintf("checking for node %d that each message only commits once", j))
@@ -234,7 +252,23 @@ var _ = Describe("StressyTest", func() {
 				_, ok = observations[proposalUint]
 				Expect(ok).To(BeFalse())
 				observations[proposalUint] = struct{}{}
+
+				if j == 0 {
+					committed = append(committed, entry)
+				}
+			}
+		}
+
+		close(releaseC)
+		for _, entry := range committed {
+			receipt := func() *mirbft.CommitReceipt {
+				receiptsMutex.Lock()
+				defer receiptsMutex.Unlock()
+				return receipts[entry.Requests[0].Request.ReqNo]
 			}
+			Eventually(receipt).ShouldNot(BeNil())
+			Expect(receipt().SeqNo).To(Equal(entry.SeqNo))
+			Expect(receipt().Epoch).To(Equal(entry.Epoch))
 		}
 	},
 		Entry("SingleNode greenpath", &TestConfig{
@@ -242,6 +276,12 @@ var _ = Describe("StressyTest", func() {
 			MsgCount:  1000,
 		}),
 
+		Entry("SingleNode parallel greenpath", &TestConfig{
+			NodeCount: 1,
+			MsgCount:  1000,
+			Parallel:  true,
+		}),
+
 		PEntry("FourNodeBFT greenpath", &TestConfig{
 			NodeCount: 4,
 			MsgCount:  1000,
@@ -258,7 +298,7 @@ var _ = Describe("StressyTest", func() {
 type Network struct {
 	nodes      []*mirbft.Node
 	fakeLogs   []*FakeLog
-	processors []*sample.SerialProcessor
+	processors []sample.Processor
 }
 
 func CreateNetwork(testConfig *TestConfig, logger *zap.Logger, doneC <-chan struct{}) *Network {
@@ -295,7 +335,7 @@ func CreateNetwork(testConfig *TestConfig, logger *zap.Logger, doneC <-chan stru
 	}
 
 	fakeLogs := make([]*FakeLog, testConfig.NodeCount)
-	processors := make([]*sample.SerialProcessor, testConfig.NodeCount)
+	processors := make([]sample.Processor, testConfig.NodeCount)
 	for i, node := range nodes {
 		node := node
 		fakeLog := &FakeLog{
@@ -304,6 +344,16 @@ func CreateNetwork(testConfig *TestConfig, logger *zap.Logger, doneC <-chan stru
 
 		fakeLogs[i] = fakeLog
 
+		if testConfig.Parallel {
+			processors[i] = &sample.ParallelProcessor{
+				Node:      node,
+				Link:      transport.Link(node.Config.ID),
+				Hasher:    func() hash.Hash { return &NoopHasher{} },
+				Committer: sample.NewRingCommitter(fakeLog, networkConfig, 0),
+			}
+			continue
+		}
+
 		processors[i] = &sample.SerialProcessor{
 			Node:   node,
 			Link:   transport.Link(node.Config.ID),
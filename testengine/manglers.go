@@ -7,6 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package testengine
 
 import (
+	"reflect"
+	"strings"
+
 	tpb "github.com/IBM/mirbft/testengine/testenginepb"
 	"github.com/golang/protobuf/proto"
 )
@@ -63,6 +66,24 @@ func (em *EventMangling) ForNodes(nodes ...uint64) *EventMangling {
 	return em
 }
 
+// ToNodes restricts the mangling to events targeted at the given nodes.
+func (em *EventMangling) ToNodes(nodes ...uint64) *EventMangling {
+	compositeMangler := &CompositeMangler{
+		Manglers: make([]Mangler, len(nodes)),
+	}
+
+	for i, node := range nodes {
+		compositeMangler.Manglers[i] = &EventTargetFilterMangler{
+			Mangler: em.Mangler,
+			Target:  node,
+		}
+	}
+
+	em.Mangler = compositeMangler
+
+	return em
+}
+
 func (em *EventMangling) FromNodes(nodes ...uint64) *EventMangling {
 	compositeMangler := &CompositeMangler{
 		Manglers: make([]Mangler, len(nodes)),
@@ -80,6 +101,17 @@ func (em *EventMangling) FromNodes(nodes ...uint64) *EventMangling {
 	return em
 }
 
+// OfTypes restricts the mangling to messages of the given types, named as in
+// the Msg oneof, for instance "Prepare" or "Commit".
+func (em *EventMangling) OfTypes(msgTypes ...string) *EventMangling {
+	em.Mangler = &MsgTypeFilterMangler{
+		Mangler: em.Mangler,
+		Types:   msgTypes,
+	}
+
+	return em
+}
+
 func (em *EventMangling) When(when func() bool) *EventMangling {
 	em.Mangler = &ConditionalMangler{
 		Mangler:   em.Mangler,
@@ -210,6 +242,19 @@ func (etfm *EventTypeFilterMangler) BeforeStep(random int, el *EventLog) {
 
 }
 
+type EventTargetFilterMangler struct {
+	Mangler Mangler
+	Target  uint64
+}
+
+func (etfm *EventTargetFilterMangler) BeforeStep(random int, el *EventLog) {
+	if el.NextEventLogEntry.Event.Target != etfm.Target {
+		return
+	}
+
+	etfm.Mangler.BeforeStep(random, el)
+}
+
 type MsgSourceFilterMangler struct {
 	Mangler Mangler
 	Source  uint64
@@ -230,6 +275,28 @@ func (msfm *MsgSourceFilterMangler) BeforeStep(random int, el *EventLog) {
 	msfm.Mangler.BeforeStep(random, el)
 }
 
+type MsgTypeFilterMangler struct {
+	Mangler Mangler
+	Types   []string
+}
+
+func (mtfm *MsgTypeFilterMangler) BeforeStep(random int, el *EventLog) {
+	event := el.NextEventLogEntry.Event
+
+	recv, ok := event.Type.(*tpb.Event_Receive_)
+	if !ok {
+		return
+	}
+
+	msgType := strings.TrimPrefix(reflect.TypeOf(recv.Receive.Msg.Type).Elem().Name(), "Msg_")
+	for _, filterType := range mtfm.Types {
+		if msgType == filterType {
+			mtfm.Mangler.BeforeStep(random, el)
+			return
+		}
+	}
+}
+
 type ConditionalMangler struct {
 	Mangler   Mangler
 	Condition func() bool
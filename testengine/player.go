@@ -54,6 +54,7 @@ func NewPlayer(el *EventLog, logger *zap.Logger) (*Player, error) {
 				SuspectTicks:         int(nodeConfig.SuspectTicks),
 				NewEpochTimeoutTicks: int(nodeConfig.NewEpochTimeoutTicks),
 				HeartbeatTicks:       int(nodeConfig.HeartbeatTicks),
+				OutOfOrderDelivery:   nodeConfig.OutOfOrderDelivery,
 				BufferSize:           int(nodeConfig.BufferSize),
 			},
 			doneC,
@@ -171,6 +172,17 @@ func (p *Player) Step() error {
 			}
 		}
 
+		for _, vr := range apply.Validations {
+			actionResults.Validations = append(actionResults.Validations, &mirbft.ValidateResult{
+				Request: &mirbft.ValidateRequest{
+					Source:  vr.Source,
+					Request: vr.Request,
+					Digest:  vr.Digest,
+				},
+				Valid: vr.Valid,
+			})
+		}
+
 		for i, cr := range apply.Checkpoints {
 			actionResults.Checkpoints[i] = &mirbft.CheckpointResult{
 				SeqNo: cr.SeqNo,
@@ -178,6 +190,22 @@ func (p *Player) Step() error {
 			}
 		}
 
+		if apply.StateTransfer != nil {
+			actionResults.StateTransfer = &mirbft.StateTransferResult{
+				Target: &mirbft.StateTarget{
+					SeqNo: apply.StateTransfer.SeqNo,
+					Value: apply.StateTransfer.Value,
+				},
+			}
+
+			for _, cw := range apply.StateTransfer.ClientWatermarks {
+				actionResults.StateTransfer.ClientWatermarks = append(actionResults.StateTransfer.ClientWatermarks, &mirbft.ClientWatermark{
+					ClientID:     cw.ClientId,
+					LowWatermark: cw.LowWatermark,
+				})
+			}
+		}
+
 		node.Node.AddResults(*actionResults)
 	case *tpb.Event_Receive_:
 		receive := et.Receive
@@ -136,6 +136,94 @@ func (ns *NodeState) Commit(commits []*mirbft.Commit, node uint64) []*tpb.Checkp
 
 }
 
+// StateTransfer replaces the state of this node with the state of the source
+// node as of the given checkpoint, as an application would by fetching it from
+// another replica.  Any outstanding commits beyond the checkpoint are retained,
+// and the checkpoints they complete are returned.
+func (ns *NodeState) StateTransfer(source *NodeState, seqNo uint64, value []byte) ([]*tpb.Checkpoint, error) {
+	if source.LastCommittedSeqNo < seqNo {
+		return nil, errors.Errorf("source has only committed through seqno=%d, cannot transfer to seqno=%d", source.LastCommittedSeqNo, seqNo)
+	}
+
+	ns.Hasher.Reset()
+	ns.Length = 0
+	ns.FirstCommit = nil
+	ns.LastCommit = nil
+
+	for cl := source.FirstCommit; cl != nil && cl.Commit.QEntry.SeqNo <= seqNo; cl = cl.Next {
+		if ns.FirstCommit == nil {
+			ns.FirstCommit = &CommitList{
+				Commit: cl.Commit,
+			}
+			ns.LastCommit = ns.FirstCommit
+		} else {
+			ns.LastCommit.Next = &CommitList{
+				Commit: cl.Commit,
+			}
+			ns.LastCommit = ns.LastCommit.Next
+		}
+
+		for _, request := range cl.Commit.QEntry.Requests {
+			ns.Hasher.Write(request.Digest)
+			ns.Length++
+		}
+	}
+
+	ns.Value = ns.Hasher.Sum(nil)
+	if !bytes.Equal(ns.Value, value) {
+		return nil, errors.Errorf("transferred state at seqno=%d has value %x, but expected %x", seqNo, ns.Value, value)
+	}
+
+	var outstanding []*mirbft.Commit
+	for _, commit := range ns.OutstandingCommits {
+		if commit != nil && commit.QEntry.SeqNo > seqNo {
+			outstanding = append(outstanding, commit)
+		}
+	}
+
+	ns.LastCommittedSeqNo = seqNo
+	ns.OutstandingCommits = nil
+
+	return ns.Commit(outstanding, 0), nil
+}
+
+// ClientWatermarks returns, for each client with requests committed at or
+// below the given seqno, the lowest request number which has not committed.
+func (ns *NodeState) ClientWatermarks(seqNo uint64) []*tpb.ClientWatermark {
+	var clientIDs []string
+	committed := map[string]map[uint64]struct{}{}
+	for cl := ns.FirstCommit; cl != nil && cl.Commit.QEntry.SeqNo <= seqNo; cl = cl.Next {
+		for _, request := range cl.Commit.QEntry.Requests {
+			clientID := string(request.Request.ClientId)
+			reqNos, ok := committed[clientID]
+			if !ok {
+				reqNos = map[uint64]struct{}{}
+				committed[clientID] = reqNos
+				clientIDs = append(clientIDs, clientID)
+			}
+			reqNos[request.Request.ReqNo] = struct{}{}
+		}
+	}
+
+	results := make([]*tpb.ClientWatermark, len(clientIDs))
+	for i, clientID := range clientIDs {
+		lowWatermark := uint64(1)
+		for {
+			if _, ok := committed[clientID][lowWatermark]; !ok {
+				break
+			}
+			lowWatermark++
+		}
+
+		results[i] = &tpb.ClientWatermark{
+			ClientId:     []byte(clientID),
+			LowWatermark: lowWatermark,
+		}
+	}
+
+	return results
+}
+
 type ClientConfig struct {
 	ID          []byte
 	TxLatency   uint64
@@ -353,8 +441,43 @@ func (r *Recording) Step() error {
 			}
 		}
 
+		for _, validateRequest := range processing.Validate {
+			apply.Validations = append(apply.Validations, &tpb.ValidateResult{
+				Source:  validateRequest.Source,
+				Request: validateRequest.Request,
+				Digest:  validateRequest.Digest,
+				Valid:   true,
+			})
+		}
+
 		apply.Checkpoints = nodeState.Commit(processing.Commits, lastEvent.Target)
 
+		if target := processing.StateTransfer; target != nil && target.SeqNo > nodeState.LastCommittedSeqNo {
+			var source *NodeState
+			for i, peer := range r.Nodes {
+				if uint64(i) != lastEvent.Target && peer.State.LastCommittedSeqNo >= target.SeqNo {
+					source = peer.State
+					break
+				}
+			}
+
+			if source == nil {
+				return errors.Errorf("node %d requested state transfer to seqno=%d, but no node has committed it", lastEvent.Target, target.SeqNo)
+			}
+
+			checkpoints, err := nodeState.StateTransfer(source, target.SeqNo, target.Value)
+			if err != nil {
+				return errors.WithMessagef(err, "node %d could not transfer state", lastEvent.Target)
+			}
+
+			apply.Checkpoints = append(apply.Checkpoints, checkpoints...)
+			apply.StateTransfer = &tpb.StateTransfer{
+				SeqNo:            target.SeqNo,
+				Value:            target.Value,
+				ClientWatermarks: nodeState.ClientWatermarks(target.SeqNo),
+			}
+		}
+
 		r.EventLog.InsertApply(lastEvent.Target, apply, uint64(nodeConfig.ReadyLatency))
 	case *tpb.Event_Propose_:
 	case *tpb.Event_Tick_:
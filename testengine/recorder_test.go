@@ -21,7 +21,7 @@ var _ = Describe("Recorder", func() {
 
 	BeforeEach(func() {
 		recorder = testengine.BasicRecorder(4, 4, 200)
-		recorder.NetworkConfig.MaxEpochLength = 100000 // XXX this works around a bug in the library for now
+		recorder.NetworkConfig.MaxEpochLength = 100000 // Keep the run within a single epoch
 		totalReqs = 4 * 200
 
 		var err error
@@ -69,7 +69,7 @@ var _ = Describe("Recorder", func() {
 	It("Executes and produces a log", func() {
 		count, err := recording.DrainClients(50000)
 		Expect(err).NotTo(HaveOccurred())
-		Expect(count).To(Equal(36305))
+		Expect(count).To(Equal(36329))
 
 		fmt.Printf("Executing test required a log of %d events\n", count)
 
@@ -84,10 +84,39 @@ var _ = Describe("Recorder", func() {
 			Expect(node.State.LastCommittedSeqNo).To(Equal(uint64(800)))
 
 			// Expect(fmt.Sprintf("%x", node.State.Value)).To(BeEmpty())
-			Expect(fmt.Sprintf("%x", node.State.Value)).To(Equal("689567a3721c39955e37f71beee801fdf89862ac9d2b2354ee7e01e9a2839230"))
+			Expect(fmt.Sprintf("%x", node.State.Value)).To(Equal("027ed46f6214fd3e52dbeabc7bcf952b0e2b37bdd0869815cfca8375a42b1740"))
 		}
 	})
 
+	When("the epoch length is bounded", func() {
+		BeforeEach(func() {
+			recorder = testengine.BasicRecorder(4, 4, 200)
+			Expect(recorder.NetworkConfig.MaxEpochLength).To(Equal(uint64(200)))
+
+			var err error
+			recording, err = recorder.Recording()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("rotates epochs gracefully", func() {
+			count, err := recording.DrainClients(50000)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(36490))
+
+			for _, node := range recording.Nodes {
+				status, err := node.PlaybackNode.Node.Status(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.EpochChanger.LastActiveEpoch).To(Equal(uint64(3)))
+				Expect(status.EpochChanger.EpochTargets).To(HaveLen(1))
+				Expect(status.EpochChanger.EpochTargets[0].Suspicions).To(BeEmpty())
+				Expect(node.State.Length).To(Equal(totalReqs))
+				Expect(node.State.LastCommittedSeqNo).To(Equal(uint64(800)))
+
+				Expect(fmt.Sprintf("%x", node.State.Value)).To(Equal("027ed46f6214fd3e52dbeabc7bcf952b0e2b37bdd0869815cfca8375a42b1740"))
+			}
+		})
+	})
+
 	When("A single-node network is selected", func() {
 		BeforeEach(func() {
 			recorder = testengine.BasicRecorder(1, 1, 3)
@@ -100,7 +129,7 @@ var _ = Describe("Recorder", func() {
 		It("still executes and produces a log", func() {
 			count, err := recording.DrainClients(50000)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(count).To(Equal(27))
+			Expect(count).To(Equal(29))
 		})
 	})
 })
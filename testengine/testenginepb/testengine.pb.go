@@ -33,7 +33,7 @@ func (m *ScenarioConfig) Reset()         { *m = ScenarioConfig{} }
 func (m *ScenarioConfig) String() string { return proto.CompactTextString(m) }
 func (*ScenarioConfig) ProtoMessage()    {}
 func (*ScenarioConfig) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{0}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{0}
 }
 func (m *ScenarioConfig) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ScenarioConfig.Unmarshal(m, b)
@@ -91,6 +91,7 @@ type NodeConfig struct {
 	ReadyLatency         int32    `protobuf:"varint,7,opt,name=ready_latency,json=readyLatency,proto3" json:"ready_latency,omitempty"`
 	ProcessLatency       int32    `protobuf:"varint,8,opt,name=process_latency,json=processLatency,proto3" json:"process_latency,omitempty"`
 	BufferSize           int32    `protobuf:"varint,9,opt,name=buffer_size,json=bufferSize,proto3" json:"buffer_size,omitempty"`
+	OutOfOrderDelivery   bool     `protobuf:"varint,10,opt,name=out_of_order_delivery,json=outOfOrderDelivery,proto3" json:"out_of_order_delivery,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -100,7 +101,7 @@ func (m *NodeConfig) Reset()         { *m = NodeConfig{} }
 func (m *NodeConfig) String() string { return proto.CompactTextString(m) }
 func (*NodeConfig) ProtoMessage()    {}
 func (*NodeConfig) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{1}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{1}
 }
 func (m *NodeConfig) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NodeConfig.Unmarshal(m, b)
@@ -183,6 +184,13 @@ func (m *NodeConfig) GetBufferSize() int32 {
 	return 0
 }
 
+func (m *NodeConfig) GetOutOfOrderDelivery() bool {
+	if m != nil {
+		return m.OutOfOrderDelivery
+	}
+	return false
+}
+
 type HashResult struct {
 	Digest []byte `protobuf:"bytes,1,opt,name=digest,proto3" json:"digest,omitempty"`
 	// Types that are valid to be assigned to Type:
@@ -201,7 +209,7 @@ func (m *HashResult) Reset()         { *m = HashResult{} }
 func (m *HashResult) String() string { return proto.CompactTextString(m) }
 func (*HashResult) ProtoMessage()    {}
 func (*HashResult) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{2}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{2}
 }
 func (m *HashResult) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_HashResult.Unmarshal(m, b)
@@ -447,7 +455,7 @@ func (m *Request) Reset()         { *m = Request{} }
 func (m *Request) String() string { return proto.CompactTextString(m) }
 func (*Request) ProtoMessage()    {}
 func (*Request) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{3}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{3}
 }
 func (m *Request) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Request.Unmarshal(m, b)
@@ -494,7 +502,7 @@ func (m *VerifyRequest) Reset()         { *m = VerifyRequest{} }
 func (m *VerifyRequest) String() string { return proto.CompactTextString(m) }
 func (*VerifyRequest) ProtoMessage()    {}
 func (*VerifyRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{4}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{4}
 }
 func (m *VerifyRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_VerifyRequest.Unmarshal(m, b)
@@ -549,7 +557,7 @@ func (m *Batch) Reset()         { *m = Batch{} }
 func (m *Batch) String() string { return proto.CompactTextString(m) }
 func (*Batch) ProtoMessage()    {}
 func (*Batch) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{5}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{5}
 }
 func (m *Batch) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Batch.Unmarshal(m, b)
@@ -611,7 +619,7 @@ func (m *VerifyBatch) Reset()         { *m = VerifyBatch{} }
 func (m *VerifyBatch) String() string { return proto.CompactTextString(m) }
 func (*VerifyBatch) ProtoMessage()    {}
 func (*VerifyBatch) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{6}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{6}
 }
 func (m *VerifyBatch) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_VerifyBatch.Unmarshal(m, b)
@@ -672,7 +680,7 @@ func (m *EpochChange) Reset()         { *m = EpochChange{} }
 func (m *EpochChange) String() string { return proto.CompactTextString(m) }
 func (*EpochChange) ProtoMessage()    {}
 func (*EpochChange) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{7}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{7}
 }
 func (m *EpochChange) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_EpochChange.Unmarshal(m, b)
@@ -713,6 +721,68 @@ func (m *EpochChange) GetEpochChange() *mirbftpb.EpochChange {
 	return nil
 }
 
+type ValidateResult struct {
+	Source               uint64            `protobuf:"varint,1,opt,name=source,proto3" json:"source,omitempty"`
+	Request              *mirbftpb.Request `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"`
+	Digest               []byte            `protobuf:"bytes,3,opt,name=digest,proto3" json:"digest,omitempty"`
+	Valid                bool              `protobuf:"varint,4,opt,name=valid,proto3" json:"valid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ValidateResult) Reset()         { *m = ValidateResult{} }
+func (m *ValidateResult) String() string { return proto.CompactTextString(m) }
+func (*ValidateResult) ProtoMessage()    {}
+func (*ValidateResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{8}
+}
+func (m *ValidateResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidateResult.Unmarshal(m, b)
+}
+func (m *ValidateResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidateResult.Marshal(b, m, deterministic)
+}
+func (dst *ValidateResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidateResult.Merge(dst, src)
+}
+func (m *ValidateResult) XXX_Size() int {
+	return xxx_messageInfo_ValidateResult.Size(m)
+}
+func (m *ValidateResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidateResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidateResult proto.InternalMessageInfo
+
+func (m *ValidateResult) GetSource() uint64 {
+	if m != nil {
+		return m.Source
+	}
+	return 0
+}
+
+func (m *ValidateResult) GetRequest() *mirbftpb.Request {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (m *ValidateResult) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+func (m *ValidateResult) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
 type Checkpoint struct {
 	SeqNo                uint64   `protobuf:"varint,1,opt,name=seq_no,json=seqNo,proto3" json:"seq_no,omitempty"`
 	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
@@ -725,7 +795,7 @@ func (m *Checkpoint) Reset()         { *m = Checkpoint{} }
 func (m *Checkpoint) String() string { return proto.CompactTextString(m) }
 func (*Checkpoint) ProtoMessage()    {}
 func (*Checkpoint) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{8}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{9}
 }
 func (m *Checkpoint) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Checkpoint.Unmarshal(m, b)
@@ -759,6 +829,106 @@ func (m *Checkpoint) GetValue() []byte {
 	return nil
 }
 
+type ClientWatermark struct {
+	ClientId             []byte   `protobuf:"bytes,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	LowWatermark         uint64   `protobuf:"varint,2,opt,name=low_watermark,json=lowWatermark,proto3" json:"low_watermark,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ClientWatermark) Reset()         { *m = ClientWatermark{} }
+func (m *ClientWatermark) String() string { return proto.CompactTextString(m) }
+func (*ClientWatermark) ProtoMessage()    {}
+func (*ClientWatermark) Descriptor() ([]byte, []int) {
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{10}
+}
+func (m *ClientWatermark) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ClientWatermark.Unmarshal(m, b)
+}
+func (m *ClientWatermark) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ClientWatermark.Marshal(b, m, deterministic)
+}
+func (dst *ClientWatermark) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ClientWatermark.Merge(dst, src)
+}
+func (m *ClientWatermark) XXX_Size() int {
+	return xxx_messageInfo_ClientWatermark.Size(m)
+}
+func (m *ClientWatermark) XXX_DiscardUnknown() {
+	xxx_messageInfo_ClientWatermark.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ClientWatermark proto.InternalMessageInfo
+
+func (m *ClientWatermark) GetClientId() []byte {
+	if m != nil {
+		return m.ClientId
+	}
+	return nil
+}
+
+func (m *ClientWatermark) GetLowWatermark() uint64 {
+	if m != nil {
+		return m.LowWatermark
+	}
+	return 0
+}
+
+type StateTransfer struct {
+	SeqNo                uint64             `protobuf:"varint,1,opt,name=seq_no,json=seqNo,proto3" json:"seq_no,omitempty"`
+	Value                []byte             `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	ClientWatermarks     []*ClientWatermark `protobuf:"bytes,3,rep,name=client_watermarks,json=clientWatermarks,proto3" json:"client_watermarks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *StateTransfer) Reset()         { *m = StateTransfer{} }
+func (m *StateTransfer) String() string { return proto.CompactTextString(m) }
+func (*StateTransfer) ProtoMessage()    {}
+func (*StateTransfer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{11}
+}
+func (m *StateTransfer) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StateTransfer.Unmarshal(m, b)
+}
+func (m *StateTransfer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StateTransfer.Marshal(b, m, deterministic)
+}
+func (dst *StateTransfer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StateTransfer.Merge(dst, src)
+}
+func (m *StateTransfer) XXX_Size() int {
+	return xxx_messageInfo_StateTransfer.Size(m)
+}
+func (m *StateTransfer) XXX_DiscardUnknown() {
+	xxx_messageInfo_StateTransfer.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StateTransfer proto.InternalMessageInfo
+
+func (m *StateTransfer) GetSeqNo() uint64 {
+	if m != nil {
+		return m.SeqNo
+	}
+	return 0
+}
+
+func (m *StateTransfer) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *StateTransfer) GetClientWatermarks() []*ClientWatermark {
+	if m != nil {
+		return m.ClientWatermarks
+	}
+	return nil
+}
+
 type Event struct {
 	Target uint64 `protobuf:"varint,1,opt,name=target,proto3" json:"target,omitempty"`
 	Time   uint64 `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
@@ -781,7 +951,7 @@ func (m *Event) Reset()         { *m = Event{} }
 func (m *Event) String() string { return proto.CompactTextString(m) }
 func (*Event) ProtoMessage()    {}
 func (*Event) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{9}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{12}
 }
 func (m *Event) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Event.Unmarshal(m, b)
@@ -1044,18 +1214,20 @@ func _Event_OneofSizer(msg proto.Message) (n int) {
 }
 
 type Event_Apply struct {
-	Digests              []*HashResult `protobuf:"bytes,1,rep,name=digests,proto3" json:"digests,omitempty"`
-	Checkpoints          []*Checkpoint `protobuf:"bytes,2,rep,name=checkpoints,proto3" json:"checkpoints,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
-	XXX_unrecognized     []byte        `json:"-"`
-	XXX_sizecache        int32         `json:"-"`
+	Digests              []*HashResult     `protobuf:"bytes,1,rep,name=digests,proto3" json:"digests,omitempty"`
+	Checkpoints          []*Checkpoint     `protobuf:"bytes,2,rep,name=checkpoints,proto3" json:"checkpoints,omitempty"`
+	Validations          []*ValidateResult `protobuf:"bytes,3,rep,name=validations,proto3" json:"validations,omitempty"`
+	StateTransfer        *StateTransfer    `protobuf:"bytes,4,opt,name=state_transfer,json=stateTransfer,proto3" json:"state_transfer,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *Event_Apply) Reset()         { *m = Event_Apply{} }
 func (m *Event_Apply) String() string { return proto.CompactTextString(m) }
 func (*Event_Apply) ProtoMessage()    {}
 func (*Event_Apply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{9, 0}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{12, 0}
 }
 func (m *Event_Apply) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Event_Apply.Unmarshal(m, b)
@@ -1089,6 +1261,20 @@ func (m *Event_Apply) GetCheckpoints() []*Checkpoint {
 	return nil
 }
 
+func (m *Event_Apply) GetValidations() []*ValidateResult {
+	if m != nil {
+		return m.Validations
+	}
+	return nil
+}
+
+func (m *Event_Apply) GetStateTransfer() *StateTransfer {
+	if m != nil {
+		return m.StateTransfer
+	}
+	return nil
+}
+
 type Event_Process struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -1099,7 +1285,7 @@ func (m *Event_Process) Reset()         { *m = Event_Process{} }
 func (m *Event_Process) String() string { return proto.CompactTextString(m) }
 func (*Event_Process) ProtoMessage()    {}
 func (*Event_Process) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{9, 1}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{12, 1}
 }
 func (m *Event_Process) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Event_Process.Unmarshal(m, b)
@@ -1130,7 +1316,7 @@ func (m *Event_Propose) Reset()         { *m = Event_Propose{} }
 func (m *Event_Propose) String() string { return proto.CompactTextString(m) }
 func (*Event_Propose) ProtoMessage()    {}
 func (*Event_Propose) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{9, 2}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{12, 2}
 }
 func (m *Event_Propose) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Event_Propose.Unmarshal(m, b)
@@ -1169,7 +1355,7 @@ func (m *Event_Receive) Reset()         { *m = Event_Receive{} }
 func (m *Event_Receive) String() string { return proto.CompactTextString(m) }
 func (*Event_Receive) ProtoMessage()    {}
 func (*Event_Receive) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{9, 3}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{12, 3}
 }
 func (m *Event_Receive) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Event_Receive.Unmarshal(m, b)
@@ -1213,7 +1399,7 @@ func (m *Event_Tick) Reset()         { *m = Event_Tick{} }
 func (m *Event_Tick) String() string { return proto.CompactTextString(m) }
 func (*Event_Tick) ProtoMessage()    {}
 func (*Event_Tick) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{9, 4}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{12, 4}
 }
 func (m *Event_Tick) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Event_Tick.Unmarshal(m, b)
@@ -1247,7 +1433,7 @@ func (m *LogEntry) Reset()         { *m = LogEntry{} }
 func (m *LogEntry) String() string { return proto.CompactTextString(m) }
 func (*LogEntry) ProtoMessage()    {}
 func (*LogEntry) Descriptor() ([]byte, []int) {
-	return fileDescriptor_testengine_f4361311d72ec22d, []int{10}
+	return fileDescriptor_testengine_9ec249b7a2c135ec, []int{13}
 }
 func (m *LogEntry) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LogEntry.Unmarshal(m, b)
@@ -1387,7 +1573,10 @@ func init() {
 	proto.RegisterType((*Batch)(nil), "testenginepb.Batch")
 	proto.RegisterType((*VerifyBatch)(nil), "testenginepb.VerifyBatch")
 	proto.RegisterType((*EpochChange)(nil), "testenginepb.EpochChange")
+	proto.RegisterType((*ValidateResult)(nil), "testenginepb.ValidateResult")
 	proto.RegisterType((*Checkpoint)(nil), "testenginepb.Checkpoint")
+	proto.RegisterType((*ClientWatermark)(nil), "testenginepb.ClientWatermark")
+	proto.RegisterType((*StateTransfer)(nil), "testenginepb.StateTransfer")
 	proto.RegisterType((*Event)(nil), "testenginepb.Event")
 	proto.RegisterType((*Event_Apply)(nil), "testenginepb.Event.Apply")
 	proto.RegisterType((*Event_Process)(nil), "testenginepb.Event.Process")
@@ -1397,71 +1586,83 @@ func init() {
 	proto.RegisterType((*LogEntry)(nil), "testenginepb.LogEntry")
 }
 
-func init() { proto.RegisterFile("testengine.proto", fileDescriptor_testengine_f4361311d72ec22d) }
-
-var fileDescriptor_testengine_f4361311d72ec22d = []byte{
-	// 1004 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x5b, 0x6f, 0x1b, 0x45,
-	0x14, 0x8e, 0xed, 0x5d, 0x3b, 0x3d, 0xeb, 0xb8, 0x30, 0x75, 0xd2, 0xc5, 0x45, 0x34, 0x98, 0x87,
-	0x46, 0xaa, 0x64, 0xd4, 0x20, 0x28, 0x14, 0x09, 0xa9, 0x49, 0x23, 0x19, 0xa9, 0x8d, 0xd0, 0x34,
-	0xe2, 0x75, 0xb5, 0xde, 0x3d, 0xb6, 0x47, 0x76, 0x66, 0x37, 0x3b, 0x63, 0x1b, 0x17, 0xf1, 0xc8,
-	0xdf, 0xe0, 0x8d, 0x3f, 0xd4, 0x27, 0x7e, 0x0e, 0x9a, 0xcb, 0x5e, 0x9c, 0x6e, 0x80, 0x07, 0xde,
-	0xe6, 0x9c, 0xf3, 0x9d, 0xfb, 0x65, 0x17, 0x3e, 0x92, 0x28, 0x24, 0xf2, 0x19, 0xe3, 0x38, 0x4a,
-	0xb3, 0x44, 0x26, 0xa4, 0x5b, 0x72, 0xd2, 0xc9, 0xe0, 0xf0, 0x9a, 0x65, 0x93, 0xa9, 0x4c, 0x27,
-	0x5f, 0x9a, 0x87, 0x01, 0x0d, 0xdf, 0x37, 0xa0, 0xf7, 0x36, 0x42, 0x1e, 0x66, 0x2c, 0x39, 0x4f,
-	0xf8, 0x94, 0xcd, 0x08, 0x01, 0x87, 0x87, 0xd7, 0xe8, 0x37, 0x8e, 0x1b, 0x27, 0xf7, 0xa8, 0x7e,
-	0x93, 0x63, 0xf0, 0x62, 0x14, 0x51, 0xc6, 0x52, 0xc9, 0x12, 0xee, 0x37, 0xb5, 0xa8, 0xca, 0x22,
-	0x6f, 0xe0, 0x88, 0x71, 0x26, 0x59, 0xb8, 0x0c, 0x38, 0xca, 0x4d, 0x92, 0x2d, 0x82, 0x48, 0xdb,
-	0xf3, 0x5b, 0xc7, 0x8d, 0x13, 0xef, 0xf4, 0xe1, 0x28, 0x0f, 0x60, 0x74, 0x69, 0xe4, 0xc6, 0x1d,
-	0xed, 0x5b, 0xb5, 0x1d, 0x2e, 0xf9, 0x1e, 0xba, 0x3c, 0x89, 0xd1, 0xda, 0x10, 0xbe, 0x73, 0xdc,
-	0x3a, 0xf1, 0x4e, 0xfd, 0x51, 0x35, 0xa7, 0xd1, 0x65, 0x12, 0xa3, 0xb5, 0xe2, 0xf1, 0xe2, 0x2d,
-	0x86, 0x7f, 0x35, 0x01, 0x4a, 0x19, 0xe9, 0x41, 0x93, 0xc5, 0x3a, 0x1d, 0x87, 0x36, 0x59, 0x4c,
-	0x9e, 0xc0, 0xfd, 0x39, 0x86, 0x99, 0x9c, 0x60, 0x28, 0x03, 0xc9, 0xa2, 0x85, 0xd0, 0x09, 0xb9,
-	0xb4, 0x57, 0xb0, 0xaf, 0x14, 0x97, 0x7c, 0x01, 0x07, 0x62, 0x25, 0x52, 0x8c, 0x72, 0x58, 0x4b,
-	0xc3, 0xba, 0x96, 0x69, 0x40, 0x5f, 0xc3, 0x43, 0x8e, 0x9b, 0x00, 0xd3, 0x24, 0x9a, 0x07, 0x92,
-	0x5d, 0x63, 0xb2, 0xca, 0xe1, 0x8e, 0x86, 0xf7, 0x39, 0x6e, 0x2e, 0x94, 0xf4, 0xca, 0x08, 0x0b,
-	0xdb, 0x0a, 0x14, 0x30, 0x2e, 0x31, 0x5b, 0x87, 0x4b, 0xdf, 0x35, 0xb6, 0x15, 0xf3, 0x47, 0xcb,
-	0x23, 0x9f, 0x43, 0x77, 0xc9, 0xf8, 0x22, 0x58, 0x86, 0x12, 0x79, 0xb4, 0xf5, 0xdb, 0x1a, 0xe3,
-	0x29, 0xde, 0x6b, 0xc3, 0x52, 0x76, 0x32, 0x0c, 0xe3, 0x6d, 0x81, 0xe9, 0x18, 0x3b, 0x9a, 0x99,
-	0x83, 0x9e, 0xc0, 0xfd, 0x34, 0x4b, 0x22, 0x14, 0xa2, 0x80, 0xed, 0x9b, 0x8c, 0x2d, 0x3b, 0x07,
-	0x3e, 0x06, 0x6f, 0xb2, 0x9a, 0x4e, 0x31, 0x0b, 0x04, 0x7b, 0x87, 0xfe, 0x3d, 0x0d, 0x02, 0xc3,
-	0x7a, 0xcb, 0xde, 0xe1, 0xf0, 0x7d, 0x13, 0x60, 0x1c, 0x8a, 0x39, 0x45, 0xb1, 0x5a, 0x4a, 0x72,
-	0x04, 0xed, 0x98, 0xcd, 0x50, 0x48, 0x5d, 0xde, 0x2e, 0xb5, 0x14, 0x79, 0x06, 0x9d, 0x0c, 0x6f,
-	0x56, 0x4a, 0xd0, 0xd4, 0xed, 0x3f, 0xdc, 0xed, 0x1c, 0x35, 0xc2, 0xf1, 0x1e, 0xcd, 0x71, 0xe4,
-	0x29, 0xb8, 0x93, 0x50, 0x46, 0x73, 0x3b, 0x2f, 0x0f, 0x76, 0x15, 0xce, 0x94, 0x68, 0xbc, 0x47,
-	0x0d, 0x86, 0xfc, 0x00, 0x5d, 0x53, 0xf0, 0x68, 0x1e, 0xf2, 0x19, 0xea, 0x4a, 0x7b, 0xa7, 0x9f,
-	0xec, 0xea, 0xe8, 0xa2, 0x9f, 0x6b, 0xc0, 0x78, 0x8f, 0x7a, 0x58, 0x92, 0x4a, 0x7f, 0x8d, 0x19,
-	0x9b, 0x6e, 0x03, 0xe3, 0xd3, 0xad, 0xd3, 0xff, 0x59, 0x23, 0x72, 0xcf, 0xde, 0xba, 0x24, 0xc9,
-	0x2b, 0xe8, 0x59, 0xfd, 0x3c, 0xcd, 0xb6, 0xb6, 0xf0, 0xa8, 0xce, 0x42, 0x99, 0xec, 0xc1, 0xba,
-	0xca, 0x38, 0x6b, 0x83, 0x23, 0xb7, 0x29, 0x0e, 0x2f, 0xa1, 0x63, 0x59, 0xaa, 0xa0, 0x22, 0x59,
-	0x65, 0x11, 0xda, 0x79, 0xb5, 0x14, 0x79, 0x7a, 0xbb, 0xa0, 0x1f, 0x97, 0xfb, 0x64, 0x75, 0x8b,
-	0x52, 0x0e, 0x7f, 0x83, 0x83, 0x1d, 0xcf, 0xff, 0x8b, 0x55, 0x35, 0x44, 0xf8, 0x8b, 0x9a, 0x7b,
-	0x8c, 0x03, 0xdb, 0xf4, 0x96, 0x6e, 0x7a, 0x2f, 0x67, 0xbf, 0xd2, 0xdc, 0xe1, 0xef, 0x0d, 0x70,
-	0x4d, 0x99, 0xee, 0xf2, 0xdb, 0x07, 0x57, 0x77, 0x43, 0x7b, 0x75, 0xa8, 0x21, 0xc8, 0x21, 0xb4,
-	0x05, 0xde, 0x04, 0x3c, 0xd1, 0x76, 0x1d, 0xea, 0x0a, 0xbc, 0xb9, 0x4c, 0xc8, 0x73, 0xe8, 0xda,
-	0x10, 0x82, 0x50, 0x6d, 0x95, 0xab, 0x4f, 0x41, 0xff, 0x83, 0x48, 0x5f, 0x46, 0x0b, 0xea, 0x65,
-	0xc5, 0x5b, 0x0c, 0xff, 0x68, 0x80, 0x57, 0xe9, 0xe1, 0x9d, 0xd1, 0x94, 0x7e, 0x9b, 0xff, 0xe4,
-	0xb7, 0xf5, 0x1f, 0xfd, 0xd6, 0x15, 0xca, 0xa9, 0x2d, 0xd4, 0x06, 0xbc, 0xca, 0x8c, 0xde, 0x19,
-	0xdf, 0x11, 0xb4, 0x93, 0x8c, 0xcd, 0x18, 0xb7, 0xf1, 0x59, 0x8a, 0x7c, 0x7b, 0x6b, 0x09, 0x5a,
-	0x76, 0xd3, 0x8a, 0x00, 0x2b, 0xc6, 0x77, 0xc6, 0x7f, 0xf8, 0x1d, 0xc0, 0xf9, 0x1c, 0xa3, 0x45,
-	0x9a, 0x30, 0x2e, 0x2b, 0xf9, 0x37, 0xaa, 0xf9, 0xf7, 0xc1, 0x5d, 0x87, 0xcb, 0x15, 0x6a, 0xaf,
-	0x5d, 0x6a, 0x88, 0xe1, 0x9f, 0x2e, 0xb8, 0x17, 0x6b, 0xe4, 0x7a, 0xa8, 0x64, 0x98, 0xcd, 0x50,
-	0xe6, 0xe1, 0x1a, 0x4a, 0x7d, 0x3f, 0xd4, 0x19, 0xb4, 0xc1, 0xea, 0x37, 0x79, 0x06, 0x6e, 0x98,
-	0xa6, 0xcb, 0xad, 0x8d, 0xf1, 0xf6, 0xa2, 0x2a, 0x7b, 0xa3, 0x97, 0x0a, 0xa0, 0x56, 0x5c, 0x23,
-	0xc9, 0x73, 0x35, 0x9b, 0x11, 0xb2, 0x75, 0xbe, 0xdd, 0x8f, 0xea, 0x94, 0xa8, 0x81, 0x98, 0x43,
-	0xa2, 0x9f, 0x4a, 0xd1, 0x5e, 0x35, 0xbb, 0xd6, 0xb5, 0x8a, 0x3f, 0x19, 0x88, 0x52, 0xb4, 0x68,
-	0xab, 0x98, 0x26, 0x02, 0xeb, 0xb7, 0xb9, 0x50, 0x54, 0x10, 0xab, 0xa8, 0x9e, 0x64, 0xa4, 0x32,
-	0x8e, 0x16, 0xfa, 0xf4, 0x7e, 0xf0, 0x91, 0x32, 0x5a, 0xea, 0xe8, 0x8f, 0xf7, 0xa8, 0xc6, 0x11,
-	0x1f, 0x3a, 0x71, 0x96, 0xa4, 0x29, 0xc6, 0xfa, 0x0c, 0xef, 0xd3, 0x9c, 0xd4, 0x12, 0x5c, 0x86,
-	0x5b, 0x8c, 0xf5, 0xed, 0x75, 0x68, 0x4e, 0x92, 0xcf, 0x00, 0xe2, 0x55, 0xba, 0x64, 0x51, 0x28,
-	0x31, 0xf6, 0x41, 0x0b, 0x2b, 0x9c, 0xc1, 0x06, 0x5c, 0x5d, 0x40, 0x72, 0x0a, 0x1d, 0x33, 0x74,
-	0xc2, 0x6f, 0xd4, 0x7d, 0x34, 0xcb, 0xeb, 0x4d, 0x73, 0x20, 0x79, 0x01, 0x5e, 0x54, 0xcc, 0x83,
-	0xfa, 0x1a, 0xd6, 0xe8, 0x95, 0x03, 0x43, 0xab, 0xe0, 0xc1, 0x3d, 0xe8, 0xd8, 0x5a, 0x0e, 0xbe,
-	0xd1, 0x4f, 0x5d, 0x92, 0xca, 0x65, 0x69, 0xfc, 0xdb, 0x65, 0x19, 0x9c, 0xa9, 0xfb, 0x67, 0x9a,
-	0x77, 0xd7, 0x0e, 0x3c, 0x86, 0xd6, 0xb5, 0x98, 0xd9, 0x2b, 0x75, 0x50, 0xda, 0x7a, 0x23, 0x66,
-	0x54, 0x49, 0x06, 0x6d, 0x70, 0x54, 0x8d, 0x8b, 0x9b, 0xfa, 0x2b, 0xec, 0xbf, 0x4e, 0x66, 0x17,
-	0x5c, 0x66, 0x5b, 0xf2, 0x02, 0xf6, 0x85, 0xfd, 0xc7, 0xb1, 0xd1, 0x7c, 0xba, 0x9b, 0xdb, 0xee,
-	0x1f, 0xd0, 0x78, 0x8f, 0x16, 0x78, 0xf5, 0x59, 0x42, 0xd5, 0x41, 0xeb, 0xfa, 0x41, 0x4d, 0x73,
-	0xd5, 0xcc, 0x6a, 0x8c, 0x72, 0x7e, 0xb5, 0x4d, 0x71, 0xd2, 0xd6, 0x3f, 0x57, 0x5f, 0xfd, 0x1d,
-	0x00, 0x00, 0xff, 0xff, 0x0c, 0xb8, 0x25, 0xde, 0x95, 0x09, 0x00, 0x00,
+func init() { proto.RegisterFile("testengine.proto", fileDescriptor_testengine_9ec249b7a2c135ec) }
+
+var fileDescriptor_testengine_9ec249b7a2c135ec = []byte{
+	// 1196 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x6d, 0x6f, 0x1b, 0xc5,
+	0x13, 0xcf, 0xc5, 0x3e, 0xc7, 0x99, 0x73, 0xdc, 0x76, 0xff, 0x49, 0x7b, 0xff, 0x14, 0x68, 0x30,
+	0x2f, 0x1a, 0xa9, 0x92, 0x51, 0x83, 0xa0, 0x50, 0xa4, 0x4a, 0x4d, 0x5a, 0x29, 0x45, 0x6d, 0x8a,
+	0x36, 0x51, 0x79, 0x79, 0x3a, 0xdf, 0x8d, 0x9d, 0x95, 0x2f, 0xb7, 0xd7, 0xdd, 0xb5, 0x8d, 0x8b,
+	0x78, 0x85, 0x90, 0x90, 0xf8, 0x0e, 0x7c, 0x00, 0x3e, 0x4e, 0xbf, 0x0e, 0x6f, 0xd0, 0x3e, 0xdc,
+	0xf9, 0x9c, 0x3a, 0x05, 0x04, 0xef, 0x76, 0x66, 0x7e, 0xf3, 0xb0, 0x33, 0xb3, 0x33, 0x0b, 0xd7,
+	0x15, 0x4a, 0x85, 0xf9, 0x88, 0xe5, 0xd8, 0x2f, 0x04, 0x57, 0x9c, 0x74, 0x16, 0x9c, 0x62, 0xb0,
+	0xbb, 0x73, 0xc1, 0xc4, 0x60, 0xa8, 0x8a, 0xc1, 0xa7, 0xf6, 0x60, 0x41, 0xbd, 0xb7, 0x1e, 0x74,
+	0x4f, 0x13, 0xcc, 0x63, 0xc1, 0xf8, 0x11, 0xcf, 0x87, 0x6c, 0x44, 0x08, 0x34, 0xf3, 0xf8, 0x02,
+	0x43, 0x6f, 0xcf, 0xdb, 0xdf, 0xa4, 0xe6, 0x4c, 0xf6, 0x20, 0x48, 0x51, 0x26, 0x82, 0x15, 0x8a,
+	0xf1, 0x3c, 0x5c, 0x37, 0xa2, 0x3a, 0x8b, 0xbc, 0x80, 0x9b, 0x2c, 0x67, 0x8a, 0xc5, 0x59, 0x94,
+	0xa3, 0x9a, 0x71, 0x31, 0x8e, 0x12, 0x63, 0x2f, 0x6c, 0xec, 0x79, 0xfb, 0xc1, 0xc1, 0xad, 0x7e,
+	0x19, 0x40, 0xff, 0xc4, 0xca, 0xad, 0x3b, 0xba, 0xed, 0xd4, 0x96, 0xb8, 0xe4, 0x6b, 0xe8, 0xe4,
+	0x3c, 0x45, 0x67, 0x43, 0x86, 0xcd, 0xbd, 0xc6, 0x7e, 0x70, 0x10, 0xf6, 0xeb, 0x77, 0xea, 0x9f,
+	0xf0, 0x14, 0x9d, 0x95, 0x20, 0xaf, 0xce, 0xb2, 0xf7, 0x6b, 0x03, 0x60, 0x21, 0x23, 0x5d, 0x58,
+	0x67, 0xa9, 0xb9, 0x4e, 0x93, 0xae, 0xb3, 0x94, 0xdc, 0x85, 0x6b, 0xe7, 0x18, 0x0b, 0x35, 0xc0,
+	0x58, 0x45, 0x8a, 0x25, 0x63, 0x69, 0x2e, 0xe4, 0xd3, 0x6e, 0xc5, 0x3e, 0xd3, 0x5c, 0xf2, 0x09,
+	0x6c, 0xc9, 0x89, 0x2c, 0x30, 0x29, 0x61, 0x0d, 0x03, 0xeb, 0x38, 0xa6, 0x05, 0x7d, 0x0e, 0xb7,
+	0x72, 0x9c, 0x45, 0x58, 0xf0, 0xe4, 0x3c, 0x52, 0xec, 0x02, 0xf9, 0xa4, 0x84, 0x37, 0x0d, 0x7c,
+	0x3b, 0xc7, 0xd9, 0x53, 0x2d, 0x3d, 0xb3, 0xc2, 0xca, 0xb6, 0x06, 0x45, 0x2c, 0x57, 0x28, 0xa6,
+	0x71, 0x16, 0xfa, 0xd6, 0xb6, 0x66, 0x3e, 0x73, 0x3c, 0xf2, 0x31, 0x74, 0x32, 0x96, 0x8f, 0xa3,
+	0x2c, 0x56, 0x98, 0x27, 0xf3, 0xb0, 0x65, 0x30, 0x81, 0xe6, 0x3d, 0xb7, 0x2c, 0x6d, 0x47, 0x60,
+	0x9c, 0xce, 0x2b, 0xcc, 0x86, 0xb5, 0x63, 0x98, 0x25, 0xe8, 0x2e, 0x5c, 0x2b, 0x04, 0x4f, 0x50,
+	0xca, 0x0a, 0xd6, 0xb6, 0x37, 0x76, 0xec, 0x12, 0x78, 0x07, 0x82, 0xc1, 0x64, 0x38, 0x44, 0x11,
+	0x49, 0xf6, 0x06, 0xc3, 0x4d, 0x03, 0x02, 0xcb, 0x3a, 0x65, 0x6f, 0x90, 0xdc, 0x87, 0x1d, 0x7d,
+	0x3f, 0x3e, 0x8c, 0xb8, 0x48, 0x51, 0x44, 0x29, 0x66, 0x6c, 0x8a, 0x62, 0x1e, 0xc2, 0x9e, 0xb7,
+	0xdf, 0xa6, 0x84, 0x4f, 0xd4, 0xcb, 0xe1, 0x4b, 0x2d, 0x7a, 0xe2, 0x24, 0xbd, 0xb7, 0xeb, 0x00,
+	0xc7, 0xb1, 0x3c, 0xa7, 0x28, 0x27, 0x99, 0x22, 0x37, 0xa1, 0x95, 0xb2, 0x11, 0x4a, 0x65, 0x2a,
+	0xd2, 0xa1, 0x8e, 0x22, 0xf7, 0x61, 0x43, 0xe0, 0xeb, 0x89, 0x16, 0xac, 0x9b, 0x8e, 0xd9, 0x59,
+	0x2e, 0x36, 0xb5, 0xc2, 0xe3, 0x35, 0x5a, 0xe2, 0xc8, 0x3d, 0xf0, 0x07, 0xb1, 0x4a, 0xce, 0x5d,
+	0x8b, 0xfd, 0x6f, 0x59, 0xe1, 0x50, 0x8b, 0x8e, 0xd7, 0xa8, 0xc5, 0x90, 0x47, 0xd0, 0xb1, 0x35,
+	0x4a, 0xce, 0xe3, 0x7c, 0x84, 0xa6, 0x38, 0xc1, 0xc1, 0xff, 0x97, 0x75, 0x4c, 0x9d, 0x8e, 0x0c,
+	0xe0, 0x78, 0x8d, 0x06, 0xb8, 0x20, 0xb5, 0xfe, 0x14, 0x05, 0x1b, 0xce, 0x23, 0xeb, 0xd3, 0x5f,
+	0xa5, 0xff, 0xca, 0x20, 0x4a, 0xcf, 0xc1, 0x74, 0x41, 0x92, 0x27, 0xd0, 0x75, 0xfa, 0xe5, 0x35,
+	0x5b, 0xc6, 0xc2, 0xed, 0x55, 0x16, 0x16, 0x97, 0xdd, 0x9a, 0xd6, 0x19, 0x87, 0x2d, 0x68, 0xaa,
+	0x79, 0x81, 0xbd, 0x13, 0xd8, 0x70, 0x2c, 0x9d, 0x50, 0xc9, 0x27, 0x22, 0x41, 0xd7, 0xe2, 0x8e,
+	0x22, 0xf7, 0x2e, 0x27, 0xf4, 0xc6, 0xe2, 0x09, 0x3a, 0xdd, 0x2a, 0x95, 0xbd, 0x1f, 0x61, 0x6b,
+	0xc9, 0xf3, 0x7f, 0x62, 0x55, 0xf7, 0x1d, 0x7e, 0xaf, 0x9f, 0x0a, 0xa6, 0x91, 0x2b, 0x7a, 0xc3,
+	0x14, 0xbd, 0x5b, 0xb2, 0x9f, 0x18, 0x6e, 0xef, 0x67, 0x0f, 0x7c, 0x9b, 0xa6, 0xab, 0xfc, 0x6e,
+	0x83, 0x6f, 0xaa, 0x61, 0xbc, 0x36, 0xa9, 0x25, 0xc8, 0x0e, 0xb4, 0x24, 0xbe, 0x8e, 0x72, 0x6e,
+	0xec, 0x36, 0xa9, 0x2f, 0xf1, 0xf5, 0x09, 0x27, 0x0f, 0xa0, 0xe3, 0x42, 0x88, 0x62, 0xfd, 0x10,
+	0x7d, 0x33, 0x3d, 0xb6, 0xdf, 0x89, 0xf4, 0x71, 0x32, 0xa6, 0x81, 0xa8, 0xce, 0xb2, 0xf7, 0x9b,
+	0x07, 0x41, 0xad, 0x86, 0x57, 0x46, 0xb3, 0xf0, 0xbb, 0xfe, 0x3e, 0xbf, 0x8d, 0xbf, 0xe9, 0x77,
+	0x55, 0xa2, 0x9a, 0x2b, 0x13, 0x35, 0x83, 0xa0, 0xd6, 0xa3, 0x57, 0xc6, 0x77, 0x13, 0x5a, 0x5c,
+	0xb0, 0x11, 0xcb, 0x5d, 0x7c, 0x8e, 0x22, 0x5f, 0x5e, 0x7a, 0x04, 0x0d, 0xf7, 0xd2, 0xaa, 0x00,
+	0x6b, 0xc6, 0x97, 0xda, 0xbf, 0xf7, 0x93, 0x07, 0xdd, 0x57, 0x71, 0xc6, 0xd2, 0x58, 0xe1, 0xe2,
+	0x25, 0xff, 0xfb, 0x16, 0x59, 0x8c, 0x83, 0xc6, 0xd2, 0x38, 0xd8, 0x06, 0x7f, 0xaa, 0xdd, 0x99,
+	0x3c, 0xb4, 0xa9, 0x25, 0x7a, 0x5f, 0x01, 0x1c, 0x9d, 0x63, 0x32, 0x2e, 0x38, 0xcb, 0x55, 0xad,
+	0x0a, 0x5e, 0xbd, 0x0a, 0x56, 0x75, 0x82, 0xc6, 0x7b, 0x87, 0x5a, 0xa2, 0x77, 0x0a, 0xd7, 0x8e,
+	0x32, 0x86, 0xb9, 0xfa, 0x2e, 0x56, 0x28, 0x2e, 0x62, 0x31, 0x26, 0xb7, 0x61, 0x33, 0x31, 0xac,
+	0xc8, 0xed, 0x87, 0x0e, 0x6d, 0x5b, 0xc6, 0xb3, 0x54, 0x0f, 0xd6, 0x8c, 0xcf, 0xa2, 0x59, 0x89,
+	0x76, 0x99, 0xec, 0x64, 0x7c, 0x56, 0x59, 0xe8, 0xfd, 0xe2, 0xc1, 0xd6, 0xa9, 0x8a, 0x15, 0x9e,
+	0x89, 0x38, 0x97, 0x43, 0x14, 0xff, 0x28, 0x26, 0xf2, 0x0d, 0xdc, 0x70, 0x01, 0x54, 0x6e, 0xca,
+	0xa6, 0xf9, 0x70, 0x79, 0x2c, 0x5c, 0x0a, 0x9d, 0x5e, 0x4f, 0x96, 0x19, 0xb2, 0xf7, 0x7b, 0x0b,
+	0xfc, 0xa7, 0x53, 0xcc, 0x4d, 0x4a, 0x55, 0x2c, 0x46, 0xa8, 0xca, 0xba, 0x58, 0x4a, 0x2f, 0x76,
+	0xbd, 0x9f, 0xdc, 0x45, 0xcc, 0x99, 0xdc, 0x07, 0x3f, 0x2e, 0x8a, 0x6c, 0x1e, 0x36, 0x56, 0x8d,
+	0x33, 0x63, 0xaf, 0xff, 0x58, 0x03, 0xf4, 0x20, 0x35, 0x48, 0xf2, 0x40, 0x97, 0x37, 0x41, 0x36,
+	0x2d, 0x67, 0xe8, 0xed, 0x55, 0x4a, 0xd4, 0x42, 0xec, 0xb8, 0x36, 0x47, 0xad, 0xe8, 0xd6, 0x4d,
+	0xe8, 0x5f, 0xad, 0xf8, 0xad, 0x85, 0x68, 0x45, 0x87, 0x76, 0x8a, 0x05, 0x97, 0x18, 0xb6, 0xde,
+	0xab, 0xa8, 0x21, 0x4e, 0x51, 0x1f, 0x49, 0x5f, 0xdf, 0x38, 0x19, 0x9b, 0x9d, 0xf8, 0xce, 0xef,
+	0xc1, 0x6a, 0xe9, 0x6d, 0x7c, 0xbc, 0x46, 0x0d, 0x8e, 0x84, 0xb0, 0x91, 0x0a, 0x5e, 0x14, 0x98,
+	0x9a, 0xfd, 0xd8, 0xa6, 0x25, 0x69, 0x24, 0x98, 0xc5, 0x73, 0x4c, 0xcd, 0x52, 0x6c, 0xd2, 0x92,
+	0x24, 0x1f, 0x01, 0xa4, 0x93, 0x22, 0x63, 0x49, 0xac, 0x30, 0x35, 0x6b, 0xb0, 0x49, 0x6b, 0x9c,
+	0xdd, 0x3f, 0x3c, 0xf0, 0x4d, 0x06, 0xc9, 0x01, 0x6c, 0xd8, 0xe6, 0x96, 0xa1, 0xb7, 0xea, 0x3b,
+	0xb3, 0x58, 0x92, 0xb4, 0x04, 0x92, 0x87, 0x10, 0x24, 0x55, 0xc3, 0xeb, 0x7f, 0xca, 0x0a, 0xbd,
+	0xc5, 0x8b, 0xa0, 0x75, 0x30, 0x79, 0x04, 0xc1, 0xd4, 0xbe, 0x58, 0xc6, 0xf3, 0xb2, 0xaf, 0x3e,
+	0xb8, 0xb4, 0x6e, 0x96, 0x9e, 0x34, 0xad, 0x2b, 0x90, 0x43, 0xe8, 0x4a, 0xdd, 0xdb, 0x91, 0x72,
+	0xcd, 0xbd, 0xba, 0xde, 0x4b, 0xfd, 0x4f, 0xb7, 0x64, 0x9d, 0xdc, 0xdd, 0x84, 0x0d, 0x57, 0xd0,
+	0xdd, 0x2f, 0xcc, 0xd1, 0xd4, 0xa5, 0x36, 0x21, 0xbc, 0xbf, 0x9a, 0x10, 0xbb, 0x87, 0x7a, 0xd5,
+	0xd9, 0x0e, 0xba, 0x6a, 0xe2, 0xdc, 0x81, 0xc6, 0x85, 0x1c, 0xb9, 0x69, 0xb3, 0xb5, 0xb0, 0xf5,
+	0x42, 0x8e, 0xa8, 0x96, 0xec, 0xb6, 0xa0, 0xa9, 0x0b, 0x5d, 0xad, 0xcf, 0x1f, 0xa0, 0xfd, 0x9c,
+	0x8f, 0x9e, 0xe6, 0x4a, 0xcc, 0xc9, 0x43, 0x68, 0x4b, 0xf7, 0x03, 0x76, 0xd1, 0x5c, 0xca, 0xd1,
+	0xf2, 0xff, 0xf8, 0x78, 0x8d, 0x56, 0x78, 0xfd, 0x03, 0x41, 0xdd, 0x46, 0xe1, 0xfa, 0xaa, 0x1f,
+	0x88, 0xe9, 0x30, 0xfd, 0x70, 0x0c, 0x46, 0x3b, 0x3f, 0x9b, 0x17, 0x38, 0x68, 0x99, 0xaf, 0xf7,
+	0x67, 0x7f, 0x0e, 0x00, 0x23, 0xf1, 0x42, 0x5f, 0xb3, 0x0b, 0x00, 0x00,
 }
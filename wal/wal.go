@@ -0,0 +1,613 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package wal provides a file backed write-ahead log which implements
+// mirbft.Storage, and durably persists the entries of Actions.Persisted.
+//
+// The log is written as a series of segment files in a single directory.  Each
+// segment begins with a short header, followed by records, each of which is the
+// length and CRC of a marshaled pb.Persisted entry, followed by the entry.  A
+// record which was torn by a crash while it was being written to the last
+// segment is discarded when the log is opened, as it was never synced, and so
+// could not have been acted upon.  A record which fails its CRC anywhere else
+// indicates the log is corrupt.
+//
+// As the state machine garbage collects its persisted state below the low
+// watermark, it sets Actions.Truncate, and Persist truncates the log to match.
+// Truncation writes the entries which remain required into a new base segment,
+// and only once it has been synced, removes the segments which precede it.
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// DefaultSegmentSizeBytes is the size beyond which a new segment is begun,
+// if no other size is configured.
+const DefaultSegmentSizeBytes = 64 * 1024 * 1024
+
+const (
+	segmentSuffix    = ".wal"
+	tmpSuffix        = ".tmp"
+	headerSize       = 8
+	recordHeaderSize = 8
+	version          = 1
+	baseFlag         = 1
+)
+
+var (
+	magic    = []byte("MWAL")
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Config describes how the log lays out its segments.
+type Config struct {
+	// SegmentSizeBytes is the size beyond which a new segment is begun.  Zero
+	// selects DefaultSegmentSizeBytes.
+	SegmentSizeBytes int64
+}
+
+// WAL is a write-ahead log of persisted entries.  It retains the entries in
+// memory, so that they may be loaded and truncated without reading back the
+// segments.  If writing to the log fails, the log must be closed and reopened
+// before it is used again.
+type WAL struct {
+	mutex            sync.Mutex
+	dir              string
+	segmentSizeBytes int64
+	entries          []*pb.Persisted
+	segments         []uint64 // The numbers of the segments, ascending
+	file             *os.File // The last segment, open for appending
+	writer           *bufio.Writer
+	size             int64 // The size of the last segment, including buffered writes
+}
+
+// Open opens the log in the given directory, creating it if it does not exist.
+// If the segments are malformed, the cause of the returned error is
+// mirbft.ErrCorruptStorage.
+func Open(dir string, config *Config) (*WAL, error) {
+	segmentSizeBytes := int64(DefaultSegmentSizeBytes)
+	if config != nil && config.SegmentSizeBytes > 0 {
+		segmentSizeBytes = config.SegmentSizeBytes
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.WithMessage(err, "could not create log directory")
+	}
+
+	w := &WAL{
+		dir:              dir,
+		segmentSizeBytes: segmentSizeBytes,
+	}
+
+	if err := w.removeSuperseded(); err != nil {
+		return nil, err
+	}
+
+	if len(w.segments) == 0 {
+		if err := w.createSegment(0, true, nil); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	for i, number := range w.segments {
+		last := i == len(w.segments)-1
+		entries, size, err := readSegment(w.segmentPath(number), last)
+		if err != nil {
+			return nil, err
+		}
+		w.entries = append(w.entries, entries...)
+
+		if last {
+			if err := w.openSegment(number, size); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return w, nil
+}
+
+// removeSuperseded lists the segments, removing any left over by a truncation
+// which did not complete, and any which precede the latest base segment.
+func (w *WAL) removeSuperseded() error {
+	files, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return errors.WithMessage(err, "could not list log directory")
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		switch {
+		case strings.HasSuffix(name, segmentSuffix+tmpSuffix):
+			if err := os.Remove(filepath.Join(w.dir, name)); err != nil {
+				return errors.WithMessage(err, "could not remove incomplete segment")
+			}
+		case strings.HasSuffix(name, segmentSuffix):
+			var number uint64
+			if _, err := fmt.Sscanf(name, "%016x"+segmentSuffix, &number); err != nil {
+				continue
+			}
+			w.segments = append(w.segments, number)
+		}
+	}
+
+	sort.Slice(w.segments, func(i, j int) bool {
+		return w.segments[i] < w.segments[j]
+	})
+
+	for i := len(w.segments) - 1; i > 0; i-- {
+		base, err := isBaseSegment(w.segmentPath(w.segments[i]))
+		if err != nil {
+			return err
+		}
+
+		if !base {
+			continue
+		}
+
+		for _, number := range w.segments[:i] {
+			if err := os.Remove(w.segmentPath(number)); err != nil {
+				return errors.WithMessage(err, "could not remove superseded segment")
+			}
+		}
+		w.segments = w.segments[i:]
+		return syncDir(w.dir)
+	}
+
+	return nil
+}
+
+func (w *WAL) segmentPath(number uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%016x%s", number, segmentSuffix))
+}
+
+func isBaseSegment(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, errors.WithMessage(err, "could not open segment")
+	}
+	defer file.Close()
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		// A segment is only renamed into place once its header is synced,
+		// and a new segment is synced before it is appended to, so only
+		// the last segment may be missing its header.
+		return false, nil
+	}
+
+	return header[5]&baseFlag != 0, nil
+}
+
+// readSegment reads the entries of a segment, returning the size of the valid
+// prefix of the segment.  If the segment is the last, a torn record at its end
+// is ignored, otherwise it indicates corruption.
+func readSegment(path string, last bool) ([]*pb.Persisted, int64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "could not read segment")
+	}
+
+	corrupt := func(format string, args ...interface{}) error {
+		return errors.Wrapf(mirbft.ErrCorruptStorage, "segment %s: %s", filepath.Base(path), fmt.Sprintf(format, args...))
+	}
+
+	if len(data) < headerSize {
+		if last {
+			return nil, 0, nil
+		}
+		return nil, 0, corrupt("header is truncated")
+	}
+
+	if !bytes.Equal(data[:4], magic) || data[4] != version {
+		return nil, 0, corrupt("header is not recognized")
+	}
+
+	var entries []*pb.Persisted
+	offset := int64(headerSize)
+	for offset < int64(len(data)) {
+		remaining := data[offset:]
+		if len(remaining) < recordHeaderSize {
+			if last {
+				break
+			}
+			return nil, 0, corrupt("record at offset %d is truncated", offset)
+		}
+
+		length := int64(binary.BigEndian.Uint32(remaining[:4]))
+		checksum := binary.BigEndian.Uint32(remaining[4:8])
+		if int64(len(remaining)) < recordHeaderSize+length {
+			if last {
+				break
+			}
+			return nil, 0, corrupt("record at offset %d is truncated", offset)
+		}
+
+		record := remaining[recordHeaderSize : recordHeaderSize+length]
+		if crc32.Checksum(record, crcTable) != checksum {
+			if last && int64(len(remaining)) == recordHeaderSize+length {
+				// Only the final record of the log may have been torn
+				break
+			}
+			return nil, 0, corrupt("record at offset %d fails its checksum", offset)
+		}
+
+		entry := &pb.Persisted{}
+		if err := proto.Unmarshal(record, entry); err != nil {
+			return nil, 0, corrupt("record at offset %d could not be unmarshaled: %s", offset, err)
+		}
+
+		entries = append(entries, entry)
+		offset += recordHeaderSize + length
+	}
+
+	return entries, offset, nil
+}
+
+// openSegment opens an existing segment for appending, discarding anything
+// beyond the given size.
+func (w *WAL) openSegment(number uint64, size int64) error {
+	file, err := os.OpenFile(w.segmentPath(number), os.O_RDWR, 0644)
+	if err != nil {
+		return errors.WithMessage(err, "could not open segment")
+	}
+
+	if size < headerSize {
+		// The header was torn, so rewrite it
+		if err := file.Truncate(0); err != nil {
+			file.Close()
+			return errors.WithMessage(err, "could not truncate segment")
+		}
+		if _, err := file.Write(segmentHeader(false)); err != nil {
+			file.Close()
+			return errors.WithMessage(err, "could not write segment header")
+		}
+		size = headerSize
+	} else if err := file.Truncate(size); err != nil {
+		file.Close()
+		return errors.WithMessage(err, "could not truncate torn record")
+	}
+
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return errors.WithMessage(err, "could not seek to end of segment")
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return errors.WithMessage(err, "could not sync segment")
+	}
+
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	w.size = size
+	return nil
+}
+
+// createSegment writes a new segment containing the given entries, syncs it,
+// and only then moves it into place, leaving it open for appending.
+func (w *WAL) createSegment(number uint64, base bool, entries []*pb.Persisted) error {
+	path := w.segmentPath(number)
+	file, err := os.OpenFile(path+tmpSuffix, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.WithMessage(err, "could not create segment")
+	}
+
+	writer := bufio.NewWriter(file)
+	size := int64(headerSize)
+	if _, err := writer.Write(segmentHeader(base)); err != nil {
+		file.Close()
+		return errors.WithMessage(err, "could not write segment header")
+	}
+
+	for _, entry := range entries {
+		n, err := writeRecord(writer, entry)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		size += n
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return errors.WithMessage(err, "could not write segment")
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return errors.WithMessage(err, "could not sync segment")
+	}
+
+	if err := os.Rename(path+tmpSuffix, path); err != nil {
+		file.Close()
+		return errors.WithMessage(err, "could not move segment into place")
+	}
+
+	if err := syncDir(w.dir); err != nil {
+		file.Close()
+		return err
+	}
+
+	w.segments = append(w.segments, number)
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	w.size = size
+	return nil
+}
+
+func segmentHeader(base bool) []byte {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	header[4] = version
+	if base {
+		header[5] = baseFlag
+	}
+	return header
+}
+
+func writeRecord(writer io.Writer, entry *pb.Persisted) (int64, error) {
+	record, err := proto.Marshal(entry)
+	if err != nil {
+		return 0, errors.WithMessage(err, "could not marshal entry")
+	}
+
+	header := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint32(header[:4], uint32(len(record)))
+	binary.BigEndian.PutUint32(header[4:], crc32.Checksum(record, crcTable))
+
+	if _, err := writer.Write(header); err != nil {
+		return 0, errors.WithMessage(err, "could not write record")
+	}
+
+	if _, err := writer.Write(record); err != nil {
+		return 0, errors.WithMessage(err, "could not write record")
+	}
+
+	return int64(recordHeaderSize + len(record)), nil
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return errors.WithMessage(err, "could not open log directory")
+	}
+	defer file.Close()
+
+	if err := file.Sync(); err != nil {
+		return errors.WithMessage(err, "could not sync log directory")
+	}
+
+	return nil
+}
+
+// Load returns the entry at the given index, counting from the oldest entry
+// which has not been truncated, or io.EOF if there is no such entry.
+func (w *WAL) Load(index uint64) (*pb.Persisted, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if index >= uint64(len(w.entries)) {
+		return nil, io.EOF
+	}
+
+	return w.entries[index], nil
+}
+
+// Append writes the entries to the log, but does not sync them.  Entries are
+// not durable until Sync returns.
+func (w *WAL) Append(entries ...*pb.Persisted) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.append(entries)
+}
+
+func (w *WAL) append(entries []*pb.Persisted) error {
+	for _, entry := range entries {
+		if w.size >= w.segmentSizeBytes {
+			if err := w.sync(); err != nil {
+				return err
+			}
+
+			if err := w.file.Close(); err != nil {
+				return errors.WithMessage(err, "could not close segment")
+			}
+
+			if err := w.createSegment(w.segments[len(w.segments)-1]+1, false, nil); err != nil {
+				return err
+			}
+		}
+
+		n, err := writeRecord(w.writer, entry)
+		if err != nil {
+			return err
+		}
+
+		w.size += n
+		w.entries = append(w.entries, entry)
+	}
+
+	return nil
+}
+
+// Sync makes all appended entries durable.
+func (w *WAL) Sync() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.sync()
+}
+
+func (w *WAL) sync() error {
+	if err := w.writer.Flush(); err != nil {
+		return errors.WithMessage(err, "could not write segment")
+	}
+
+	if err := w.file.Sync(); err != nil {
+		return errors.WithMessage(err, "could not sync segment")
+	}
+
+	return nil
+}
+
+// Persist appends the entries of Actions.Persisted, and syncs them once, all
+// together, then truncates the log to Actions.Truncate, if it is set.  It must
+// return before any of the other actions are performed, in particular before
+// any messages are transmitted, as the messages may commit this node to the
+// persisted state.
+func (w *WAL) Persist(actions *mirbft.Actions) error {
+	if len(actions.Persisted) == 0 && actions.Truncate == 0 {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(actions.Persisted) > 0 {
+		if err := w.append(actions.Persisted); err != nil {
+			return err
+		}
+
+		if err := w.sync(); err != nil {
+			return err
+		}
+	}
+
+	if actions.Truncate == 0 {
+		return nil
+	}
+
+	return w.truncate(actions.Truncate)
+}
+
+// Truncate discards the entries which the state machine no longer requires
+// once it has garbage collected its persisted state below the low watermark,
+// that is, the checkpoints and Q and P entries below the low watermark, and
+// all but the latest network config, epoch change, and new epoch entries.
+// Persist truncates the log as the state machine requests, so Truncate need
+// only be called directly by consumers which write entries via Append.
+func (w *WAL) Truncate(lowWatermark uint64) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.truncate(lowWatermark)
+}
+
+func (w *WAL) truncate(lowWatermark uint64) error {
+	retained := retain(w.entries, lowWatermark)
+	if len(retained) == len(w.entries) {
+		return nil
+	}
+
+	if err := w.sync(); err != nil {
+		return err
+	}
+
+	if err := w.file.Close(); err != nil {
+		return errors.WithMessage(err, "could not close segment")
+	}
+
+	superseded := w.segments
+	w.segments = nil
+	if err := w.createSegment(superseded[len(superseded)-1]+1, true, retained); err != nil {
+		return err
+	}
+	w.entries = retained
+
+	for _, number := range superseded {
+		if err := os.Remove(w.segmentPath(number)); err != nil {
+			return errors.WithMessage(err, "could not remove superseded segment")
+		}
+	}
+
+	return syncDir(w.dir)
+}
+
+// retain returns, in their original order, the entries which the state machine
+// requires once it has truncated its persisted state to the low watermark.
+func retain(entries []*pb.Persisted, lowWatermark uint64) []*pb.Persisted {
+	lastNetworkConfig, lastECEntry, lastNEntry := -1, -1, -1
+	for i, entry := range entries {
+		switch entry.Type.(type) {
+		case *pb.Persisted_NetworkConfig:
+			lastNetworkConfig = i
+		case *pb.Persisted_Ecentry:
+			lastECEntry = i
+		case *pb.Persisted_Nentry:
+			lastNEntry = i
+		}
+	}
+
+	var retained []*pb.Persisted
+	for i, entry := range entries {
+		switch d := entry.Type.(type) {
+		case *pb.Persisted_NetworkConfig:
+			if i != lastNetworkConfig {
+				continue
+			}
+		case *pb.Persisted_Ecentry:
+			if i != lastECEntry {
+				continue
+			}
+		case *pb.Persisted_Nentry:
+			if i != lastNEntry {
+				continue
+			}
+		case *pb.Persisted_Checkpoint:
+			if d.Checkpoint.SeqNo < lowWatermark {
+				continue
+			}
+		case *pb.Persisted_Qentry:
+			if d.Qentry.SeqNo < lowWatermark {
+				continue
+			}
+		case *pb.Persisted_Pentry:
+			if d.Pentry.SeqNo < lowWatermark {
+				continue
+			}
+		}
+
+		retained = append(retained, entry)
+	}
+
+	return retained
+}
+
+// Close syncs the log and closes its last segment.
+func (w *WAL) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.sync(); err != nil {
+		w.file.Close()
+		return err
+	}
+
+	if err := w.file.Close(); err != nil {
+		return errors.WithMessage(err, "could not close segment")
+	}
+
+	return nil
+}
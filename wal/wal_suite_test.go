@@ -0,0 +1,13 @@
+package wal_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWal(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Wal Suite")
+}
@@ -0,0 +1,396 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wal_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/IBM/mirbft"
+	pb "github.com/IBM/mirbft/mirbftpb"
+	"github.com/IBM/mirbft/wal"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("WAL", func() {
+	var (
+		dir     string
+		config  *wal.Config
+		entries []*pb.Persisted
+	)
+
+	loadAll := func(w *wal.WAL) []*pb.Persisted {
+		var loaded []*pb.Persisted
+		for index := uint64(0); ; index++ {
+			entry, err := w.Load(index)
+			if err == io.EOF {
+				return loaded
+			}
+			Expect(err).NotTo(HaveOccurred())
+			loaded = append(loaded, entry)
+		}
+	}
+
+	expectEntries := func(actual, expected []*pb.Persisted) {
+		Expect(actual).To(HaveLen(len(expected)))
+		for i := range expected {
+			Expect(proto.Equal(actual[i], expected[i])).To(BeTrue(), "entry %d differs", i)
+		}
+	}
+
+	segments := func() []string {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+		Expect(err).NotTo(HaveOccurred())
+		return paths
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "wal")
+		Expect(err).NotTo(HaveOccurred())
+
+		config = &wal.Config{}
+
+		entries = []*pb.Persisted{
+			{
+				Type: &pb.Persisted_NetworkConfig{
+					NetworkConfig: mirbft.StandardInitialNetworkConfig(4),
+				},
+			},
+			{
+				Type: &pb.Persisted_Checkpoint{
+					Checkpoint: &pb.Checkpoint{SeqNo: 0, Value: []byte("cp0")},
+				},
+			},
+		}
+
+		for seqNo := uint64(1); seqNo <= 45; seqNo++ {
+			digest := []byte(fmt.Sprintf("digest-%d", seqNo))
+			entries = append(entries,
+				&pb.Persisted{
+					Type: &pb.Persisted_Qentry{
+						Qentry: &pb.QEntry{
+							Epoch:  0,
+							SeqNo:  seqNo,
+							Digest: digest,
+						},
+					},
+				},
+				&pb.Persisted{
+					Type: &pb.Persisted_Pentry{
+						Pentry: &pb.PEntry{
+							Epoch:  0,
+							SeqNo:  seqNo,
+							Digest: digest,
+						},
+					},
+				},
+			)
+
+			if seqNo%20 == 0 {
+				entries = append(entries, &pb.Persisted{
+					Type: &pb.Persisted_Checkpoint{
+						Checkpoint: &pb.Checkpoint{SeqNo: seqNo, Value: []byte(fmt.Sprintf("cp%d", seqNo))},
+					},
+				})
+			}
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("loads the persisted entries once reopened", func() {
+		w, err := wal.Open(dir, config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loadAll(w)).To(BeEmpty())
+
+		Expect(w.Persist(&mirbft.Actions{Persisted: entries[:10]})).To(Succeed())
+		Expect(w.Persist(&mirbft.Actions{Persisted: entries[10:]})).To(Succeed())
+		Expect(w.Close()).To(Succeed())
+
+		w, err = wal.Open(dir, config)
+		Expect(err).NotTo(HaveOccurred())
+		expectEntries(loadAll(w), entries)
+		Expect(w.Close()).To(Succeed())
+	})
+
+	It("may be used to restart a node", func() {
+		w, err := wal.Open(dir, config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Persist(&mirbft.Actions{Persisted: entries})).To(Succeed())
+
+		doneC := make(chan struct{})
+		defer close(doneC)
+		node, err := mirbft.RestartNode(&mirbft.Config{
+			ID:     1,
+			Logger: zap.NewNop(),
+			BatchParameters: mirbft.BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}, doneC, w)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(node.Replicas).To(HaveLen(4))
+		Expect(w.Close()).To(Succeed())
+	})
+
+	It("truncates as the state machine garbage collects, and restarts from what remains", func() {
+		w, err := wal.Open(dir, config)
+		Expect(err).NotTo(HaveOccurred())
+
+		nodeConfig := &mirbft.Config{
+			ID:     3,
+			Logger: zap.NewNop(),
+			BatchParameters: mirbft.BatchParameters{
+				CutSizeBytes: 1,
+			},
+			SuspectTicks:         4,
+			NewEpochTimeoutTicks: 8,
+			BufferSize:           500,
+		}
+
+		doneC := make(chan struct{})
+		node, err := mirbft.StartNewNode(nodeConfig, doneC, mirbft.StandardInitialNetworkConfig(4), w)
+		Expect(err).NotTo(HaveOccurred())
+
+		persistNext := func() *mirbft.Actions {
+			actions := &mirbft.Actions{}
+			Eventually(node.Ready()).Should(Receive(actions))
+			Expect(w.Persist(actions)).To(Succeed())
+			return actions
+		}
+
+		persistNext()
+
+		target := &mirbft.StateTarget{
+			SeqNo: 100,
+			Value: []byte("cp100"),
+		}
+		for source := uint64(0); source < 3; source++ {
+			Expect(node.Step(context.Background(), source, &pb.Msg{
+				Type: &pb.Msg_Checkpoint{
+					Checkpoint: &pb.Checkpoint{
+						SeqNo: target.SeqNo,
+						Value: target.Value,
+					},
+				},
+			})).To(Succeed())
+		}
+
+		Eventually(func() *mirbft.StateTarget {
+			return persistNext().StateTransfer
+		}).Should(Equal(target))
+
+		Expect(node.StateTransferComplete(&mirbft.StateTransferResult{
+			Target: target,
+		})).To(Succeed())
+		Expect(persistNext().Truncate).To(Equal(uint64(100)))
+
+		close(doneC)
+		Eventually(node.Err()).Should(BeClosed())
+		Expect(w.Close()).To(Succeed())
+
+		w, err = wal.Open(dir, config)
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Close()
+
+		var checkpoints []uint64
+		for _, entry := range loadAll(w) {
+			if d, ok := entry.Type.(*pb.Persisted_Checkpoint); ok {
+				checkpoints = append(checkpoints, d.Checkpoint.SeqNo)
+			}
+		}
+		Expect(checkpoints).To(Equal([]uint64{100}))
+
+		doneC = make(chan struct{})
+		defer close(doneC)
+		node, err = mirbft.RestartNode(nodeConfig, doneC, w)
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := node.Status(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Checkpoints).To(HaveLen(1))
+		Expect(status.Checkpoints[0].SeqNo).To(Equal(uint64(100)))
+	})
+
+	When("the segments are small", func() {
+		BeforeEach(func() {
+			config.SegmentSizeBytes = 256
+		})
+
+		It("spreads the entries across segments", func() {
+			w, err := wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Persist(&mirbft.Actions{Persisted: entries})).To(Succeed())
+			Expect(w.Close()).To(Succeed())
+			Expect(len(segments())).To(BeNumerically(">", 1))
+
+			w, err = wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			expectEntries(loadAll(w), entries)
+			Expect(w.Close()).To(Succeed())
+		})
+
+		It("detects corruption in an earlier segment", func() {
+			w, err := wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Persist(&mirbft.Actions{Persisted: entries})).To(Succeed())
+			Expect(w.Close()).To(Succeed())
+
+			first := segments()[0]
+			data, err := ioutil.ReadFile(first)
+			Expect(err).NotTo(HaveOccurred())
+			data[len(data)-1] ^= 0xff
+			Expect(ioutil.WriteFile(first, data, 0644)).To(Succeed())
+
+			_, err = wal.Open(dir, config)
+			Expect(errors.Cause(err)).To(Equal(mirbft.ErrCorruptStorage))
+		})
+	})
+
+	When("the last record was torn", func() {
+		BeforeEach(func() {
+			w, err := wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Persist(&mirbft.Actions{Persisted: entries[:10]})).To(Succeed())
+			Expect(w.Close()).To(Succeed())
+
+			paths := segments()
+			last := paths[len(paths)-1]
+			data, err := ioutil.ReadFile(last)
+			Expect(err).NotTo(HaveOccurred())
+
+			// Write only the beginning of a copy of the final record
+			Expect(ioutil.WriteFile(last, append(data, data[len(data)-20:len(data)-10]...), 0644)).To(Succeed())
+		})
+
+		It("discards it, and appends after the entries which precede it", func() {
+			w, err := wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			expectEntries(loadAll(w), entries[:10])
+
+			Expect(w.Persist(&mirbft.Actions{Persisted: entries[10:]})).To(Succeed())
+			Expect(w.Close()).To(Succeed())
+
+			w, err = wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			expectEntries(loadAll(w), entries)
+			Expect(w.Close()).To(Succeed())
+		})
+	})
+
+	Describe("Truncate", func() {
+		var w *wal.WAL
+
+		BeforeEach(func() {
+			entries = append(entries,
+				&pb.Persisted{
+					Type: &pb.Persisted_NetworkConfig{
+						NetworkConfig: mirbft.StandardInitialNetworkConfig(7),
+					},
+				},
+				&pb.Persisted{
+					Type: &pb.Persisted_Ecentry{
+						Ecentry: &pb.ECEntry{
+							EpochChange: &pb.EpochChange{NewEpoch: 1},
+						},
+					},
+				},
+				&pb.Persisted{
+					Type: &pb.Persisted_Ecentry{
+						Ecentry: &pb.ECEntry{
+							EpochChange: &pb.EpochChange{NewEpoch: 2},
+						},
+					},
+				},
+			)
+
+			var err error
+			w, err = wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Persist(&mirbft.Actions{Persisted: entries})).To(Succeed())
+		})
+
+		It("retains only the entries at or above the low watermark, and the latest of the rest", func() {
+			Expect(w.Truncate(20)).To(Succeed())
+			Expect(w.Close()).To(Succeed())
+			Expect(segments()).To(HaveLen(1))
+
+			w, err := wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			loaded := loadAll(w)
+			Expect(w.Close()).To(Succeed())
+
+			var seqNos []uint64
+			var networkConfigs, epochChanges int
+			for _, entry := range loaded {
+				switch d := entry.Type.(type) {
+				case *pb.Persisted_Qentry:
+					seqNos = append(seqNos, d.Qentry.SeqNo)
+				case *pb.Persisted_Checkpoint:
+					Expect(d.Checkpoint.SeqNo).To(BeNumerically(">=", 20))
+				case *pb.Persisted_NetworkConfig:
+					networkConfigs++
+					Expect(d.NetworkConfig.Nodes).To(HaveLen(7))
+				case *pb.Persisted_Ecentry:
+					epochChanges++
+					Expect(d.Ecentry.EpochChange.NewEpoch).To(Equal(uint64(2)))
+				}
+			}
+
+			Expect(seqNos).To(HaveLen(26))
+			Expect(seqNos[0]).To(Equal(uint64(20)))
+			Expect(networkConfigs).To(Equal(1))
+			Expect(epochChanges).To(Equal(1))
+		})
+
+		It("continues to append after truncating", func() {
+			Expect(w.Truncate(40)).To(Succeed())
+			extra := &pb.Persisted{
+				Type: &pb.Persisted_Checkpoint{
+					Checkpoint: &pb.Checkpoint{SeqNo: 60, Value: []byte("cp60")},
+				},
+			}
+			Expect(w.Persist(&mirbft.Actions{Persisted: []*pb.Persisted{extra}})).To(Succeed())
+			retained := loadAll(w)
+			Expect(w.Close()).To(Succeed())
+
+			w, err := wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			loaded := loadAll(w)
+			expectEntries(loaded, retained)
+			Expect(proto.Equal(loaded[len(loaded)-1], extra)).To(BeTrue())
+			Expect(w.Close()).To(Succeed())
+		})
+
+		It("ignores a truncation which did not complete", func() {
+			Expect(w.Close()).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%016x.wal.tmp", 1)), []byte("partial"), 0644)).To(Succeed())
+
+			w, err := wal.Open(dir, config)
+			Expect(err).NotTo(HaveOccurred())
+			expectEntries(loadAll(w), entries)
+			Expect(w.Close()).To(Succeed())
+
+			_, err = os.Stat(filepath.Join(dir, fmt.Sprintf("%016x.wal.tmp", 1)))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})